@@ -1,12 +1,24 @@
 package main
 
 import (
+	"log/slog"
+	"os"
+
 	"github.com/janobono/auth-service/internal/config"
 	"github.com/janobono/auth-service/internal/server"
 )
 
 func main() {
 	serverConfig := config.InitConfig()
+
+	if len(os.Args) > 1 {
+		if err := server.RunCommand(serverConfig, os.Args[1:]); err != nil {
+			slog.Error("Command failed", "command", os.Args[1], "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := server.NewServer(serverConfig, nil)
 	app.Start()
 }
@@ -0,0 +1,15 @@
+-- name: AddAuditCheckpoint :one
+insert into audit_checkpoint (id, seq, hash, kid, signature, created_at)
+values ($1, $2, $3, $4, $5, $6)
+returning *;
+
+-- name: GetAuditCheckpoints :many
+select *
+from audit_checkpoint
+order by seq, created_at;
+
+-- name: GetLastAuditCheckpoint :one
+select *
+from audit_checkpoint
+order by seq desc
+limit 1;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- name: AddAuditEvent :one
+insert into audit_event (id, created_at, actor_id, actor_email, action, target_type, target_id, ip, user_agent, before, after, prev_hash, hash)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+returning *;
+
+-- name: GetLastAuditEvent :one
+select *
+from audit_event
+order by seq desc
+limit 1;
+
+-- name: LockAuditChain :exec
+select pg_advisory_xact_lock(hashtext('audit_event'));
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: AddConfirmationToken :one
+insert into confirmation_token (id, user_id, purpose, created_at, expires_at)
+values ($1, $2, $3, $4, $5)
+returning *;
+
+-- name: InvalidateConfirmationTokens :exec
+update confirmation_token
+set consumed_at = $3
+where user_id = $1
+  and purpose = $2
+  and consumed_at is null;
+
+-- name: DeleteExpiredConfirmationTokens :exec
+delete
+from confirmation_token
+where user_id = $1
+  and expires_at < $2;
+
+-- name: ConsumeConfirmationToken :one
+update confirmation_token
+set consumed_at = $4
+where id = $1
+  and user_id = $2
+  and purpose = $3
+  and consumed_at is null
+  and expires_at > $4
+returning *;
\ No newline at end of file
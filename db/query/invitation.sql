@@ -0,0 +1,34 @@
+-- name: AddInvitation :one
+insert into invitation (id, user_id, email, created_at, expires_at)
+values ($1, $2, $3, $4, $5)
+returning *;
+
+-- name: GetInvitationById :one
+select *
+from invitation
+where id = $1
+limit 1;
+
+-- name: GetInvitationByUserId :one
+select *
+from invitation
+where user_id = $1
+limit 1;
+
+-- name: SetInvitationAccepted :one
+update invitation
+set accepted_at = $2
+where id = $1
+returning *;
+
+-- name: SetInvitationExpiresAt :one
+update invitation
+set expires_at = $2
+where id = $1
+returning *;
+
+-- name: SetInvitationRevoked :one
+update invitation
+set revoked_at = $2
+where id = $1
+returning *;
\ No newline at end of file
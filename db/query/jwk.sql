@@ -1,14 +1,23 @@
 -- name: AddJwk :one
-insert into jwk (id, kty, use, alg, public_key, private_key, active, created_at, expires_at)
-values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+insert into jwk (id, kid, kty, use, alg, public_key, private_key, private_key_dek, kek_version, created_at,
+                 activates_at, expires_at, published_until, imported)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 returning *;
 
--- name: DeleteNotActiveJwks :exec
+-- name: DeleteExpiredJwks :exec
 delete
 from jwk
-where active is false
-  and use = $1
-  and expires_at < $2;
+where use = $1
+  and published_until < $2
+  and id not in (select kid from audit_checkpoint);
+
+-- name: DeletePendingJwks :exec
+delete
+from jwk
+where id != $1
+  and use = $2
+  and activates_at > $3
+  and imported = false;
 
 -- name: GetJwk :one
 select *
@@ -16,22 +25,78 @@ from jwk
 where id = $1
 limit 1;
 
+-- name: GetJwkByKid :one
+select *
+from jwk
+where kid = $1
+limit 1;
+
+-- name: GetJwks :many
+select *
+from jwk
+order by created_at desc;
+
 -- name: GetActiveJwk :one
 select *
 from jwk
-where active is true
-  and use = $1
-order by created_at
+where use = $1
+  and revoked_at is null
+  and activates_at <= $2
+  and expires_at > $2
+order by activates_at desc
+limit 1;
+
+-- name: GetPendingJwk :one
+select *
+from jwk
+where use = $1
+  and revoked_at is null
+  and activates_at > $2
+order by activates_at desc
 limit 1;
 
--- name: DeactivateJwks :exec
+-- name: GetRevokedJwkKids :many
+select kid
+from jwk
+where revoked_at is not null
+order by kid;
+
+-- name: RetireJwks :exec
 update jwk
-set active = false
+set expires_at      = $3,
+    published_until = $4
 where id != $1
-  and use = $2;
+  and use = $2
+  and activates_at < $3
+  and expires_at > $3;
+
+-- name: LockJwks :exec
+select pg_advisory_xact_lock(hashtext('jwk_' || sqlc.arg(use)::text));
+
+-- name: GetPublishedJwks :many
+select *
+from jwk
+where revoked_at is null
+  and published_until > $1
+order by activates_at;
 
--- name: GetActiveJwks :many
+-- name: GetJwksNotWrappedBy :many
 select *
 from jwk
-where active is true
-order by created_at;
\ No newline at end of file
+where kek_version is null
+   or kek_version != $1
+order by created_at
+for update;
+
+-- name: SetJwkPrivateKey :exec
+update jwk
+set private_key     = $2,
+    private_key_dek = $3,
+    kek_version     = $4
+where id = $1;
+
+-- name: SetJwkRevoked :one
+update jwk
+set revoked_at = $2
+where id = $1
+returning *;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- name: AddLoginEvent :one
+insert into login_event (id, user_id, created_at, ip, user_agent, method, success, fingerprint)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+returning *;
+
+-- name: CountSuccessfulLoginEventsByFingerprint :one
+select count(*)
+from login_event
+where user_id = $1
+  and fingerprint = $2
+  and success is true;
+
+-- name: GetLoginEventById :one
+select *
+from login_event
+where id = $1
+limit 1;
+
+-- name: SetLoginEventRevoked :one
+update login_event
+set revoked_at = $2
+where id = $1
+returning *;
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- name: AddMail :one
+insert into mail (id, created_at, recipients, subject, data, status, attempts, next_attempt_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+returning *;
+
+-- name: ClaimMails :many
+update mail
+set next_attempt_at = sqlc.arg(lease_until)
+where id in (select m.id
+             from mail m
+             where m.status = sqlc.arg(status)
+               and m.next_attempt_at <= sqlc.arg(now)::timestamptz
+             order by m.next_attempt_at
+             limit sqlc.arg(batch_size)::int for update skip locked)
+returning *;
+
+-- name: GetMailById :one
+select *
+from mail
+where id = $1;
+
+-- name: PurgeMailData :execrows
+update mail
+set data = '{}'::jsonb
+where status != $1
+  and created_at < $2
+  and data != '{}'::jsonb;
+
+-- name: SetMailResult :one
+update mail
+set status          = $2,
+    attempts        = $3,
+    next_attempt_at = $4,
+    last_error      = $5,
+    sent_at         = $6
+where id = $1
+returning *;
\ No newline at end of file
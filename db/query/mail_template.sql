@@ -0,0 +1,46 @@
+-- name: AddMailTemplate :one
+insert into mail_template (id, code, locale, version, subject, html_body, text_body, created_at, updated_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+returning *;
+
+-- name: AddMailTemplateVersion :exec
+insert into mail_template_version (mail_template_id, version, subject, html_body, text_body, created_at)
+values ($1, $2, $3, $4, $5, $6);
+
+-- name: CountMailTemplatesByCodeAndLocale :one
+select count(*)
+from mail_template
+where code = $1
+  and locale = $2;
+
+-- name: DeleteMailTemplateById :exec
+delete
+from mail_template
+where id = $1;
+
+-- name: GetMailTemplateById :one
+select *
+from mail_template
+where id = $1;
+
+-- name: GetMailTemplatesByCode :many
+select *
+from mail_template
+where code = $1
+order by locale;
+
+-- name: GetMailTemplateVersions :many
+select *
+from mail_template_version
+where mail_template_id = $1
+order by version desc;
+
+-- name: SetMailTemplate :one
+update mail_template
+set version    = version + 1,
+    subject    = $2,
+    html_body  = $3,
+    text_body  = $4,
+    updated_at = $5
+where id = $1
+returning *;
\ No newline at end of file
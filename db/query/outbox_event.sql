@@ -0,0 +1,35 @@
+-- name: AddOutboxEvent :one
+insert into outbox_event (id, created_at, event_type, aggregate_id, payload)
+values ($1, $2, $3, $4, $5)
+returning *;
+
+-- name: LockOutboxEvents :exec
+select pg_advisory_xact_lock(hashtext('outbox_event'));
+
+-- name: GetOutboxEventById :one
+select *
+from outbox_event
+where id = $1;
+
+-- name: GetOutboxEventsAfterSeq :many
+select *
+from outbox_event
+where seq > $1
+order by seq
+limit $2;
+
+-- name: GetLastOutboxEventSeq :one
+select coalesce(max(seq), 0)::bigint
+from outbox_event;
+
+-- name: GetNotDispatchedOutboxEvents :many
+select *
+from outbox_event
+where dispatched_at is null
+order by seq
+limit $1 for update skip locked;
+
+-- name: SetOutboxEventDispatched :exec
+update outbox_event
+set dispatched_at = $2
+where id = $1;
\ No newline at end of file
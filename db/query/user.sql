@@ -1,6 +1,6 @@
 -- name: AddUser :one
-insert into "user" (id, created_at, email, password, confirmed, enabled)
-values ($1, $2, $3, $4, $5, $6)
+insert into "user" (id, created_at, email, password, confirmed, enabled, locale)
+values ($1, $2, $3, $4, $5, $6, $7)
 returning *;
 
 -- name: CountAllUsers :one
@@ -34,6 +34,18 @@ from "user"
 where id = $1
 limit 1;
 
+-- name: GetUsersByAttributeId :many
+select *
+from "user"
+where id in (select user_id from user_attribute where attribute_id = $1)
+order by id;
+
+-- name: GetUsersByAuthorityId :many
+select *
+from "user"
+where id in (select user_id from user_authority where authority_id = $1)
+order by id;
+
 -- name: GetUserByEmail :one
 select *
 from "user"
@@ -48,7 +60,14 @@ returning *;
 
 -- name: SetUserEmail :one
 update "user"
-set email = $2
+set email         = $2,
+    pending_email = null
+where id = $1
+returning *;
+
+-- name: SetUserPendingEmail :one
+update "user"
+set pending_email = $2
 where id = $1
 returning *;
 
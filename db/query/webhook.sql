@@ -0,0 +1,29 @@
+-- name: AddWebhook :one
+insert into webhook (id, url, secret, event_types, enabled, created_at)
+values ($1, $2, $3, $4, $5, $6)
+returning *;
+
+-- name: DeleteWebhookById :exec
+delete
+from webhook
+where id = $1;
+
+-- name: GetEnabledWebhooks :many
+select *
+from webhook
+where enabled is true
+order by created_at;
+
+-- name: GetWebhookById :one
+select *
+from webhook
+where id = $1;
+
+-- name: SetWebhook :one
+update webhook
+set url         = $2,
+    secret      = $3,
+    event_types = $4,
+    enabled     = $5
+where id = $1
+returning *;
\ No newline at end of file
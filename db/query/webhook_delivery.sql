@@ -0,0 +1,30 @@
+-- name: AddWebhookDelivery :exec
+insert into webhook_delivery (id, webhook_id, outbox_event_id, status, attempts, next_attempt_at, created_at)
+values ($1, $2, $3, $4, $5, $6, $7)
+on conflict (webhook_id, outbox_event_id) do nothing;
+
+-- name: ClaimWebhookDeliveries :many
+update webhook_delivery
+set next_attempt_at = sqlc.arg(lease_until)
+where id in (select d.id
+             from webhook_delivery d
+             where d.status = sqlc.arg(status)
+               and d.next_attempt_at <= sqlc.arg(now)::timestamptz
+             order by d.next_attempt_at
+             limit sqlc.arg(batch_size)::int for update skip locked)
+returning *;
+
+-- name: GetWebhookDeliveryById :one
+select *
+from webhook_delivery
+where id = $1;
+
+-- name: SetWebhookDeliveryResult :one
+update webhook_delivery
+set status          = $2,
+    attempts        = $3,
+    next_attempt_at = $4,
+    last_error      = $5,
+    delivered_at    = $6
+where id = $1
+returning *;
\ No newline at end of file
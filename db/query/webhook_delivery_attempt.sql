@@ -0,0 +1,10 @@
+-- name: AddWebhookDeliveryAttempt :one
+insert into webhook_delivery_attempt (id, delivery_id, created_at, status_code, error, duration_ms)
+values ($1, $2, $3, $4, $5, $6)
+returning *;
+
+-- name: GetWebhookDeliveryAttempts :many
+select *
+from webhook_delivery_attempt
+where delivery_id = $1
+order by created_at;
\ No newline at end of file
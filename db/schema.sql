@@ -4,20 +4,29 @@ create extension if not exists unaccent;
 -- Table: jwk
 create table if not exists jwk
 (
-    id          uuid         not null,
-    kty         varchar(255) not null,
-    use         varchar(255) not null,
-    alg         varchar(255) not null,
-    public_key  bytea        not null,
-    private_key bytea        not null,
-    active      boolean      not null,
-    created_at  timestamptz  not null,
-    expires_at  timestamptz  not null
+    id              uuid         not null,
+    kid             varchar(255) not null,
+    kty             varchar(255) not null,
+    use             varchar(255) not null,
+    alg             varchar(255) not null,
+    public_key      bytea        not null,
+    private_key     bytea        not null,
+    private_key_dek bytea,
+    kek_version     varchar(255),
+    created_at      timestamptz  not null,
+    activates_at    timestamptz  not null,
+    expires_at      timestamptz  not null,
+    published_until timestamptz  not null,
+    revoked_at      timestamptz,
+    imported        boolean      not null default false
 );
 
 alter table jwk
     add constraint pk_jwk primary key (id);
 
+alter table jwk
+    add constraint uq_jwk_kid unique (kid);
+
 -- Table: attribute
 create table if not exists attribute
 (
@@ -46,12 +55,14 @@ alter table authority
 -- Table: user
 create table if not exists "user"
 (
-    id         uuid         not null,
-    created_at timestamptz  not null,
-    email      varchar(255) not null,
-    password   varchar(255) not null,
-    confirmed  bool         not null,
-    enabled    bool         not null
+    id            uuid         not null,
+    created_at    timestamptz  not null,
+    email         varchar(255) not null,
+    password      varchar(255) not null,
+    confirmed     bool         not null,
+    enabled       bool         not null,
+    pending_email varchar(255),
+    locale        varchar(35)
 );
 
 alter table "user"
@@ -92,3 +103,270 @@ alter table user_authority
 
 alter table user_authority
     add constraint fk_user_authority_authority foreign key (authority_id) references authority (id) on delete cascade;
+
+-- Table: confirmation_token
+create table if not exists confirmation_token
+(
+    id          uuid         not null,
+    user_id     uuid         not null,
+    purpose     varchar(255) not null,
+    created_at  timestamptz  not null,
+    expires_at  timestamptz  not null,
+    consumed_at timestamptz
+);
+
+alter table confirmation_token
+    add constraint pk_confirmation_token primary key (id);
+
+alter table confirmation_token
+    add constraint fk_confirmation_token_user foreign key (user_id) references "user" (id) on delete cascade;
+
+create index if not exists idx_confirmation_token_user_purpose on confirmation_token (user_id, purpose);
+
+-- Table: invitation
+create table if not exists invitation
+(
+    id          uuid         not null,
+    user_id     uuid         not null,
+    email       varchar(255) not null,
+    created_at  timestamptz  not null,
+    expires_at  timestamptz  not null,
+    accepted_at timestamptz,
+    revoked_at  timestamptz
+);
+
+alter table invitation
+    add constraint pk_invitation primary key (id);
+
+alter table invitation
+    add constraint uq_invitation_user_id unique (user_id);
+
+alter table invitation
+    add constraint fk_invitation_user foreign key (user_id) references "user" (id) on delete cascade;
+
+-- Table: audit_event
+create table if not exists audit_event
+(
+    id          uuid         not null,
+    created_at  timestamptz  not null,
+    actor_id    varchar(255),
+    actor_email varchar(255),
+    action      varchar(255) not null,
+    target_type varchar(255) not null,
+    target_id   varchar(255),
+    ip          varchar(255),
+    user_agent  text,
+    before      jsonb,
+    after       jsonb,
+    seq         bigserial,
+    prev_hash   varchar(64),
+    hash        varchar(64)
+);
+
+alter table audit_event
+    add constraint pk_audit_event primary key (id);
+
+create index if not exists idx_audit_event_created_at on audit_event (created_at);
+create index if not exists idx_audit_event_actor_id on audit_event (actor_id);
+create index if not exists idx_audit_event_target_id on audit_event (target_id);
+create index if not exists idx_audit_event_action on audit_event (action);
+create unique index if not exists idx_audit_event_seq on audit_event (seq);
+
+create or replace function audit_event_append_only() returns trigger as
+$$
+begin
+    raise exception '% is append-only', tg_table_name;
+end;
+$$ language plpgsql;
+
+create trigger trg_audit_event_append_only
+    before update or delete
+    on audit_event
+    for each row
+execute function audit_event_append_only();
+
+-- Table: audit_checkpoint
+create table if not exists audit_checkpoint
+(
+    id         uuid        not null,
+    seq        bigint      not null,
+    hash       varchar(64) not null,
+    kid        uuid        not null,
+    signature  text        not null,
+    created_at timestamptz not null
+);
+
+alter table audit_checkpoint
+    add constraint pk_audit_checkpoint primary key (id);
+
+alter table audit_checkpoint
+    add constraint fk_audit_checkpoint_jwk foreign key (kid) references jwk (id);
+
+create index if not exists idx_audit_checkpoint_seq on audit_checkpoint (seq);
+
+create trigger trg_audit_checkpoint_append_only
+    before update or delete
+    on audit_checkpoint
+    for each row
+execute function audit_event_append_only();
+
+-- Table: login_event
+create table if not exists login_event
+(
+    id          uuid         not null,
+    user_id     uuid         not null,
+    created_at  timestamptz  not null,
+    ip          varchar(255),
+    user_agent  text,
+    method      varchar(255) not null,
+    success     boolean      not null,
+    fingerprint varchar(255) not null,
+    revoked_at  timestamptz
+);
+
+alter table login_event
+    add constraint pk_login_event primary key (id);
+
+alter table login_event
+    add constraint fk_login_event_user foreign key (user_id) references "user" (id) on delete cascade;
+
+create index if not exists idx_login_event_user_id_created_at on login_event (user_id, created_at);
+create index if not exists idx_login_event_user_id_fingerprint on login_event (user_id, fingerprint);
+
+-- Table: outbox_event
+create table if not exists outbox_event
+(
+    id            uuid         not null,
+    seq           bigserial    not null,
+    created_at    timestamptz  not null,
+    event_type    varchar(255) not null,
+    aggregate_id  uuid         not null,
+    payload       jsonb        not null,
+    dispatched_at timestamptz
+);
+
+alter table outbox_event
+    add constraint pk_outbox_event primary key (id);
+
+create unique index if not exists idx_outbox_event_seq on outbox_event (seq);
+create index if not exists idx_outbox_event_not_dispatched on outbox_event (seq) where dispatched_at is null;
+
+-- Table: webhook
+create table if not exists webhook
+(
+    id          uuid           not null,
+    url         varchar(2048)  not null,
+    secret      varchar(255)   not null,
+    event_types varchar(255)[] not null,
+    enabled     boolean        not null,
+    created_at  timestamptz    not null
+);
+
+alter table webhook
+    add constraint pk_webhook primary key (id);
+
+-- Table: webhook_delivery
+create table if not exists webhook_delivery
+(
+    id              uuid         not null,
+    webhook_id      uuid         not null,
+    outbox_event_id uuid         not null,
+    status          varchar(255) not null,
+    attempts        integer      not null,
+    next_attempt_at timestamptz  not null,
+    last_error      text,
+    created_at      timestamptz  not null,
+    delivered_at    timestamptz
+);
+
+alter table webhook_delivery
+    add constraint pk_webhook_delivery primary key (id);
+
+alter table webhook_delivery
+    add constraint uq_webhook_delivery_webhook_id_outbox_event_id unique (webhook_id, outbox_event_id);
+
+alter table webhook_delivery
+    add constraint fk_webhook_delivery_webhook foreign key (webhook_id) references webhook (id) on delete cascade;
+
+alter table webhook_delivery
+    add constraint fk_webhook_delivery_outbox_event foreign key (outbox_event_id) references outbox_event (id) on delete cascade;
+
+create index if not exists idx_webhook_delivery_status_next_attempt_at on webhook_delivery (status, next_attempt_at);
+create index if not exists idx_webhook_delivery_webhook_id_created_at on webhook_delivery (webhook_id, created_at);
+
+-- Table: webhook_delivery_attempt
+create table if not exists webhook_delivery_attempt
+(
+    id          uuid        not null,
+    delivery_id uuid        not null,
+    created_at  timestamptz not null,
+    status_code integer,
+    error       text,
+    duration_ms bigint      not null
+);
+
+alter table webhook_delivery_attempt
+    add constraint pk_webhook_delivery_attempt primary key (id);
+
+alter table webhook_delivery_attempt
+    add constraint fk_webhook_delivery_attempt_webhook_delivery foreign key (delivery_id) references webhook_delivery (id) on delete cascade;
+
+create index if not exists idx_webhook_delivery_attempt_delivery_id_created_at on webhook_delivery_attempt (delivery_id, created_at);
+
+-- Table: mail
+create table if not exists mail
+(
+    id              uuid           not null,
+    created_at      timestamptz    not null,
+    recipients      varchar(255)[] not null,
+    subject         text           not null,
+    data            jsonb          not null,
+    status          varchar(255)   not null,
+    attempts        integer        not null,
+    next_attempt_at timestamptz    not null,
+    last_error      text,
+    sent_at         timestamptz
+);
+
+alter table mail
+    add constraint pk_mail primary key (id);
+
+create index if not exists idx_mail_status_next_attempt_at on mail (status, next_attempt_at);
+create index if not exists idx_mail_created_at on mail (created_at);
+
+-- Table: mail_template
+create table if not exists mail_template
+(
+    id         uuid        not null,
+    code       varchar(50) not null,
+    locale     varchar(35) not null,
+    version    integer     not null,
+    subject    text        not null,
+    html_body  text        not null,
+    text_body  text,
+    created_at timestamptz not null,
+    updated_at timestamptz not null
+);
+
+alter table mail_template
+    add constraint pk_mail_template primary key (id);
+
+alter table mail_template
+    add constraint uq_mail_template_code_locale unique (code, locale);
+
+-- Table: mail_template_version
+create table if not exists mail_template_version
+(
+    mail_template_id uuid        not null,
+    version          integer     not null,
+    subject          text        not null,
+    html_body        text        not null,
+    text_body        text,
+    created_at       timestamptz not null
+);
+
+alter table mail_template_version
+    add constraint pk_mail_template_version primary key (mail_template_id, version);
+
+alter table mail_template_version
+    add constraint fk_mail_template_version_mail_template foreign key (mail_template_id) references mail_template (id) on delete cascade;
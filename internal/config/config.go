@@ -18,6 +18,7 @@ type ServerConfig struct {
 	SecurityConfig *SecurityConfig
 	CorsConfig     *CorsConfig
 	AppConfig      *AppConfig
+	WebhookConfig  *WebhookConfig
 }
 
 type DbConfig struct {
@@ -30,30 +31,70 @@ type DbConfig struct {
 }
 
 type MailConfig struct {
-	Host                         string
-	Port                         int
-	User                         string
-	Password                     string
-	AuthEnabled                  bool
-	TlsEnabled                   bool
-	SignUpMailSubject            string
-	SignUpMailTemplateUrl        string
-	ResetPasswordMailSubject     string
-	ResetPasswordMailTemplateUrl string
+	Host                             string
+	Port                             int
+	User                             string
+	Password                         string
+	AuthEnabled                      bool
+	TlsEnabled                       bool
+	Transport                        string
+	FileDir                          string
+	SignUpMailSubject                string
+	SignUpMailTemplateUrl            string
+	ResetPasswordMailSubject         string
+	ResetPasswordMailTemplateUrl     string
+	ChangeEmailMailSubject           string
+	ChangeEmailMailTemplateUrl       string
+	ChangeEmailNoticeMailSubject     string
+	ChangeEmailNoticeMailTemplateUrl string
+	InvitationMailSubject            string
+	InvitationMailTemplateUrl        string
+	NewSignInMailSubject             string
+	NewSignInMailTemplateUrl         string
+	QueueInterval                    time.Duration
+	QueueBatchSize                   int
+	QueueWorkers                     int
+	MaxAttempts                      int
+	RetryBackoff                     time.Duration
+	RetryBackoffMax                  time.Duration
+	Retention                        time.Duration
 }
 
 type SecurityConfig struct {
-	ReadAuthorities          []string
-	WriteAuthorities         []string
-	DefaultUsername          string
-	DefaultPassword          string
-	TokenIssuer              string
-	AccessTokenExpiresIn     time.Duration
-	AccessTokenJwkExpiresIn  time.Duration
-	RefreshTokenExpiresIn    time.Duration
-	RefreshTokenJwkExpiresIn time.Duration
-	ContentTokenExpiresIn    time.Duration
-	ContentTokenJwkExpiresIn time.Duration
+	ReadAuthorities             []string
+	WriteAuthorities            []string
+	DefaultUsername             string
+	DefaultPassword             string
+	TokenIssuer                 string
+	TokenAudience               []string
+	TokenAudienceLegacyMode     bool
+	TokenClaims                 map[string]string
+	TokenValidationMode         string
+	UserCacheSize               int
+	UserCacheTtl                time.Duration
+	AccessTokenExpiresIn        time.Duration
+	AccessTokenJwkExpiresIn     time.Duration
+	AccessTokenJwkAlgorithm     string
+	RefreshTokenExpiresIn       time.Duration
+	RefreshTokenJwkExpiresIn    time.Duration
+	RefreshTokenJwkAlgorithm    string
+	ContentTokenJwkExpiresIn    time.Duration
+	ContentTokenJwkAlgorithm    string
+	ConfirmUserTokenExpiresIn   time.Duration
+	ResetPasswordTokenExpiresIn time.Duration
+	ChangeEmailTokenExpiresIn   time.Duration
+	RevertEmailTokenExpiresIn   time.Duration
+	InvitationTokenExpiresIn    time.Duration
+	RevokeSessionTokenExpiresIn time.Duration
+	AuditJwkExpiresIn           time.Duration
+	AuditCheckpointInterval     time.Duration
+	JwkRsaKeySize               int
+	JwkPublishLeadTime          time.Duration
+	JwkRefreshInterval          time.Duration
+	JwkRevocationPollInterval   time.Duration
+	JwkGenerationEnabled        bool
+	JwkKekVersion               string
+	JwkKeks                     string
 }
 
 type CorsConfig struct {
@@ -69,13 +110,32 @@ type AppConfig struct {
 	CaptchaServiceUrl             string
 	ConfirmationWebUrl            string
 	ConfirmationPath              string
+	ResetPasswordPath             string
+	ResetPasswordLegacyMode       bool
+	InvitationPath                string
+	RevokeSessionPath             string
 	SignUpConfirmationMailEnabled bool
+	ConfirmationPolicy            string
+	ConfirmationGraceDays         int
 	PasswordCharacters            string
 	PasswordLength                int
+	PasswordMinLength             int
+	PasswordRequireUppercase      bool
+	PasswordRequireDigit          bool
+	PasswordRequireSpecial        bool
 	MandatoryUserAttributes       map[string]string
 	MandatoryUserAuthorities      []string
 }
 
+type WebhookConfig struct {
+	DispatchInterval time.Duration
+	BatchSize        int
+	Timeout          time.Duration
+	MaxAttempts      int
+	RetryBackoff     time.Duration
+	RetryBackoffMax  time.Duration
+}
+
 func InitConfig() *ServerConfig {
 	err := godotenv.Load(".env.local")
 	if err != nil {
@@ -96,29 +156,69 @@ func InitConfig() *ServerConfig {
 			MigrationsUrl:  common.Env("DB_MIGRATIONS_URL"),
 		},
 		MailConfig: &MailConfig{
-			Host:                         common.Env("MAIL_HOST"),
-			Port:                         common.EnvInt("MAIL_PORT"),
-			User:                         common.Env("MAIL_USER"),
-			Password:                     common.Env("MAIL_PASSWORD"),
-			AuthEnabled:                  common.EnvBool("MAIL_AUTH_ENABLED"),
-			TlsEnabled:                   common.EnvBool("MAIL_TLS_ENABLED"),
-			SignUpMailSubject:            common.Env("MAIL_SIGN_UP_MAIL_SUBJECT"),
-			SignUpMailTemplateUrl:        common.Env("MAIL_SIGN_UP_MAIL_TEMPLATE_URL"),
-			ResetPasswordMailSubject:     common.Env("MAIL_RESET_PASSWORD_MAIL_SUBJECT"),
-			ResetPasswordMailTemplateUrl: common.Env("MAIL_RESET_PASSWORD_MAIL_TEMPLATE_URL"),
+			Host:                             common.Env("MAIL_HOST"),
+			Port:                             common.EnvInt("MAIL_PORT"),
+			User:                             common.Env("MAIL_USER"),
+			Password:                         common.Env("MAIL_PASSWORD"),
+			AuthEnabled:                      common.EnvBool("MAIL_AUTH_ENABLED"),
+			TlsEnabled:                       common.EnvBool("MAIL_TLS_ENABLED"),
+			Transport:                        common.Env("MAIL_TRANSPORT"),
+			FileDir:                          common.Env("MAIL_FILE_DIR"),
+			SignUpMailSubject:                common.Env("MAIL_SIGN_UP_MAIL_SUBJECT"),
+			SignUpMailTemplateUrl:            common.Env("MAIL_SIGN_UP_MAIL_TEMPLATE_URL"),
+			ResetPasswordMailSubject:         common.Env("MAIL_RESET_PASSWORD_MAIL_SUBJECT"),
+			ResetPasswordMailTemplateUrl:     common.Env("MAIL_RESET_PASSWORD_MAIL_TEMPLATE_URL"),
+			ChangeEmailMailSubject:           common.Env("MAIL_CHANGE_EMAIL_MAIL_SUBJECT"),
+			ChangeEmailMailTemplateUrl:       common.Env("MAIL_CHANGE_EMAIL_MAIL_TEMPLATE_URL"),
+			ChangeEmailNoticeMailSubject:     common.Env("MAIL_CHANGE_EMAIL_NOTICE_MAIL_SUBJECT"),
+			ChangeEmailNoticeMailTemplateUrl: common.Env("MAIL_CHANGE_EMAIL_NOTICE_MAIL_TEMPLATE_URL"),
+			InvitationMailSubject:            common.Env("MAIL_INVITATION_MAIL_SUBJECT"),
+			InvitationMailTemplateUrl:        common.Env("MAIL_INVITATION_MAIL_TEMPLATE_URL"),
+			NewSignInMailSubject:             common.Env("MAIL_NEW_SIGN_IN_MAIL_SUBJECT"),
+			NewSignInMailTemplateUrl:         common.Env("MAIL_NEW_SIGN_IN_MAIL_TEMPLATE_URL"),
+			QueueInterval:                    time.Duration(common.EnvInt("MAIL_QUEUE_INTERVAL")) * time.Second,
+			QueueBatchSize:                   common.EnvInt("MAIL_QUEUE_BATCH_SIZE"),
+			QueueWorkers:                     common.EnvInt("MAIL_QUEUE_WORKERS"),
+			MaxAttempts:                      common.EnvInt("MAIL_MAX_ATTEMPTS"),
+			RetryBackoff:                     time.Duration(common.EnvInt("MAIL_RETRY_BACKOFF")) * time.Second,
+			RetryBackoffMax:                  time.Duration(common.EnvInt("MAIL_RETRY_BACKOFF_MAX")) * time.Second,
+			Retention:                        time.Duration(common.EnvInt("MAIL_RETENTION")) * time.Hour,
 		},
 		SecurityConfig: &SecurityConfig{
-			ReadAuthorities:          common.EnvSlice("SECURITY_READ_AUTHORITIES"),
-			WriteAuthorities:         common.EnvSlice("SECURITY_WRITE_AUTHORITIES"),
-			DefaultUsername:          common.Env("SECURITY_DEFAULT_USERNAME"),
-			DefaultPassword:          common.Env("SECURITY_DEFAULT_PASSWORD"),
-			TokenIssuer:              common.Env("SECURITY_TOKEN_ISSUER"),
-			AccessTokenExpiresIn:     time.Duration(common.EnvInt("SECURITY_ACCESS_TOKEN_EXPIRES_IN")) * time.Minute,
-			AccessTokenJwkExpiresIn:  time.Duration(common.EnvInt("SECURITY_ACCESS_TOKEN_JWK_EXPIRES_IN")) * time.Minute,
-			RefreshTokenExpiresIn:    time.Duration(common.EnvInt("SECURITY_REFRESH_TOKEN_EXPIRES_IN")) * time.Minute,
-			RefreshTokenJwkExpiresIn: time.Duration(common.EnvInt("SECURITY_REFRESH_TOKEN_JWK_EXPIRES_IN")) * time.Minute,
-			ContentTokenExpiresIn:    time.Duration(common.EnvInt("SECURITY_CONTENT_TOKEN_EXPIRES_IN")) * time.Minute,
-			ContentTokenJwkExpiresIn: time.Duration(common.EnvInt("SECURITY_CONTENT_TOKEN_JWK_EXPIRES_IN")) * time.Minute,
+			ReadAuthorities:             common.EnvSlice("SECURITY_READ_AUTHORITIES"),
+			WriteAuthorities:            common.EnvSlice("SECURITY_WRITE_AUTHORITIES"),
+			DefaultUsername:             common.Env("SECURITY_DEFAULT_USERNAME"),
+			DefaultPassword:             common.Env("SECURITY_DEFAULT_PASSWORD"),
+			TokenIssuer:                 common.Env("SECURITY_TOKEN_ISSUER"),
+			TokenAudience:               common.EnvSlice("SECURITY_TOKEN_AUDIENCE"),
+			TokenAudienceLegacyMode:     common.EnvBool("SECURITY_TOKEN_AUDIENCE_LEGACY_MODE"),
+			TokenClaims:                 common.EnvMap("SECURITY_TOKEN_CLAIMS"),
+			TokenValidationMode:         common.Env("SECURITY_TOKEN_VALIDATION_MODE"),
+			UserCacheSize:               common.EnvInt("SECURITY_USER_CACHE_SIZE"),
+			UserCacheTtl:                time.Duration(common.EnvInt("SECURITY_USER_CACHE_TTL")) * time.Second,
+			AccessTokenExpiresIn:        time.Duration(common.EnvInt("SECURITY_ACCESS_TOKEN_EXPIRES_IN")) * time.Minute,
+			AccessTokenJwkExpiresIn:     time.Duration(common.EnvInt("SECURITY_ACCESS_TOKEN_JWK_EXPIRES_IN")) * time.Minute,
+			AccessTokenJwkAlgorithm:     common.Env("SECURITY_ACCESS_TOKEN_JWK_ALGORITHM"),
+			RefreshTokenExpiresIn:       time.Duration(common.EnvInt("SECURITY_REFRESH_TOKEN_EXPIRES_IN")) * time.Minute,
+			RefreshTokenJwkExpiresIn:    time.Duration(common.EnvInt("SECURITY_REFRESH_TOKEN_JWK_EXPIRES_IN")) * time.Minute,
+			RefreshTokenJwkAlgorithm:    common.Env("SECURITY_REFRESH_TOKEN_JWK_ALGORITHM"),
+			ContentTokenJwkExpiresIn:    time.Duration(common.EnvInt("SECURITY_CONTENT_TOKEN_JWK_EXPIRES_IN")) * time.Minute,
+			ContentTokenJwkAlgorithm:    common.Env("SECURITY_CONTENT_TOKEN_JWK_ALGORITHM"),
+			ConfirmUserTokenExpiresIn:   time.Duration(common.EnvInt("SECURITY_CONFIRM_USER_TOKEN_EXPIRES_IN")) * time.Minute,
+			ResetPasswordTokenExpiresIn: time.Duration(common.EnvInt("SECURITY_RESET_PASSWORD_TOKEN_EXPIRES_IN")) * time.Minute,
+			ChangeEmailTokenExpiresIn:   time.Duration(common.EnvInt("SECURITY_CHANGE_EMAIL_TOKEN_EXPIRES_IN")) * time.Minute,
+			RevertEmailTokenExpiresIn:   time.Duration(common.EnvInt("SECURITY_REVERT_EMAIL_TOKEN_EXPIRES_IN")) * time.Minute,
+			InvitationTokenExpiresIn:    time.Duration(common.EnvInt("SECURITY_INVITATION_TOKEN_EXPIRES_IN")) * time.Minute,
+			RevokeSessionTokenExpiresIn: time.Duration(common.EnvInt("SECURITY_REVOKE_SESSION_TOKEN_EXPIRES_IN")) * time.Minute,
+			AuditJwkExpiresIn:           time.Duration(common.EnvInt("SECURITY_AUDIT_JWK_EXPIRES_IN")) * time.Minute,
+			AuditCheckpointInterval:     time.Duration(common.EnvInt("SECURITY_AUDIT_CHECKPOINT_INTERVAL")) * time.Minute,
+			JwkRsaKeySize:               common.EnvInt("SECURITY_JWK_RSA_KEY_SIZE"),
+			JwkPublishLeadTime:          time.Duration(common.EnvInt("SECURITY_JWK_PUBLISH_LEAD_TIME")) * time.Minute,
+			JwkRefreshInterval:          time.Duration(common.EnvInt("SECURITY_JWK_REFRESH_INTERVAL")) * time.Second,
+			JwkRevocationPollInterval:   time.Duration(common.EnvInt("SECURITY_JWK_REVOCATION_POLL_INTERVAL")) * time.Second,
+			JwkGenerationEnabled:        common.EnvBool("SECURITY_JWK_GENERATION_ENABLED"),
+			JwkKekVersion:               common.Env("SECURITY_JWK_KEK_VERSION"),
+			JwkKeks:                     common.Env("SECURITY_JWK_KEKS"),
 		},
 		CorsConfig: &CorsConfig{
 			AllowedOrigins:   common.EnvSlice("CORS_ALLOWED_ORIGINS"),
@@ -132,11 +232,29 @@ func InitConfig() *ServerConfig {
 			CaptchaServiceUrl:             common.Env("APP_CAPTCHA_SERVICE_URL"),
 			ConfirmationWebUrl:            common.Env("APP_CONFIRMATION_WEB_URL"),
 			ConfirmationPath:              common.Env("APP_CONFIRMATION_PATH"),
+			ResetPasswordPath:             common.Env("APP_RESET_PASSWORD_PATH"),
+			ResetPasswordLegacyMode:       common.EnvBool("APP_RESET_PASSWORD_LEGACY_MODE"),
+			InvitationPath:                common.Env("APP_INVITATION_PATH"),
+			RevokeSessionPath:             common.Env("APP_REVOKE_SESSION_PATH"),
 			SignUpConfirmationMailEnabled: common.EnvBool("APP_SIGN_UP_MAIL_CONFIRMATION"),
+			ConfirmationPolicy:            common.Env("APP_CONFIRMATION_POLICY"),
+			ConfirmationGraceDays:         common.EnvInt("APP_CONFIRMATION_GRACE_DAYS"),
 			PasswordCharacters:            common.Env("APP_PASSWORD_CHARACTERS"),
 			PasswordLength:                common.EnvInt("APP_PASSWORD_LENGTH"),
+			PasswordMinLength:             common.EnvInt("APP_PASSWORD_MIN_LENGTH"),
+			PasswordRequireUppercase:      common.EnvBool("APP_PASSWORD_REQUIRE_UPPERCASE"),
+			PasswordRequireDigit:          common.EnvBool("APP_PASSWORD_REQUIRE_DIGIT"),
+			PasswordRequireSpecial:        common.EnvBool("APP_PASSWORD_REQUIRE_SPECIAL"),
 			MandatoryUserAttributes:       common.EnvMap("APP_MANDATORY_USER_ATTRIBUTES"),
 			MandatoryUserAuthorities:      common.EnvSlice("APP_MANDATORY_USER_AUTHORITIES"),
 		},
+		WebhookConfig: &WebhookConfig{
+			DispatchInterval: time.Duration(common.EnvInt("WEBHOOK_DISPATCH_INTERVAL")) * time.Second,
+			BatchSize:        common.EnvInt("WEBHOOK_BATCH_SIZE"),
+			Timeout:          time.Duration(common.EnvInt("WEBHOOK_TIMEOUT")) * time.Second,
+			MaxAttempts:      common.EnvInt("WEBHOOK_MAX_ATTEMPTS"),
+			RetryBackoff:     time.Duration(common.EnvInt("WEBHOOK_RETRY_BACKOFF")) * time.Second,
+			RetryBackoffMax:  time.Duration(common.EnvInt("WEBHOOK_RETRY_BACKOFF_MAX")) * time.Second,
+		},
 	}
 }
@@ -18,6 +18,8 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
+type txContextKey struct{}
+
 type DataSource struct {
 	Pool    *pgxpool.Pool
 	Queries *sqlc.Queries
@@ -84,10 +86,7 @@ func (ds *DataSource) Close() {
 }
 
 func (ds *DataSource) ExecTx(ctx context.Context, fn func(*sqlc.Queries) (interface{}, error)) (interface{}, error) {
-	tx, err := ds.Pool.BeginTx(ctx, pgx.TxOptions{
-		IsoLevel:   pgx.ReadCommitted,
-		AccessMode: pgx.ReadWrite,
-	})
+	tx, err := ds.beginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -104,3 +103,37 @@ func (ds *DataSource) ExecTx(ctx context.Context, fn func(*sqlc.Queries) (interf
 
 	return result, tx.Commit(ctx)
 }
+
+func (ds *DataSource) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := ds.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("rollback failed: %v, original error: %w", rbErr, err)
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (ds *DataSource) QueriesFor(ctx context.Context) *sqlc.Queries {
+	if tx, ok := ctx.Value(txContextKey{}).(pgx.Tx); ok {
+		return ds.Queries.WithTx(tx)
+	}
+	return ds.Queries
+}
+
+func (ds *DataSource) beginTx(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(txContextKey{}).(pgx.Tx); ok {
+		return tx.Begin(ctx)
+	}
+
+	return ds.Pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.ReadCommitted,
+		AccessMode: pgx.ReadWrite,
+	})
+}
@@ -34,7 +34,7 @@ func NewAttributeRepository(dataSource *db.DataSource) AttributeRepository {
 }
 
 func (a *attributeRepositoryImpl) AddAttribute(ctx context.Context, data *AttributeData) (*Attribute, error) {
-	attribute, err := a.dataSource.Queries.AddAttribute(ctx, sqlc.AddAttributeParams{
+	attribute, err := a.dataSource.QueriesFor(ctx).AddAttribute(ctx, sqlc.AddAttributeParams{
 		ID:       db2.NewUUID(),
 		Key:      data.Key,
 		Required: data.Required,
@@ -49,26 +49,26 @@ func (a *attributeRepositoryImpl) AddAttribute(ctx context.Context, data *Attrib
 }
 
 func (a *attributeRepositoryImpl) CountById(ctx context.Context, id pgtype.UUID) (int64, error) {
-	return a.dataSource.Queries.CountAttributesById(ctx, id)
+	return a.dataSource.QueriesFor(ctx).CountAttributesById(ctx, id)
 }
 
 func (a *attributeRepositoryImpl) CountByKey(ctx context.Context, key string) (int64, error) {
-	return a.dataSource.Queries.CountAttributesByKey(ctx, key)
+	return a.dataSource.QueriesFor(ctx).CountAttributesByKey(ctx, key)
 }
 
 func (a *attributeRepositoryImpl) CountByKeyAndNotId(ctx context.Context, key string, id pgtype.UUID) (int64, error) {
-	return a.dataSource.Queries.CountAttributesByKeyNotId(ctx, sqlc.CountAttributesByKeyNotIdParams{
+	return a.dataSource.QueriesFor(ctx).CountAttributesByKeyNotId(ctx, sqlc.CountAttributesByKeyNotIdParams{
 		Key: key,
 		ID:  id,
 	})
 }
 
 func (a *attributeRepositoryImpl) DeleteAttributeById(ctx context.Context, id pgtype.UUID) error {
-	return a.dataSource.Queries.DeleteAttributeById(ctx, id)
+	return a.dataSource.QueriesFor(ctx).DeleteAttributeById(ctx, id)
 }
 
 func (a *attributeRepositoryImpl) GetAllAttributes(ctx context.Context) ([]*Attribute, error) {
-	attributes, err := a.dataSource.Queries.GetAllAttributes(ctx)
+	attributes, err := a.dataSource.QueriesFor(ctx).GetAllAttributes(ctx)
 
 	if err != nil {
 		return nil, err
@@ -82,7 +82,7 @@ func (a *attributeRepositoryImpl) GetAllAttributes(ctx context.Context) ([]*Attr
 }
 
 func (a *attributeRepositoryImpl) GetAttributeById(ctx context.Context, id pgtype.UUID) (*Attribute, error) {
-	attribute, err := a.dataSource.Queries.GetAttributeById(ctx, id)
+	attribute, err := a.dataSource.QueriesFor(ctx).GetAttributeById(ctx, id)
 
 	if err != nil {
 		return nil, err
@@ -92,7 +92,7 @@ func (a *attributeRepositoryImpl) GetAttributeById(ctx context.Context, id pgtyp
 }
 
 func (a *attributeRepositoryImpl) GetAttributeByKey(ctx context.Context, key string) (*Attribute, error) {
-	attribute, err := a.dataSource.Queries.GetAttributeByKey(ctx, key)
+	attribute, err := a.dataSource.QueriesFor(ctx).GetAttributeByKey(ctx, key)
 
 	if err != nil {
 		return nil, err
@@ -118,7 +118,7 @@ func (a *attributeRepositoryImpl) SearchAttributes(ctx context.Context, criteria
 }
 
 func (a *attributeRepositoryImpl) SetAttribute(ctx context.Context, id pgtype.UUID, data *AttributeData) (*Attribute, error) {
-	attribute, err := a.dataSource.Queries.SetAttribute(ctx, sqlc.SetAttributeParams{
+	attribute, err := a.dataSource.QueriesFor(ctx).SetAttribute(ctx, sqlc.SetAttributeParams{
 		ID:       id,
 		Key:      data.Key,
 		Required: data.Required,
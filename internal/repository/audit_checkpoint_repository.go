@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type AuditCheckpointRepository interface {
+	AddAuditCheckpoint(ctx context.Context, data *AuditCheckpointData) (*AuditCheckpoint, error)
+	GetAuditCheckpoints(ctx context.Context) ([]*AuditCheckpoint, error)
+	GetLastAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error)
+}
+
+type auditCheckpointRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewAuditCheckpointRepository(dataSource *db.DataSource) AuditCheckpointRepository {
+	return &auditCheckpointRepositoryImpl{dataSource}
+}
+
+func (a *auditCheckpointRepositoryImpl) AddAuditCheckpoint(ctx context.Context, data *AuditCheckpointData) (*AuditCheckpoint, error) {
+	auditCheckpoint, err := a.dataSource.QueriesFor(ctx).AddAuditCheckpoint(ctx, sqlc.AddAuditCheckpointParams{
+		ID:        db2.NewUUID(),
+		Seq:       data.Seq,
+		Hash:      data.Hash,
+		Kid:       data.Kid,
+		Signature: data.Signature,
+		CreatedAt: db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuditCheckpoint(&auditCheckpoint), nil
+}
+
+func (a *auditCheckpointRepositoryImpl) GetAuditCheckpoints(ctx context.Context) ([]*AuditCheckpoint, error) {
+	auditCheckpoints, err := a.dataSource.QueriesFor(ctx).GetAuditCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*AuditCheckpoint, len(auditCheckpoints))
+	for i, auditCheckpoint := range auditCheckpoints {
+		result[i] = toAuditCheckpoint(&auditCheckpoint)
+	}
+	return result, nil
+}
+
+func (a *auditCheckpointRepositoryImpl) GetLastAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error) {
+	auditCheckpoint, err := a.dataSource.QueriesFor(ctx).GetLastAuditCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuditCheckpoint(&auditCheckpoint), nil
+}
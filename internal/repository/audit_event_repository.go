@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+const auditEventSelect = "select a.id, a.created_at, a.actor_id, a.actor_email, a.action, a.target_type, a.target_id, a.ip, a.user_agent, a.before, a.after, a.seq, a.prev_hash, a.hash from audit_event a"
+
+type AuditEventRepository interface {
+	AddAuditEvent(ctx context.Context, data *AuditEventData) (*AuditEvent, error)
+	ExportAuditChain(ctx context.Context, fn func(*AuditEvent) error) error
+	ExportAuditEvents(ctx context.Context, criteria *SearchAuditEventsCriteria, fn func(*AuditEvent) error) error
+	GetLastAuditEvent(ctx context.Context) (*AuditEvent, error)
+	SearchAuditEvents(ctx context.Context, criteria *SearchAuditEventsCriteria, pageable *common.Pageable) (*common.Page[*AuditEvent], error)
+}
+
+type auditEventRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewAuditEventRepository(dataSource *db.DataSource) AuditEventRepository {
+	return &auditEventRepositoryImpl{dataSource}
+}
+
+func (a *auditEventRepositoryImpl) AddAuditEvent(ctx context.Context, data *AuditEventData) (*AuditEvent, error) {
+	auditEvent, err := a.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
+		// the chain is global, so audited writes serialize from here until their transaction commits; audited() records
+		// after the change itself to keep that window short
+		if err := q.LockAuditChain(ctx); err != nil {
+			return nil, err
+		}
+
+		prevHash := ""
+		last, err := q.GetLastAuditEvent(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			prevHash = fromText(last.Hash)
+		}
+
+		auditEvent := &AuditEvent{
+			ID:         db2.NewUUID(),
+			CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+			ActorID:    data.ActorID,
+			ActorEmail: data.ActorEmail,
+			Action:     data.Action,
+			TargetType: data.TargetType,
+			TargetID:   data.TargetID,
+			IP:         data.IP,
+			UserAgent:  data.UserAgent,
+			Before:     data.Before,
+			After:      data.After,
+			PrevHash:   prevHash,
+		}
+
+		hash, err := AuditEventHash(auditEvent)
+		if err != nil {
+			return nil, err
+		}
+
+		created, err := q.AddAuditEvent(ctx, sqlc.AddAuditEventParams{
+			ID:         auditEvent.ID,
+			CreatedAt:  db2.TimestampUTC(auditEvent.CreatedAt),
+			ActorID:    toText(auditEvent.ActorID),
+			ActorEmail: toText(auditEvent.ActorEmail),
+			Action:     auditEvent.Action,
+			TargetType: auditEvent.TargetType,
+			TargetID:   toText(auditEvent.TargetID),
+			Ip:         toText(auditEvent.IP),
+			UserAgent:  toText(auditEvent.UserAgent),
+			Before:     auditEvent.Before,
+			After:      auditEvent.After,
+			PrevHash:   toText(auditEvent.PrevHash),
+			Hash:       toText(hash),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &created, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	createdAuditEvent, ok := auditEvent.(*sqlc.AuditEvent)
+	if !ok {
+		return nil, fmt.Errorf("invalid audit event type: %T", auditEvent)
+	}
+
+	return toAuditEvent(createdAuditEvent), nil
+}
+
+func (a *auditEventRepositoryImpl) ExportAuditChain(ctx context.Context, fn func(*AuditEvent) error) error {
+	return a.export(ctx, auditEventSelect+" order by a.seq asc", nil, fn)
+}
+
+func (a *auditEventRepositoryImpl) ExportAuditEvents(ctx context.Context, criteria *SearchAuditEventsCriteria, fn func(*AuditEvent) error) error {
+	var query strings.Builder
+	query.WriteString(auditEventSelect)
+
+	paramIndex := 1
+	conditions, parameters := a.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by a.created_at asc, a.id asc")
+
+	return a.export(ctx, query.String(), parameters, fn)
+}
+
+func (a *auditEventRepositoryImpl) GetLastAuditEvent(ctx context.Context) (*AuditEvent, error) {
+	auditEvent, err := a.dataSource.QueriesFor(ctx).GetLastAuditEvent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuditEvent(&auditEvent), nil
+}
+
+func (a *auditEventRepositoryImpl) SearchAuditEvents(ctx context.Context, criteria *SearchAuditEventsCriteria, pageable *common.Pageable) (*common.Page[*AuditEvent], error) {
+	totalRows, err := a.countAuditEvents(ctx, criteria)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := a.searchAuditEvents(ctx, criteria, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*AuditEvent](pageable, totalRows, content), nil
+}
+
+func (a *auditEventRepositoryImpl) countAuditEvents(ctx context.Context, criteria *SearchAuditEventsCriteria) (int64, error) {
+	var query strings.Builder
+	query.WriteString("select count(*) from audit_event a")
+
+	paramIndex := 1
+	conditions, parameters := a.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	row := a.dataSource.Pool.QueryRow(ctx, query.String(), parameters...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (a *auditEventRepositoryImpl) searchAuditEvents(ctx context.Context, criteria *SearchAuditEventsCriteria, pageable *common.Pageable) ([]*AuditEvent, error) {
+	var query strings.Builder
+	query.WriteString(auditEventSelect)
+
+	paramIndex := 1
+	conditions, parameters := a.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by " + pageable.Sort)
+	query.WriteString(fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+
+	rows, err := a.dataSource.Pool.Query(ctx, query.String(), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*AuditEvent
+	for rows.Next() {
+		auditEvent, err := a.scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, auditEvent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (a *auditEventRepositoryImpl) export(ctx context.Context, query string, parameters []interface{}, fn func(*AuditEvent) error) error {
+	rows, err := a.dataSource.Pool.Query(ctx, query, parameters...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		auditEvent, err := a.scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(auditEvent); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (a *auditEventRepositoryImpl) scanAuditEvent(rows interface{ Scan(dest ...any) error }) (*AuditEvent, error) {
+	var auditEvent sqlc.AuditEvent
+	if err := rows.Scan(
+		&auditEvent.ID,
+		&auditEvent.CreatedAt,
+		&auditEvent.ActorID,
+		&auditEvent.ActorEmail,
+		&auditEvent.Action,
+		&auditEvent.TargetType,
+		&auditEvent.TargetID,
+		&auditEvent.Ip,
+		&auditEvent.UserAgent,
+		&auditEvent.Before,
+		&auditEvent.After,
+		&auditEvent.Seq,
+		&auditEvent.PrevHash,
+		&auditEvent.Hash,
+	); err != nil {
+		return nil, err
+	}
+	return toAuditEvent(&auditEvent), nil
+}
+
+func (a *auditEventRepositoryImpl) buildSearchQueryParts(criteria *SearchAuditEventsCriteria, paramIndex *int) (conditions []string, parameters []interface{}) {
+	conditions = []string{}
+	parameters = []interface{}{}
+
+	addCondition := func(format string, value interface{}) {
+		conditions = append(conditions, fmt.Sprintf(format, *paramIndex))
+		parameters = append(parameters, value)
+		*paramIndex++
+	}
+
+	if !common.IsBlank(criteria.ActorID) {
+		addCondition("a.actor_id = $%d", criteria.ActorID)
+	}
+
+	if !common.IsBlank(criteria.TargetID) {
+		addCondition("a.target_id = $%d", criteria.TargetID)
+	}
+
+	if !common.IsBlank(criteria.Action) {
+		addCondition("a.action = $%d", criteria.Action)
+	}
+
+	if criteria.From != nil {
+		addCondition("a.created_at >= $%d", db2.TimestampUTC(*criteria.From))
+	}
+
+	if criteria.To != nil {
+		addCondition("a.created_at < $%d", db2.TimestampUTC(*criteria.To))
+	}
+
+	return conditions, parameters
+}
+
+func AuditEventHash(auditEvent *AuditEvent) (string, error) {
+	before, err := canonicalJson(auditEvent.Before)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := canonicalJson(auditEvent.After)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := json.Marshal(struct {
+		PrevHash   string          `json:"prevHash"`
+		ID         string          `json:"id"`
+		CreatedAt  string          `json:"createdAt"`
+		ActorID    string          `json:"actorId"`
+		ActorEmail string          `json:"actorEmail"`
+		Action     string          `json:"action"`
+		TargetType string          `json:"targetType"`
+		TargetID   string          `json:"targetId"`
+		IP         string          `json:"ip"`
+		UserAgent  string          `json:"userAgent"`
+		Before     json.RawMessage `json:"before"`
+		After      json.RawMessage `json:"after"`
+	}{
+		PrevHash:   auditEvent.PrevHash,
+		ID:         auditEvent.ID.String(),
+		CreatedAt:  auditEvent.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ActorID:    auditEvent.ActorID,
+		ActorEmail: auditEvent.ActorEmail,
+		Action:     auditEvent.Action,
+		TargetType: auditEvent.TargetType,
+		TargetID:   auditEvent.TargetID,
+		IP:         auditEvent.IP,
+		UserAgent:  auditEvent.UserAgent,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func canonicalJson(data []byte) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return json.RawMessage("null"), nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
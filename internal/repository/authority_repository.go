@@ -34,7 +34,7 @@ func NewAuthorityRepository(dataSource *db.DataSource) AuthorityRepository {
 }
 
 func (a *authorityRepositoryImpl) AddAuthority(ctx context.Context, data *AuthorityData) (*Authority, error) {
-	authority, err := a.dataSource.Queries.AddAuthority(ctx, sqlc.AddAuthorityParams{
+	authority, err := a.dataSource.QueriesFor(ctx).AddAuthority(ctx, sqlc.AddAuthorityParams{
 		ID:        db2.NewUUID(),
 		Authority: data.Authority,
 	})
@@ -47,26 +47,26 @@ func (a *authorityRepositoryImpl) AddAuthority(ctx context.Context, data *Author
 }
 
 func (a *authorityRepositoryImpl) CountById(ctx context.Context, id pgtype.UUID) (int64, error) {
-	return a.dataSource.Queries.CountAuthoritiesById(ctx, id)
+	return a.dataSource.QueriesFor(ctx).CountAuthoritiesById(ctx, id)
 }
 
 func (a *authorityRepositoryImpl) CountByAuthority(ctx context.Context, authority string) (int64, error) {
-	return a.dataSource.Queries.CountAuthoritiesByAuthority(ctx, authority)
+	return a.dataSource.QueriesFor(ctx).CountAuthoritiesByAuthority(ctx, authority)
 }
 
 func (a *authorityRepositoryImpl) CountByAuthorityAndNotId(ctx context.Context, authority string, id pgtype.UUID) (int64, error) {
-	return a.dataSource.Queries.CountAuthoritiesByAuthorityNotId(ctx, sqlc.CountAuthoritiesByAuthorityNotIdParams{
+	return a.dataSource.QueriesFor(ctx).CountAuthoritiesByAuthorityNotId(ctx, sqlc.CountAuthoritiesByAuthorityNotIdParams{
 		Authority: authority,
 		ID:        id,
 	})
 }
 
 func (a *authorityRepositoryImpl) DeleteAuthorityById(ctx context.Context, id pgtype.UUID) error {
-	return a.dataSource.Queries.DeleteAuthorityById(ctx, id)
+	return a.dataSource.QueriesFor(ctx).DeleteAuthorityById(ctx, id)
 }
 
 func (a *authorityRepositoryImpl) GetAllAuthorities(ctx context.Context) ([]*Authority, error) {
-	authorities, err := a.dataSource.Queries.GetAllAuthorities(ctx)
+	authorities, err := a.dataSource.QueriesFor(ctx).GetAllAuthorities(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +79,7 @@ func (a *authorityRepositoryImpl) GetAllAuthorities(ctx context.Context) ([]*Aut
 }
 
 func (a *authorityRepositoryImpl) GetAuthorityById(ctx context.Context, id pgtype.UUID) (*Authority, error) {
-	dbAuthority, err := a.dataSource.Queries.GetAuthorityById(ctx, id)
+	dbAuthority, err := a.dataSource.QueriesFor(ctx).GetAuthorityById(ctx, id)
 
 	if err != nil {
 		return nil, err
@@ -89,7 +89,7 @@ func (a *authorityRepositoryImpl) GetAuthorityById(ctx context.Context, id pgtyp
 }
 
 func (a *authorityRepositoryImpl) GetAuthorityByAuthority(ctx context.Context, authority string) (*Authority, error) {
-	dbAuthority, err := a.dataSource.Queries.GetAuthorityByAuthority(ctx, authority)
+	dbAuthority, err := a.dataSource.QueriesFor(ctx).GetAuthorityByAuthority(ctx, authority)
 
 	if err != nil {
 		return nil, err
@@ -115,7 +115,7 @@ func (a *authorityRepositoryImpl) SearchAuthorities(ctx context.Context, criteri
 }
 
 func (a *authorityRepositoryImpl) SetAuthority(ctx context.Context, id pgtype.UUID, data *AuthorityData) (*Authority, error) {
-	authority, err := a.dataSource.Queries.SetAuthority(ctx, sqlc.SetAuthorityParams{
+	authority, err := a.dataSource.QueriesFor(ctx).SetAuthority(ctx, sqlc.SetAuthorityParams{
 		ID:        id,
 		Authority: data.Authority,
 	})
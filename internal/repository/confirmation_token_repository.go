@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type ConfirmationTokenRepository interface {
+	AddConfirmationToken(ctx context.Context, data ConfirmationTokenData) (*ConfirmationToken, error)
+	ConsumeConfirmationToken(ctx context.Context, data ConsumeConfirmationTokenData) (*ConfirmationToken, error)
+	InvalidateConfirmationTokens(ctx context.Context, userID pgtype.UUID, purpose string) error
+}
+
+type confirmationTokenRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewConfirmationTokenRepository(dataSource *db.DataSource) ConfirmationTokenRepository {
+	return &confirmationTokenRepositoryImpl{dataSource}
+}
+
+func (c *confirmationTokenRepositoryImpl) AddConfirmationToken(ctx context.Context, data ConfirmationTokenData) (*ConfirmationToken, error) {
+	token, err := c.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
+		now := time.Now()
+
+		if !data.KeepOutstanding {
+			err := q.InvalidateConfirmationTokens(ctx, sqlc.InvalidateConfirmationTokensParams{
+				UserID:     data.UserID,
+				Purpose:    data.Purpose,
+				ConsumedAt: db2.TimestampUTC(now),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := q.DeleteExpiredConfirmationTokens(ctx, sqlc.DeleteExpiredConfirmationTokensParams{
+			UserID:    data.UserID,
+			ExpiresAt: db2.TimestampUTC(now),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := q.AddConfirmationToken(ctx, sqlc.AddConfirmationTokenParams{
+			ID:        db2.NewUUID(),
+			UserID:    data.UserID,
+			Purpose:   data.Purpose,
+			CreatedAt: db2.TimestampUTC(now),
+			ExpiresAt: db2.TimestampUTC(now.Add(data.Expiration)),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &token, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	createdToken, ok := token.(*sqlc.ConfirmationToken)
+	if !ok {
+		return nil, fmt.Errorf("invalid confirmation token type: %T", token)
+	}
+
+	return toConfirmationToken(createdToken), nil
+}
+
+func (c *confirmationTokenRepositoryImpl) ConsumeConfirmationToken(ctx context.Context, data ConsumeConfirmationTokenData) (*ConfirmationToken, error) {
+	token, err := c.dataSource.QueriesFor(ctx).ConsumeConfirmationToken(ctx, sqlc.ConsumeConfirmationTokenParams{
+		ID:         data.ID,
+		UserID:     data.UserID,
+		Purpose:    data.Purpose,
+		ConsumedAt: db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toConfirmationToken(&token), nil
+}
+
+func (c *confirmationTokenRepositoryImpl) InvalidateConfirmationTokens(ctx context.Context, userID pgtype.UUID, purpose string) error {
+	return c.dataSource.QueriesFor(ctx).InvalidateConfirmationTokens(ctx, sqlc.InvalidateConfirmationTokensParams{
+		UserID:     userID,
+		Purpose:    purpose,
+		ConsumedAt: db2.TimestampUTC(time.Now()),
+	})
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type InvitationRepository interface {
+	AddInvitation(ctx context.Context, data *InvitationData) (*Invitation, error)
+	GetInvitationById(ctx context.Context, id pgtype.UUID) (*Invitation, error)
+	GetInvitationByUserId(ctx context.Context, userID pgtype.UUID) (*Invitation, error)
+	SearchInvitations(ctx context.Context, criteria *SearchInvitationsCriteria, pageable *common.Pageable) (*common.Page[*Invitation], error)
+	SetInvitationAccepted(ctx context.Context, id pgtype.UUID) (*Invitation, error)
+	SetInvitationExpiresAt(ctx context.Context, id pgtype.UUID, expiresAt time.Time) (*Invitation, error)
+	SetInvitationRevoked(ctx context.Context, id pgtype.UUID) (*Invitation, error)
+}
+
+type invitationRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewInvitationRepository(dataSource *db.DataSource) InvitationRepository {
+	return &invitationRepositoryImpl{dataSource}
+}
+
+func (i *invitationRepositoryImpl) AddInvitation(ctx context.Context, data *InvitationData) (*Invitation, error) {
+	now := time.Now()
+
+	invitation, err := i.dataSource.QueriesFor(ctx).AddInvitation(ctx, sqlc.AddInvitationParams{
+		ID:        db2.NewUUID(),
+		UserID:    data.UserID,
+		Email:     data.Email,
+		CreatedAt: db2.TimestampUTC(now),
+		ExpiresAt: db2.TimestampUTC(now.Add(data.Expiration)),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toInvitation(&invitation), nil
+}
+
+func (i *invitationRepositoryImpl) GetInvitationById(ctx context.Context, id pgtype.UUID) (*Invitation, error) {
+	invitation, err := i.dataSource.QueriesFor(ctx).GetInvitationById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toInvitation(&invitation), nil
+}
+
+func (i *invitationRepositoryImpl) GetInvitationByUserId(ctx context.Context, userID pgtype.UUID) (*Invitation, error) {
+	invitation, err := i.dataSource.QueriesFor(ctx).GetInvitationByUserId(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toInvitation(&invitation), nil
+}
+
+func (i *invitationRepositoryImpl) SearchInvitations(ctx context.Context, criteria *SearchInvitationsCriteria, pageable *common.Pageable) (*common.Page[*Invitation], error) {
+	totalRows, err := i.countInvitations(ctx, criteria)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := i.searchInvitations(ctx, criteria, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*Invitation](pageable, totalRows, content), nil
+}
+
+func (i *invitationRepositoryImpl) SetInvitationAccepted(ctx context.Context, id pgtype.UUID) (*Invitation, error) {
+	invitation, err := i.dataSource.QueriesFor(ctx).SetInvitationAccepted(ctx, sqlc.SetInvitationAcceptedParams{
+		ID:         id,
+		AcceptedAt: db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toInvitation(&invitation), nil
+}
+
+func (i *invitationRepositoryImpl) SetInvitationExpiresAt(ctx context.Context, id pgtype.UUID, expiresAt time.Time) (*Invitation, error) {
+	invitation, err := i.dataSource.QueriesFor(ctx).SetInvitationExpiresAt(ctx, sqlc.SetInvitationExpiresAtParams{
+		ID:        id,
+		ExpiresAt: db2.TimestampUTC(expiresAt),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toInvitation(&invitation), nil
+}
+
+func (i *invitationRepositoryImpl) SetInvitationRevoked(ctx context.Context, id pgtype.UUID) (*Invitation, error) {
+	invitation, err := i.dataSource.QueriesFor(ctx).SetInvitationRevoked(ctx, sqlc.SetInvitationRevokedParams{
+		ID:        id,
+		RevokedAt: db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toInvitation(&invitation), nil
+}
+
+func (i *invitationRepositoryImpl) countInvitations(ctx context.Context, criteria *SearchInvitationsCriteria) (int64, error) {
+	var query strings.Builder
+	query.WriteString("select count(*) from invitation i")
+
+	paramIndex := 1
+	conditions, parameters := i.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	row := i.dataSource.Pool.QueryRow(ctx, query.String(), parameters...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (i *invitationRepositoryImpl) searchInvitations(ctx context.Context, criteria *SearchInvitationsCriteria, pageable *common.Pageable) ([]*Invitation, error) {
+	var query strings.Builder
+	query.WriteString("select i.id, i.user_id, i.email, i.created_at, i.expires_at, i.accepted_at, i.revoked_at from invitation i")
+
+	paramIndex := 1
+	conditions, parameters := i.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by " + pageable.Sort)
+	query.WriteString(fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+
+	rows, err := i.dataSource.Pool.Query(ctx, query.String(), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*Invitation
+	for rows.Next() {
+		var invitation sqlc.Invitation
+		if err := rows.Scan(
+			&invitation.ID,
+			&invitation.UserID,
+			&invitation.Email,
+			&invitation.CreatedAt,
+			&invitation.ExpiresAt,
+			&invitation.AcceptedAt,
+			&invitation.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		content = append(content, toInvitation(&invitation))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (i *invitationRepositoryImpl) buildSearchQueryParts(criteria *SearchInvitationsCriteria, paramIndex *int) (conditions []string, parameters []interface{}) {
+	conditions = []string{}
+	parameters = []interface{}{}
+
+	searchValues := common.SplitWithoutBlank(common.ToScDf(criteria.SearchField), " ")
+	if len(searchValues) > 0 {
+		if cond, params := i.buildEmailSearchConditions(searchValues, paramIndex); cond != "" {
+			conditions = append(conditions, cond)
+			parameters = append(parameters, params...)
+		}
+	}
+
+	if cond, params := i.buildStatusCondition(criteria.Status, paramIndex); cond != "" {
+		conditions = append(conditions, cond)
+		parameters = append(parameters, params...)
+	}
+
+	return conditions, parameters
+}
+
+func (i *invitationRepositoryImpl) buildEmailSearchConditions(values []string, paramIndex *int) (string, []interface{}) {
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	params := make([]interface{}, 0, len(values))
+
+	sb.WriteString("(")
+	for j, val := range values {
+		if j > 0 {
+			sb.WriteString(" or ")
+		}
+		sb.WriteString(fmt.Sprintf("i.email like $%d", *paramIndex))
+		params = append(params, "%"+val+"%")
+		*paramIndex++
+	}
+	sb.WriteString(")")
+
+	return sb.String(), params
+}
+
+func (i *invitationRepositoryImpl) buildStatusCondition(status string, paramIndex *int) (string, []interface{}) {
+	var cond string
+	switch status {
+	case InvitationStatusPending:
+		cond = fmt.Sprintf("(i.accepted_at is null and i.revoked_at is null and i.expires_at >= $%d)", *paramIndex)
+	case InvitationStatusAccepted:
+		return "i.accepted_at is not null", nil
+	case InvitationStatusRevoked:
+		return "(i.accepted_at is null and i.revoked_at is not null)", nil
+	case InvitationStatusExpired:
+		cond = fmt.Sprintf("(i.accepted_at is null and i.revoked_at is null and i.expires_at < $%d)", *paramIndex)
+	default:
+		return "", nil
+	}
+	*paramIndex++
+	return cond, []interface{}{db2.TimestampUTC(time.Now())}
+}
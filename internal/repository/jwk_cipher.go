@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const jwkDekSize = 32
+
+// JwkCipher envelope-encrypts private keys: every key is sealed with its own random data encryption key (DEK) and the
+// DEK is wrapped with the current key encryption key (KEK). Rotating the KEK only re-wraps DEKs, older KEK versions
+// stay available for decryption until all keys are re-wrapped.
+type JwkCipher struct {
+	kekVersion string
+	keks       map[string]cipher.AEAD
+}
+
+func NewJwkCipher(kekVersion string, keks map[string][]byte) (*JwkCipher, error) {
+	if _, ok := keks[kekVersion]; !ok {
+		return nil, fmt.Errorf("kek version %s not configured", kekVersion)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keks))
+	for version, kek := range keks {
+		aead, err := newAead(kek)
+		if err != nil {
+			return nil, fmt.Errorf("kek version %s: %w", version, err)
+		}
+		aeads[version] = aead
+	}
+
+	return &JwkCipher{kekVersion: kekVersion, keks: aeads}, nil
+}
+
+func (jc *JwkCipher) KekVersion() string {
+	return jc.kekVersion
+}
+
+func (jc *JwkCipher) encrypt(id pgtype.UUID, plaintext []byte) ([]byte, []byte, error) {
+	dek := make([]byte, jwkDekSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newAead(dek)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err := seal(aead, plaintext, id.Bytes[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrappedDek, err := seal(jc.keks[jc.kekVersion], dek, id.Bytes[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, wrappedDek, nil
+}
+
+func (jc *JwkCipher) decrypt(id pgtype.UUID, kekVersion string, ciphertext, wrappedDek []byte) ([]byte, error) {
+	dek, err := jc.unwrap(id, kekVersion, wrappedDek)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAead(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(aead, ciphertext, id.Bytes[:])
+}
+
+func (jc *JwkCipher) rewrap(id pgtype.UUID, kekVersion string, wrappedDek []byte) ([]byte, error) {
+	dek, err := jc.unwrap(id, kekVersion, wrappedDek)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(jc.keks[jc.kekVersion], dek, id.Bytes[:])
+}
+
+func (jc *JwkCipher) unwrap(id pgtype.UUID, kekVersion string, wrappedDek []byte) ([]byte, error) {
+	kek, ok := jc.keks[kekVersion]
+	if !ok {
+		return nil, fmt.Errorf("kek version %s not configured", kekVersion)
+	}
+
+	dek, err := open(kek, wrappedDek, id.Bytes[:])
+	if err != nil {
+		return nil, fmt.Errorf("unwrap dek: %w", err)
+	}
+	return dek, nil
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, additionalData)
+}
@@ -2,6 +2,10 @@ package repository
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -15,59 +19,131 @@ import (
 
 type JwkRepository interface {
 	AddJwk(ctx context.Context, data JwkData) (*Jwk, error)
+	ImportJwk(ctx context.Context, data JwkImportData) (*Jwk, error)
 	GetActiveJwk(ctx context.Context, use string) (*Jwk, error)
+	GetPendingJwk(ctx context.Context, use string) (*Jwk, error)
 	GetJwk(ctx context.Context, id pgtype.UUID) (*Jwk, error)
-	GetActiveJwks(ctx context.Context) ([]*Jwk, error)
+	GetJwkByKid(ctx context.Context, kid string) (*Jwk, error)
+	GetJwks(ctx context.Context) ([]*Jwk, error)
+	GetPublishedJwks(ctx context.Context) ([]*Jwk, error)
+	GetRevokedJwkKids(ctx context.Context) ([]string, error)
+	LockJwks(ctx context.Context, use string) error
+	RewrapJwks(ctx context.Context) (int, error)
+	SetJwkRevoked(ctx context.Context, id pgtype.UUID) (*Jwk, error)
 }
 
 type jwkRepositoryImpl struct {
 	dataSource *db.DataSource
+	jwkCipher  *JwkCipher
 }
 
-func NewJwkRepository(dataSource *db.DataSource) JwkRepository {
-	return &jwkRepositoryImpl{dataSource}
+func NewJwkRepository(dataSource *db.DataSource, jwkCipher *JwkCipher) JwkRepository {
+	return &jwkRepositoryImpl{dataSource, jwkCipher}
 }
 
 func (j *jwkRepositoryImpl) AddJwk(ctx context.Context, data JwkData) (*Jwk, error) {
+	privateKey, err := generateKey(data.Alg, data.RsaKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	// a generated key replaces any other key still waiting for its activation
+	return j.saveJwk(ctx, JwkImportData{
+		Use:             data.Use,
+		Alg:             data.Alg,
+		PrivateKey:      privateKey,
+		ActivatesAt:     data.ActivatesAt,
+		ExpiresAt:       data.ActivatesAt.Add(data.Expiration),
+		TokenExpiration: data.TokenExpiration,
+	}, true)
+}
+
+func (j *jwkRepositoryImpl) ImportJwk(ctx context.Context, data JwkImportData) (*Jwk, error) {
+	return j.saveJwk(ctx, data, false)
+}
+
+func (j *jwkRepositoryImpl) saveJwk(ctx context.Context, data JwkImportData, generated bool) (*Jwk, error) {
 	jwk, err := j.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
-		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		kty, err := keyType(data.PrivateKey)
 		if err != nil {
 			return nil, err
 		}
-		publicKey := &privateKey.PublicKey
 
-		privatePEM := encodePrivateKey(privateKey)
-		publicPEM, err := encodePublicKey(publicKey)
+		publicPEM, err := encodePublicKey(data.PrivateKey.Public())
 		if err != nil {
 			return nil, err
 		}
 
+		privatePEM, err := encodePrivateKey(data.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		id := db2.NewUUID()
+		encryptedPrivateKey, privateKeyDek, err := j.jwkCipher.encrypt(id, privatePEM)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := data.Kid
+		if kid == "" {
+			kid = id.String()
+		}
+
 		now := time.Now()
 
 		jwk, err := q.AddJwk(ctx, sqlc.AddJwkParams{
-			ID:         db2.NewUUID(),
-			Kty:        "RSA",
-			Use:        data.Use,
-			Alg:        "RS256",
-			PublicKey:  publicPEM,
-			PrivateKey: privatePEM,
-			Active:     true,
-			CreatedAt:  db2.TimestampUTC(now),
-			ExpiresAt:  db2.TimestampUTC(now.Add(data.Expiration)),
+			ID:             id,
+			Kid:            kid,
+			Kty:            kty,
+			Use:            data.Use,
+			Alg:            data.Alg,
+			PublicKey:      publicPEM,
+			PrivateKey:     encryptedPrivateKey,
+			PrivateKeyDek:  privateKeyDek,
+			KekVersion:     toText(j.jwkCipher.KekVersion()),
+			CreatedAt:      db2.TimestampUTC(now),
+			ActivatesAt:    db2.TimestampUTC(data.ActivatesAt),
+			ExpiresAt:      db2.TimestampUTC(data.ExpiresAt),
+			PublishedUntil: db2.TimestampUTC(data.ExpiresAt.Add(data.TokenExpiration)),
+			Imported:       !generated,
 		})
 
 		if err != nil {
 			return nil, err
 		}
 
-		err = q.DeactivateJwks(ctx, sqlc.DeactivateJwksParams{ID: jwk.ID, Use: data.Use})
+		// imported pending keys are left alone, they were scheduled on purpose
+		if generated {
+			err = q.DeletePendingJwks(ctx, sqlc.DeletePendingJwksParams{
+				ID:          jwk.ID,
+				Use:         data.Use,
+				ActivatesAt: db2.TimestampUTC(now),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// older keys stop signing once the new key activates and stay published until their last token expires, a
+		// back-dated key retires them now because they signed tokens up to this moment
+		retireAt := data.ActivatesAt
+		if retireAt.Before(now) {
+			retireAt = now
+		}
+		err = q.RetireJwks(ctx, sqlc.RetireJwksParams{
+			ID:             jwk.ID,
+			Use:            data.Use,
+			ExpiresAt:      db2.TimestampUTC(retireAt),
+			PublishedUntil: db2.TimestampUTC(retireAt.Add(data.TokenExpiration)),
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		err = q.DeleteNotActiveJwks(ctx, sqlc.DeleteNotActiveJwksParams{
-			Use:       data.Use,
-			ExpiresAt: db2.TimestampUTC(now),
+		err = q.DeleteExpiredJwks(ctx, sqlc.DeleteExpiredJwksParams{
+			Use:            data.Use,
+			PublishedUntil: db2.TimestampUTC(now),
 		})
 		if err != nil {
 			return nil, err
@@ -85,39 +161,146 @@ func (j *jwkRepositoryImpl) AddJwk(ctx context.Context, data JwkData) (*Jwk, err
 		return nil, fmt.Errorf("invalid jwk type: %T", jwk)
 	}
 
-	return toJwk(createdJwk)
+	return toJwk(createdJwk, j.jwkCipher)
 }
 
 func (j *jwkRepositoryImpl) GetActiveJwk(ctx context.Context, use string) (*Jwk, error) {
-	jwk, err := j.dataSource.Queries.GetActiveJwk(ctx, use)
+	jwk, err := j.dataSource.QueriesFor(ctx).GetActiveJwk(ctx, sqlc.GetActiveJwkParams{
+		Use:         use,
+		ActivatesAt: db2.TimestampUTC(time.Now()),
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return toJwk(&jwk)
+	return toJwk(&jwk, j.jwkCipher)
+}
+
+func (j *jwkRepositoryImpl) GetPendingJwk(ctx context.Context, use string) (*Jwk, error) {
+	jwk, err := j.dataSource.QueriesFor(ctx).GetPendingJwk(ctx, sqlc.GetPendingJwkParams{
+		Use:         use,
+		ActivatesAt: db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toJwk(&jwk, j.jwkCipher)
 }
 
 func (j *jwkRepositoryImpl) GetJwk(ctx context.Context, id pgtype.UUID) (*Jwk, error) {
-	jwk, err := j.dataSource.Queries.GetJwk(ctx, id)
+	jwk, err := j.dataSource.QueriesFor(ctx).GetJwk(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toJwk(&jwk, j.jwkCipher)
+}
+
+func (j *jwkRepositoryImpl) GetJwkByKid(ctx context.Context, kid string) (*Jwk, error) {
+	jwk, err := j.dataSource.QueriesFor(ctx).GetJwkByKid(ctx, kid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toJwk(&jwk, j.jwkCipher)
+}
+
+func (j *jwkRepositoryImpl) GetJwks(ctx context.Context) ([]*Jwk, error) {
+	jwks, err := j.dataSource.QueriesFor(ctx).GetJwks(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return toJwk(&jwk)
+	return j.toJwks(jwks)
+}
+
+func (j *jwkRepositoryImpl) GetPublishedJwks(ctx context.Context) ([]*Jwk, error) {
+	jwks, err := j.dataSource.QueriesFor(ctx).GetPublishedJwks(ctx, db2.TimestampUTC(time.Now()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return j.toJwks(jwks)
+}
+
+// LockJwks serializes key rotation of the use across all replicas until the surrounding transaction ends.
+func (j *jwkRepositoryImpl) GetRevokedJwkKids(ctx context.Context) ([]string, error) {
+	return j.dataSource.QueriesFor(ctx).GetRevokedJwkKids(ctx)
+}
+
+func (j *jwkRepositoryImpl) LockJwks(ctx context.Context, use string) error {
+	return j.dataSource.QueriesFor(ctx).LockJwks(ctx, use)
+}
+
+func (j *jwkRepositoryImpl) RewrapJwks(ctx context.Context) (int, error) {
+	count, err := j.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
+		kekVersion := j.jwkCipher.KekVersion()
+
+		jwks, err := q.GetJwksNotWrappedBy(ctx, toText(kekVersion))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, jwk := range jwks {
+			params := sqlc.SetJwkPrivateKeyParams{
+				ID:         jwk.ID,
+				PrivateKey: jwk.PrivateKey,
+				KekVersion: toText(kekVersion),
+			}
+
+			if jwk.KekVersion.Valid {
+				params.PrivateKeyDek, err = j.jwkCipher.rewrap(jwk.ID, jwk.KekVersion.String, jwk.PrivateKeyDek)
+			} else {
+				params.PrivateKey, params.PrivateKeyDek, err = j.jwkCipher.encrypt(jwk.ID, jwk.PrivateKey)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("jwk %s: %w", jwk.ID.String(), err)
+			}
+
+			if err := q.SetJwkPrivateKey(ctx, params); err != nil {
+				return nil, err
+			}
+		}
+
+		return len(jwks), nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	rewrapped, ok := count.(int)
+	if !ok {
+		return 0, fmt.Errorf("invalid count type: %T", count)
+	}
+
+	return rewrapped, nil
 }
 
-func (j *jwkRepositoryImpl) GetActiveJwks(ctx context.Context) ([]*Jwk, error) {
-	jwks, err := j.dataSource.Queries.GetActiveJwks(ctx)
+func (j *jwkRepositoryImpl) SetJwkRevoked(ctx context.Context, id pgtype.UUID) (*Jwk, error) {
+	jwk, err := j.dataSource.QueriesFor(ctx).SetJwkRevoked(ctx, sqlc.SetJwkRevokedParams{
+		ID:        id,
+		RevokedAt: db2.TimestampUTC(time.Now()),
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	return toJwk(&jwk, j.jwkCipher)
+}
+
+func (j *jwkRepositoryImpl) toJwks(jwks []sqlc.Jwk) ([]*Jwk, error) {
 	result := make([]*Jwk, len(jwks))
 	for i, dbJwk := range jwks {
-		jwk, err := toJwk(&dbJwk)
+		jwk, err := toJwk(&dbJwk, j.jwkCipher)
 		if err != nil {
 			return nil, err
 		}
@@ -126,3 +309,32 @@ func (j *jwkRepositoryImpl) GetActiveJwks(ctx context.Context) ([]*Jwk, error) {
 
 	return result, nil
 }
+
+func generateKey(alg string, rsaKeySize int) (crypto.Signer, error) {
+	switch alg {
+	case JwkAlgRS256, JwkAlgPS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case JwkAlgES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case JwkAlgES384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case JwkAlgEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	default:
+		return nil, fmt.Errorf("unsupported jwk algorithm: %s", alg)
+	}
+}
+
+func keyType(privateKey crypto.Signer) (string, error) {
+	switch privateKey.(type) {
+	case *rsa.PrivateKey:
+		return "RSA", nil
+	case *ecdsa.PrivateKey:
+		return "EC", nil
+	case ed25519.PrivateKey:
+		return "OKP", nil
+	default:
+		return "", fmt.Errorf("unsupported private key type: %T", privateKey)
+	}
+}
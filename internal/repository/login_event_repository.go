@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type LoginEventRepository interface {
+	AddLoginEvent(ctx context.Context, data *LoginEventData) (*LoginEvent, error)
+	CountSuccessfulLoginEventsByFingerprint(ctx context.Context, userID pgtype.UUID, fingerprint string) (int64, error)
+	GetLoginEventById(ctx context.Context, id pgtype.UUID) (*LoginEvent, error)
+	SearchLoginEvents(ctx context.Context, criteria *SearchLoginEventsCriteria, pageable *common.Pageable) (*common.Page[*LoginEvent], error)
+	SetLoginEventRevoked(ctx context.Context, id pgtype.UUID) (*LoginEvent, error)
+}
+
+type loginEventRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewLoginEventRepository(dataSource *db.DataSource) LoginEventRepository {
+	return &loginEventRepositoryImpl{dataSource}
+}
+
+func (l *loginEventRepositoryImpl) AddLoginEvent(ctx context.Context, data *LoginEventData) (*LoginEvent, error) {
+	loginEvent, err := l.dataSource.QueriesFor(ctx).AddLoginEvent(ctx, sqlc.AddLoginEventParams{
+		ID:          db2.NewUUID(),
+		UserID:      data.UserID,
+		CreatedAt:   db2.TimestampUTC(time.Now()),
+		Ip:          toText(data.IP),
+		UserAgent:   toText(data.UserAgent),
+		Method:      data.Method,
+		Success:     data.Success,
+		Fingerprint: data.Fingerprint,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toLoginEvent(&loginEvent), nil
+}
+
+func (l *loginEventRepositoryImpl) CountSuccessfulLoginEventsByFingerprint(ctx context.Context, userID pgtype.UUID, fingerprint string) (int64, error) {
+	return l.dataSource.QueriesFor(ctx).CountSuccessfulLoginEventsByFingerprint(ctx, sqlc.CountSuccessfulLoginEventsByFingerprintParams{
+		UserID:      userID,
+		Fingerprint: fingerprint,
+	})
+}
+
+func (l *loginEventRepositoryImpl) GetLoginEventById(ctx context.Context, id pgtype.UUID) (*LoginEvent, error) {
+	loginEvent, err := l.dataSource.QueriesFor(ctx).GetLoginEventById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toLoginEvent(&loginEvent), nil
+}
+
+func (l *loginEventRepositoryImpl) SearchLoginEvents(ctx context.Context, criteria *SearchLoginEventsCriteria, pageable *common.Pageable) (*common.Page[*LoginEvent], error) {
+	totalRows, err := l.countLoginEvents(ctx, criteria)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := l.searchLoginEvents(ctx, criteria, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*LoginEvent](pageable, totalRows, content), nil
+}
+
+func (l *loginEventRepositoryImpl) SetLoginEventRevoked(ctx context.Context, id pgtype.UUID) (*LoginEvent, error) {
+	loginEvent, err := l.dataSource.QueriesFor(ctx).SetLoginEventRevoked(ctx, sqlc.SetLoginEventRevokedParams{
+		ID:        id,
+		RevokedAt: db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toLoginEvent(&loginEvent), nil
+}
+
+func (l *loginEventRepositoryImpl) countLoginEvents(ctx context.Context, criteria *SearchLoginEventsCriteria) (int64, error) {
+	var query strings.Builder
+	query.WriteString("select count(*) from login_event l")
+
+	paramIndex := 1
+	conditions, parameters := l.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	row := l.dataSource.Pool.QueryRow(ctx, query.String(), parameters...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (l *loginEventRepositoryImpl) searchLoginEvents(ctx context.Context, criteria *SearchLoginEventsCriteria, pageable *common.Pageable) ([]*LoginEvent, error) {
+	var query strings.Builder
+	query.WriteString("select l.id, l.user_id, l.created_at, l.ip, l.user_agent, l.method, l.success, l.fingerprint, l.revoked_at from login_event l")
+
+	paramIndex := 1
+	conditions, parameters := l.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by " + pageable.Sort)
+	query.WriteString(fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+
+	rows, err := l.dataSource.Pool.Query(ctx, query.String(), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*LoginEvent
+	for rows.Next() {
+		var loginEvent sqlc.LoginEvent
+		if err := rows.Scan(
+			&loginEvent.ID,
+			&loginEvent.UserID,
+			&loginEvent.CreatedAt,
+			&loginEvent.Ip,
+			&loginEvent.UserAgent,
+			&loginEvent.Method,
+			&loginEvent.Success,
+			&loginEvent.Fingerprint,
+			&loginEvent.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		content = append(content, toLoginEvent(&loginEvent))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (l *loginEventRepositoryImpl) buildSearchQueryParts(criteria *SearchLoginEventsCriteria, paramIndex *int) (conditions []string, parameters []interface{}) {
+	conditions = []string{}
+	parameters = []interface{}{}
+
+	if criteria.UserID.Valid {
+		conditions = append(conditions, fmt.Sprintf("l.user_id = $%d", *paramIndex))
+		parameters = append(parameters, criteria.UserID)
+		*paramIndex++
+	}
+
+	return conditions, parameters
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type MailRepository interface {
+	AddMail(ctx context.Context, data *MailData) (*Mail, error)
+	ClaimMails(ctx context.Context, batchSize int32, lease time.Duration) ([]*Mail, error)
+	GetMailById(ctx context.Context, id pgtype.UUID) (*Mail, error)
+	PurgeMailData(ctx context.Context, createdBefore time.Time) (int64, error)
+	SearchMails(ctx context.Context, criteria *SearchMailsCriteria, pageable *common.Pageable) (*common.Page[*Mail], error)
+	SetMailResult(ctx context.Context, id pgtype.UUID, data *MailResultData) (*Mail, error)
+}
+
+type mailRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewMailRepository(dataSource *db.DataSource) MailRepository {
+	return &mailRepositoryImpl{dataSource}
+}
+
+func (m *mailRepositoryImpl) AddMail(ctx context.Context, data *MailData) (*Mail, error) {
+	now := db2.TimestampUTC(time.Now())
+
+	mail, err := m.dataSource.QueriesFor(ctx).AddMail(ctx, sqlc.AddMailParams{
+		ID:            db2.NewUUID(),
+		CreatedAt:     now,
+		Recipients:    data.Recipients,
+		Subject:       data.Subject,
+		Data:          data.Data,
+		Status:        MailStatusQueued,
+		Attempts:      0,
+		NextAttemptAt: now,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toMail(&mail), nil
+}
+
+func (m *mailRepositoryImpl) ClaimMails(ctx context.Context, batchSize int32, lease time.Duration) ([]*Mail, error) {
+	now := time.Now()
+
+	mails, err := m.dataSource.QueriesFor(ctx).ClaimMails(ctx, sqlc.ClaimMailsParams{
+		LeaseUntil: db2.TimestampUTC(now.Add(lease)),
+		Status:     MailStatusQueued,
+		Now:        db2.TimestampUTC(now),
+		BatchSize:  batchSize,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Mail, len(mails))
+	for i, mail := range mails {
+		result[i] = toMail(&mail)
+	}
+
+	return result, nil
+}
+
+func (m *mailRepositoryImpl) GetMailById(ctx context.Context, id pgtype.UUID) (*Mail, error) {
+	mail, err := m.dataSource.QueriesFor(ctx).GetMailById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toMail(&mail), nil
+}
+
+func (m *mailRepositoryImpl) SearchMails(ctx context.Context, criteria *SearchMailsCriteria, pageable *common.Pageable) (*common.Page[*Mail], error) {
+	totalRows, err := m.countMails(ctx, criteria)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := m.searchMails(ctx, criteria, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*Mail](pageable, totalRows, content), nil
+}
+
+// PurgeMailData blanks the content of sent and failed mails created before createdBefore, the recipients, subject and
+// delivery state are kept.
+func (m *mailRepositoryImpl) PurgeMailData(ctx context.Context, createdBefore time.Time) (int64, error) {
+	return m.dataSource.QueriesFor(ctx).PurgeMailData(ctx, sqlc.PurgeMailDataParams{
+		Status:    MailStatusQueued,
+		CreatedAt: db2.TimestampUTC(createdBefore),
+	})
+}
+
+func (m *mailRepositoryImpl) SetMailResult(ctx context.Context, id pgtype.UUID, data *MailResultData) (*Mail, error) {
+	var sentAt pgtype.Timestamptz
+	if data.Status == MailStatusSent {
+		sentAt = db2.TimestampUTC(time.Now())
+	}
+
+	mail, err := m.dataSource.QueriesFor(ctx).SetMailResult(ctx, sqlc.SetMailResultParams{
+		ID:            id,
+		Status:        data.Status,
+		Attempts:      data.Attempts,
+		NextAttemptAt: db2.TimestampUTC(data.NextAttemptAt),
+		LastError:     toText(data.LastError),
+		SentAt:        sentAt,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toMail(&mail), nil
+}
+
+func (m *mailRepositoryImpl) countMails(ctx context.Context, criteria *SearchMailsCriteria) (int64, error) {
+	var query strings.Builder
+	query.WriteString("select count(*) from mail m")
+
+	paramIndex := 1
+	conditions, parameters := m.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	row := m.dataSource.Pool.QueryRow(ctx, query.String(), parameters...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (m *mailRepositoryImpl) searchMails(ctx context.Context, criteria *SearchMailsCriteria, pageable *common.Pageable) ([]*Mail, error) {
+	var query strings.Builder
+	query.WriteString("select m.id, m.created_at, m.recipients, m.subject, m.data, m.status, m.attempts, m.next_attempt_at, m.last_error, m.sent_at from mail m")
+
+	paramIndex := 1
+	conditions, parameters := m.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by " + pageable.Sort)
+	query.WriteString(fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+
+	rows, err := m.dataSource.Pool.Query(ctx, query.String(), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*Mail
+	for rows.Next() {
+		var mail sqlc.Mail
+		if err := rows.Scan(
+			&mail.ID,
+			&mail.CreatedAt,
+			&mail.Recipients,
+			&mail.Subject,
+			&mail.Data,
+			&mail.Status,
+			&mail.Attempts,
+			&mail.NextAttemptAt,
+			&mail.LastError,
+			&mail.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		content = append(content, toMail(&mail))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (m *mailRepositoryImpl) buildSearchQueryParts(criteria *SearchMailsCriteria, paramIndex *int) (conditions []string, parameters []interface{}) {
+	conditions = []string{}
+	parameters = []interface{}{}
+
+	if criteria.Status != "" {
+		conditions = append(conditions, fmt.Sprintf("m.status = $%d", *paramIndex))
+		parameters = append(parameters, criteria.Status)
+		*paramIndex++
+	}
+
+	return conditions, parameters
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type MailTemplateRepository interface {
+	AddMailTemplate(ctx context.Context, data *MailTemplateData) (*MailTemplate, error)
+	CountByCodeAndLocale(ctx context.Context, code, locale string) (int64, error)
+	DeleteMailTemplateById(ctx context.Context, id pgtype.UUID) error
+	GetMailTemplateById(ctx context.Context, id pgtype.UUID) (*MailTemplate, error)
+	GetMailTemplatesByCode(ctx context.Context, code string) ([]*MailTemplate, error)
+	GetMailTemplateVersions(ctx context.Context, id pgtype.UUID) ([]*MailTemplateVersion, error)
+	SearchMailTemplates(ctx context.Context, criteria *SearchMailTemplatesCriteria, pageable *common.Pageable) (*common.Page[*MailTemplate], error)
+	SetMailTemplate(ctx context.Context, id pgtype.UUID, data *MailTemplateContentData) (*MailTemplate, error)
+}
+
+type mailTemplateRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewMailTemplateRepository(dataSource *db.DataSource) MailTemplateRepository {
+	return &mailTemplateRepositoryImpl{dataSource}
+}
+
+func (m *mailTemplateRepositoryImpl) AddMailTemplate(ctx context.Context, data *MailTemplateData) (*MailTemplate, error) {
+	mailTemplate, err := m.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
+		now := db2.TimestampUTC(time.Now())
+
+		created, err := q.AddMailTemplate(ctx, sqlc.AddMailTemplateParams{
+			ID:        db2.NewUUID(),
+			Code:      data.Code,
+			Locale:    data.Locale,
+			Version:   1,
+			Subject:   data.Subject,
+			HtmlBody:  data.HtmlBody,
+			TextBody:  toText(data.TextBody),
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := m.addVersion(ctx, q, &created); err != nil {
+			return nil, err
+		}
+
+		return &created, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	createdMailTemplate, ok := mailTemplate.(*sqlc.MailTemplate)
+	if !ok {
+		return nil, fmt.Errorf("invalid mail template type: %T", mailTemplate)
+	}
+
+	return toMailTemplate(createdMailTemplate), nil
+}
+
+func (m *mailTemplateRepositoryImpl) CountByCodeAndLocale(ctx context.Context, code, locale string) (int64, error) {
+	return m.dataSource.QueriesFor(ctx).CountMailTemplatesByCodeAndLocale(ctx, sqlc.CountMailTemplatesByCodeAndLocaleParams{
+		Code:   code,
+		Locale: locale,
+	})
+}
+
+func (m *mailTemplateRepositoryImpl) DeleteMailTemplateById(ctx context.Context, id pgtype.UUID) error {
+	return m.dataSource.QueriesFor(ctx).DeleteMailTemplateById(ctx, id)
+}
+
+func (m *mailTemplateRepositoryImpl) GetMailTemplateById(ctx context.Context, id pgtype.UUID) (*MailTemplate, error) {
+	mailTemplate, err := m.dataSource.QueriesFor(ctx).GetMailTemplateById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toMailTemplate(&mailTemplate), nil
+}
+
+func (m *mailTemplateRepositoryImpl) GetMailTemplatesByCode(ctx context.Context, code string) ([]*MailTemplate, error) {
+	mailTemplates, err := m.dataSource.QueriesFor(ctx).GetMailTemplatesByCode(ctx, code)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*MailTemplate, len(mailTemplates))
+	for i, mailTemplate := range mailTemplates {
+		result[i] = toMailTemplate(&mailTemplate)
+	}
+
+	return result, nil
+}
+
+func (m *mailTemplateRepositoryImpl) GetMailTemplateVersions(ctx context.Context, id pgtype.UUID) ([]*MailTemplateVersion, error) {
+	versions, err := m.dataSource.QueriesFor(ctx).GetMailTemplateVersions(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*MailTemplateVersion, len(versions))
+	for i, version := range versions {
+		result[i] = toMailTemplateVersion(&version)
+	}
+
+	return result, nil
+}
+
+func (m *mailTemplateRepositoryImpl) SearchMailTemplates(ctx context.Context, criteria *SearchMailTemplatesCriteria, pageable *common.Pageable) (*common.Page[*MailTemplate], error) {
+	totalRows, err := m.countMailTemplates(ctx, criteria)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := m.searchMailTemplates(ctx, criteria, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*MailTemplate](pageable, totalRows, content), nil
+}
+
+func (m *mailTemplateRepositoryImpl) SetMailTemplate(ctx context.Context, id pgtype.UUID, data *MailTemplateContentData) (*MailTemplate, error) {
+	mailTemplate, err := m.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
+		updated, err := q.SetMailTemplate(ctx, sqlc.SetMailTemplateParams{
+			ID:        id,
+			Subject:   data.Subject,
+			HtmlBody:  data.HtmlBody,
+			TextBody:  toText(data.TextBody),
+			UpdatedAt: db2.TimestampUTC(time.Now()),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := m.addVersion(ctx, q, &updated); err != nil {
+			return nil, err
+		}
+
+		return &updated, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	updatedMailTemplate, ok := mailTemplate.(*sqlc.MailTemplate)
+	if !ok {
+		return nil, fmt.Errorf("invalid mail template type: %T", mailTemplate)
+	}
+
+	return toMailTemplate(updatedMailTemplate), nil
+}
+
+func (m *mailTemplateRepositoryImpl) addVersion(ctx context.Context, q *sqlc.Queries, mailTemplate *sqlc.MailTemplate) error {
+	return q.AddMailTemplateVersion(ctx, sqlc.AddMailTemplateVersionParams{
+		MailTemplateID: mailTemplate.ID,
+		Version:        mailTemplate.Version,
+		Subject:        mailTemplate.Subject,
+		HtmlBody:       mailTemplate.HtmlBody,
+		TextBody:       mailTemplate.TextBody,
+		CreatedAt:      mailTemplate.UpdatedAt,
+	})
+}
+
+func (m *mailTemplateRepositoryImpl) countMailTemplates(ctx context.Context, criteria *SearchMailTemplatesCriteria) (int64, error) {
+	var query strings.Builder
+	query.WriteString("select count(*) from mail_template mt")
+
+	paramIndex := 1
+	conditions, parameters := m.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	row := m.dataSource.Pool.QueryRow(ctx, query.String(), parameters...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (m *mailTemplateRepositoryImpl) searchMailTemplates(ctx context.Context, criteria *SearchMailTemplatesCriteria, pageable *common.Pageable) ([]*MailTemplate, error) {
+	var query strings.Builder
+	query.WriteString("select mt.id, mt.code, mt.locale, mt.version, mt.subject, mt.html_body, mt.text_body, mt.created_at, mt.updated_at from mail_template mt")
+
+	paramIndex := 1
+	conditions, parameters := m.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by " + pageable.Sort)
+	query.WriteString(fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+
+	rows, err := m.dataSource.Pool.Query(ctx, query.String(), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*MailTemplate
+	for rows.Next() {
+		var mailTemplate sqlc.MailTemplate
+		if err := rows.Scan(
+			&mailTemplate.ID,
+			&mailTemplate.Code,
+			&mailTemplate.Locale,
+			&mailTemplate.Version,
+			&mailTemplate.Subject,
+			&mailTemplate.HtmlBody,
+			&mailTemplate.TextBody,
+			&mailTemplate.CreatedAt,
+			&mailTemplate.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		content = append(content, toMailTemplate(&mailTemplate))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (m *mailTemplateRepositoryImpl) buildSearchQueryParts(criteria *SearchMailTemplatesCriteria, paramIndex *int) (conditions []string, parameters []interface{}) {
+	conditions = []string{}
+	parameters = []interface{}{}
+
+	if criteria.Code != "" {
+		conditions = append(conditions, fmt.Sprintf("mt.code = $%d", *paramIndex))
+		parameters = append(parameters, criteria.Code)
+		*paramIndex++
+	}
+
+	if criteria.Locale != "" {
+		conditions = append(conditions, fmt.Sprintf("mt.locale = $%d", *paramIndex))
+		parameters = append(parameters, criteria.Locale)
+		*paramIndex++
+	}
+
+	return conditions, parameters
+}
@@ -1,13 +1,13 @@
 package repository
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/janobono/auth-service/generated/sqlc"
 )
@@ -25,6 +25,51 @@ type AttributeData struct {
 	Hidden   bool
 }
 
+type AuditCheckpoint struct {
+	ID        pgtype.UUID
+	Seq       int64
+	Hash      string
+	Kid       pgtype.UUID
+	Signature string
+	CreatedAt time.Time
+}
+
+type AuditCheckpointData struct {
+	Seq       int64
+	Hash      string
+	Kid       pgtype.UUID
+	Signature string
+}
+
+type AuditEvent struct {
+	ID         pgtype.UUID
+	CreatedAt  time.Time
+	ActorID    string
+	ActorEmail string
+	Action     string
+	TargetType string
+	TargetID   string
+	IP         string
+	UserAgent  string
+	Before     []byte
+	After      []byte
+	Seq        int64
+	PrevHash   string
+	Hash       string
+}
+
+type AuditEventData struct {
+	ActorID    string
+	ActorEmail string
+	Action     string
+	TargetType string
+	TargetID   string
+	IP         string
+	UserAgent  string
+	Before     []byte
+	After      []byte
+}
+
 type Authority struct {
 	ID        pgtype.UUID
 	Authority string
@@ -34,23 +79,218 @@ type AuthorityData struct {
 	Authority string
 }
 
-type Jwk struct {
+type ConfirmationToken struct {
 	ID         pgtype.UUID
-	Kty        string
-	Use        string
-	Alg        string
-	PublicKey  *rsa.PublicKey
-	PrivateKey *rsa.PrivateKey
-	Active     bool
+	UserID     pgtype.UUID
+	Purpose    string
 	CreatedAt  time.Time
 	ExpiresAt  time.Time
+	ConsumedAt *time.Time
 }
 
-type JwkData struct {
-	Use        string
+type ConfirmationTokenData struct {
+	UserID          pgtype.UUID
+	Purpose         string
+	Expiration      time.Duration
+	KeepOutstanding bool
+}
+
+type ConsumeConfirmationTokenData struct {
+	ID      pgtype.UUID
+	UserID  pgtype.UUID
+	Purpose string
+}
+
+const (
+	InvitationStatusPending  = "PENDING"
+	InvitationStatusAccepted = "ACCEPTED"
+	InvitationStatusRevoked  = "REVOKED"
+	InvitationStatusExpired  = "EXPIRED"
+)
+
+type Invitation struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Email      string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (i *Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case time.Now().After(i.ExpiresAt):
+		return InvitationStatusExpired
+	default:
+		return InvitationStatusPending
+	}
+}
+
+type InvitationData struct {
+	UserID     pgtype.UUID
+	Email      string
 	Expiration time.Duration
 }
 
+const (
+	JwkAlgRS256 = "RS256"
+	JwkAlgPS256 = "PS256"
+	JwkAlgES256 = "ES256"
+	JwkAlgES384 = "ES384"
+	JwkAlgEdDSA = "EdDSA"
+)
+
+type Jwk struct {
+	ID             pgtype.UUID
+	Kid            string
+	Kty            string
+	Use            string
+	Alg            string
+	PublicKey      crypto.PublicKey
+	PrivateKey     crypto.Signer
+	CreatedAt      time.Time
+	ActivatesAt    time.Time
+	ExpiresAt      time.Time
+	PublishedUntil time.Time
+	RevokedAt      *time.Time
+}
+
+type JwkData struct {
+	Use             string
+	Alg             string
+	RsaKeySize      int
+	ActivatesAt     time.Time
+	Expiration      time.Duration
+	TokenExpiration time.Duration
+}
+
+type JwkImportData struct {
+	Kid             string
+	Use             string
+	Alg             string
+	PrivateKey      crypto.Signer
+	ActivatesAt     time.Time
+	ExpiresAt       time.Time
+	TokenExpiration time.Duration
+}
+
+type LoginEvent struct {
+	ID          pgtype.UUID
+	UserID      pgtype.UUID
+	CreatedAt   time.Time
+	IP          string
+	UserAgent   string
+	Method      string
+	Success     bool
+	Fingerprint string
+	RevokedAt   *time.Time
+}
+
+type LoginEventData struct {
+	UserID      pgtype.UUID
+	IP          string
+	UserAgent   string
+	Method      string
+	Success     bool
+	Fingerprint string
+}
+
+const (
+	MailStatusQueued = "QUEUED"
+	MailStatusSent   = "SENT"
+	MailStatusFailed = "FAILED"
+)
+
+type Mail struct {
+	ID            pgtype.UUID
+	CreatedAt     time.Time
+	Recipients    []string
+	Subject       string
+	Data          []byte
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        *time.Time
+}
+
+type MailData struct {
+	Recipients []string
+	Subject    string
+	Data       []byte
+}
+
+type MailTemplate struct {
+	ID        pgtype.UUID
+	Code      string
+	Locale    string
+	Version   int32
+	Subject   string
+	HtmlBody  string
+	TextBody  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type MailTemplateContentData struct {
+	Subject  string
+	HtmlBody string
+	TextBody string
+}
+
+type MailTemplateData struct {
+	Code     string
+	Locale   string
+	Subject  string
+	HtmlBody string
+	TextBody string
+}
+
+type MailTemplateVersion struct {
+	MailTemplateID pgtype.UUID
+	Version        int32
+	Subject        string
+	HtmlBody       string
+	TextBody       string
+	CreatedAt      time.Time
+}
+
+type MailResultData struct {
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+type OutboxEvent struct {
+	ID           pgtype.UUID
+	Seq          int64
+	CreatedAt    time.Time
+	EventType    string
+	AggregateID  pgtype.UUID
+	Payload      []byte
+	DispatchedAt *time.Time
+}
+
+type OutboxEventData struct {
+	EventType   string
+	AggregateID pgtype.UUID
+	Payload     []byte
+}
+
+type SearchAuditEventsCriteria struct {
+	ActorID  string
+	TargetID string
+	Action   string
+	From     *time.Time
+	To       *time.Time
+}
+
 type SearchAttributesCriteria struct {
 	SearchField string
 }
@@ -59,6 +299,29 @@ type SearchAuthoritiesCriteria struct {
 	SearchField string
 }
 
+type SearchInvitationsCriteria struct {
+	SearchField string
+	Status      string
+}
+
+type SearchLoginEventsCriteria struct {
+	UserID pgtype.UUID
+}
+
+type SearchWebhookDeliveriesCriteria struct {
+	WebhookID pgtype.UUID
+	Status    string
+}
+
+type SearchMailsCriteria struct {
+	Status string
+}
+
+type SearchMailTemplatesCriteria struct {
+	Code   string
+	Locale string
+}
+
 type SearchUsersCriteria struct {
 	SearchField   string
 	Email         string
@@ -66,12 +329,14 @@ type SearchUsersCriteria struct {
 }
 
 type User struct {
-	ID        pgtype.UUID
-	CreatedAt time.Time
-	Email     string
-	Password  string
-	Confirmed bool
-	Enabled   bool
+	ID           pgtype.UUID
+	CreatedAt    time.Time
+	Email        string
+	Password     string
+	Confirmed    bool
+	Enabled      bool
+	PendingEmail string
+	Locale       string
 }
 
 type UserAttributesData struct {
@@ -89,6 +354,7 @@ type UserData struct {
 	Password  string
 	Confirmed bool
 	Enabled   bool
+	Locale    string
 }
 
 type UserAttribute struct {
@@ -96,16 +362,88 @@ type UserAttribute struct {
 	Value     string
 }
 
-func encodePrivateKey(privateKey *rsa.PrivateKey) []byte {
-	privateDER := x509.MarshalPKCS1PrivateKey(privateKey)
+const (
+	WebhookDeliveryStatusScheduled  = "SCHEDULED"
+	WebhookDeliveryStatusDelivered  = "DELIVERED"
+	WebhookDeliveryStatusDeadLetter = "DEAD_LETTER"
+)
+
+type Webhook struct {
+	ID         pgtype.UUID
+	URL        string
+	Secret     string
+	EventTypes []string
+	Enabled    bool
+	CreatedAt  time.Time
+}
+
+func (w *Webhook) Accepts(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, item := range w.EventTypes {
+		if item == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookData struct {
+	URL        string
+	Secret     string
+	EventTypes []string
+	Enabled    bool
+}
+
+type WebhookDelivery struct {
+	ID            pgtype.UUID
+	WebhookID     pgtype.UUID
+	OutboxEventID pgtype.UUID
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+type WebhookDeliveryResultData struct {
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         pgtype.UUID
+	DeliveryID pgtype.UUID
+	CreatedAt  time.Time
+	StatusCode int32
+	Error      string
+	Duration   time.Duration
+}
+
+type WebhookDeliveryAttemptData struct {
+	DeliveryID pgtype.UUID
+	StatusCode int32
+	Error      string
+	Duration   time.Duration
+}
+
+func encodePrivateKey(privateKey crypto.Signer) ([]byte, error) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
 	block := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
+		Type:  "PRIVATE KEY",
 		Bytes: privateDER,
 	}
-	return pem.EncodeToMemory(block)
+	return pem.EncodeToMemory(block), nil
 }
 
-func encodePublicKey(publicKey *rsa.PublicKey) ([]byte, error) {
+func encodePublicKey(publicKey crypto.PublicKey) ([]byte, error) {
 	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
 	if err != nil {
 		return nil, err
@@ -117,16 +455,64 @@ func encodePublicKey(publicKey *rsa.PublicKey) ([]byte, error) {
 	return pem.EncodeToMemory(block), nil
 }
 
-func parsePrivate(jwk *sqlc.Jwk) (*rsa.PrivateKey, error) {
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(jwk.PrivateKey)
+func fromText(text pgtype.Text) string {
+	if text.Valid {
+		return text.String
+	}
+	return ""
+}
+
+func parsePrivate(jwk *sqlc.Jwk, jwkCipher *JwkCipher) (crypto.Signer, error) {
+	privatePEM := jwk.PrivateKey
+	if jwk.KekVersion.Valid {
+		var err error
+		privatePEM, err = jwkCipher.decrypt(jwk.ID, jwk.KekVersion.String, jwk.PrivateKey, jwk.PrivateKeyDek)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt private key: %w", err)
+		}
+	}
+
+	privateKey, err := ParsePrivateKey(privatePEM)
 	if err != nil {
 		return nil, fmt.Errorf("invalid private key: %w", err)
 	}
 	return privateKey, nil
 }
 
-func parsePublicKey(jwk *sqlc.Jwk) (*rsa.PublicKey, error) {
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(jwk.PublicKey)
+func ParsePrivateKey(privatePEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("key must be a PEM encoded private key")
+	}
+
+	var privateKey any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", privateKey)
+	}
+	return signer, nil
+}
+
+func parsePublicKey(jwk *sqlc.Jwk) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(jwk.PublicKey)
+	if block == nil {
+		return nil, errors.New("invalid public key: key must be a PEM encoded public key")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
 		return nil, fmt.Errorf("invalid public key: %w", err)
 	}
@@ -142,6 +528,36 @@ func toAttribute(attribute *sqlc.Attribute) *Attribute {
 	}
 }
 
+func toAuditCheckpoint(auditCheckpoint *sqlc.AuditCheckpoint) *AuditCheckpoint {
+	return &AuditCheckpoint{
+		ID:        auditCheckpoint.ID,
+		Seq:       auditCheckpoint.Seq,
+		Hash:      auditCheckpoint.Hash,
+		Kid:       auditCheckpoint.Kid,
+		Signature: auditCheckpoint.Signature,
+		CreatedAt: auditCheckpoint.CreatedAt.Time,
+	}
+}
+
+func toAuditEvent(auditEvent *sqlc.AuditEvent) *AuditEvent {
+	return &AuditEvent{
+		ID:         auditEvent.ID,
+		CreatedAt:  auditEvent.CreatedAt.Time,
+		ActorID:    fromText(auditEvent.ActorID),
+		ActorEmail: fromText(auditEvent.ActorEmail),
+		Action:     auditEvent.Action,
+		TargetType: auditEvent.TargetType,
+		TargetID:   fromText(auditEvent.TargetID),
+		IP:         fromText(auditEvent.Ip),
+		UserAgent:  fromText(auditEvent.UserAgent),
+		Before:     auditEvent.Before,
+		After:      auditEvent.After,
+		Seq:        auditEvent.Seq,
+		PrevHash:   fromText(auditEvent.PrevHash),
+		Hash:       fromText(auditEvent.Hash),
+	}
+}
+
 func toAuthority(authority *sqlc.Authority) *Authority {
 	return &Authority{
 		ID:        authority.ID,
@@ -149,8 +565,38 @@ func toAuthority(authority *sqlc.Authority) *Authority {
 	}
 }
 
-func toJwk(jwk *sqlc.Jwk) (*Jwk, error) {
-	privateKey, err := parsePrivate(jwk)
+func toConfirmationToken(token *sqlc.ConfirmationToken) *ConfirmationToken {
+	return &ConfirmationToken{
+		ID:         token.ID,
+		UserID:     token.UserID,
+		Purpose:    token.Purpose,
+		CreatedAt:  token.CreatedAt.Time,
+		ExpiresAt:  token.ExpiresAt.Time,
+		ConsumedAt: toTimePointer(token.ConsumedAt),
+	}
+}
+
+func toEventTypes(eventTypes []string) []string {
+	if eventTypes == nil {
+		return []string{}
+	}
+	return eventTypes
+}
+
+func toInvitation(invitation *sqlc.Invitation) *Invitation {
+	return &Invitation{
+		ID:         invitation.ID,
+		UserID:     invitation.UserID,
+		Email:      invitation.Email,
+		CreatedAt:  invitation.CreatedAt.Time,
+		ExpiresAt:  invitation.ExpiresAt.Time,
+		AcceptedAt: toTimePointer(invitation.AcceptedAt),
+		RevokedAt:  toTimePointer(invitation.RevokedAt),
+	}
+}
+
+func toJwk(jwk *sqlc.Jwk, jwkCipher *JwkCipher) (*Jwk, error) {
+	privateKey, err := parsePrivate(jwk, jwkCipher)
 
 	if err != nil {
 		return nil, err
@@ -163,26 +609,108 @@ func toJwk(jwk *sqlc.Jwk) (*Jwk, error) {
 	}
 
 	return &Jwk{
-		ID:         jwk.ID,
-		Kty:        jwk.Kty,
-		Use:        jwk.Use,
-		Alg:        jwk.Alg,
-		PublicKey:  publicKey,
-		PrivateKey: privateKey,
-		Active:     jwk.Active,
-		CreatedAt:  jwk.CreatedAt.Time,
-		ExpiresAt:  jwk.ExpiresAt.Time,
+		ID:             jwk.ID,
+		Kid:            jwk.Kid,
+		Kty:            jwk.Kty,
+		Use:            jwk.Use,
+		Alg:            jwk.Alg,
+		PublicKey:      publicKey,
+		PrivateKey:     privateKey,
+		CreatedAt:      jwk.CreatedAt.Time,
+		ActivatesAt:    jwk.ActivatesAt.Time,
+		ExpiresAt:      jwk.ExpiresAt.Time,
+		PublishedUntil: jwk.PublishedUntil.Time,
+		RevokedAt:      toTimePointer(jwk.RevokedAt),
 	}, nil
 }
 
+func toLoginEvent(loginEvent *sqlc.LoginEvent) *LoginEvent {
+	return &LoginEvent{
+		ID:          loginEvent.ID,
+		UserID:      loginEvent.UserID,
+		CreatedAt:   loginEvent.CreatedAt.Time,
+		IP:          fromText(loginEvent.Ip),
+		UserAgent:   fromText(loginEvent.UserAgent),
+		Method:      loginEvent.Method,
+		Success:     loginEvent.Success,
+		Fingerprint: loginEvent.Fingerprint,
+		RevokedAt:   toTimePointer(loginEvent.RevokedAt),
+	}
+}
+
+func toMail(mail *sqlc.Mail) *Mail {
+	return &Mail{
+		ID:            mail.ID,
+		CreatedAt:     mail.CreatedAt.Time,
+		Recipients:    mail.Recipients,
+		Subject:       mail.Subject,
+		Data:          mail.Data,
+		Status:        mail.Status,
+		Attempts:      mail.Attempts,
+		NextAttemptAt: mail.NextAttemptAt.Time,
+		LastError:     fromText(mail.LastError),
+		SentAt:        toTimePointer(mail.SentAt),
+	}
+}
+
+func toMailTemplate(mailTemplate *sqlc.MailTemplate) *MailTemplate {
+	return &MailTemplate{
+		ID:        mailTemplate.ID,
+		Code:      mailTemplate.Code,
+		Locale:    mailTemplate.Locale,
+		Version:   mailTemplate.Version,
+		Subject:   mailTemplate.Subject,
+		HtmlBody:  mailTemplate.HtmlBody,
+		TextBody:  fromText(mailTemplate.TextBody),
+		CreatedAt: mailTemplate.CreatedAt.Time,
+		UpdatedAt: mailTemplate.UpdatedAt.Time,
+	}
+}
+
+func toMailTemplateVersion(version *sqlc.MailTemplateVersion) *MailTemplateVersion {
+	return &MailTemplateVersion{
+		MailTemplateID: version.MailTemplateID,
+		Version:        version.Version,
+		Subject:        version.Subject,
+		HtmlBody:       version.HtmlBody,
+		TextBody:       fromText(version.TextBody),
+		CreatedAt:      version.CreatedAt.Time,
+	}
+}
+
+func toOutboxEvent(outboxEvent *sqlc.OutboxEvent) *OutboxEvent {
+	return &OutboxEvent{
+		ID:           outboxEvent.ID,
+		Seq:          outboxEvent.Seq,
+		CreatedAt:    outboxEvent.CreatedAt.Time,
+		EventType:    outboxEvent.EventType,
+		AggregateID:  outboxEvent.AggregateID,
+		Payload:      outboxEvent.Payload,
+		DispatchedAt: toTimePointer(outboxEvent.DispatchedAt),
+	}
+}
+
+func toText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+func toTimePointer(timestamp pgtype.Timestamptz) *time.Time {
+	if timestamp.Valid {
+		return &timestamp.Time
+	}
+	return nil
+}
+
 func toUser(user *sqlc.User) *User {
 	return &User{
-		ID:        user.ID,
-		CreatedAt: user.CreatedAt.Time,
-		Email:     user.Email,
-		Password:  user.Password,
-		Confirmed: user.Confirmed,
-		Enabled:   user.Enabled,
+		ID:           user.ID,
+		CreatedAt:    user.CreatedAt.Time,
+		Email:        user.Email,
+		Password:     user.Password,
+		Confirmed:    user.Confirmed,
+		Enabled:      user.Enabled,
+		PendingEmail: fromText(user.PendingEmail),
+		Locale:       fromText(user.Locale),
 	}
 }
 
@@ -202,3 +730,39 @@ func toUserAttribute(attribute *sqlc.GetUserAttributesRow) *UserAttribute {
 		}(),
 	}
 }
+
+func toWebhook(webhook *sqlc.Webhook) *Webhook {
+	return &Webhook{
+		ID:         webhook.ID,
+		URL:        webhook.Url,
+		Secret:     webhook.Secret,
+		EventTypes: webhook.EventTypes,
+		Enabled:    webhook.Enabled,
+		CreatedAt:  webhook.CreatedAt.Time,
+	}
+}
+
+func toWebhookDelivery(webhookDelivery *sqlc.WebhookDelivery) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:            webhookDelivery.ID,
+		WebhookID:     webhookDelivery.WebhookID,
+		OutboxEventID: webhookDelivery.OutboxEventID,
+		Status:        webhookDelivery.Status,
+		Attempts:      webhookDelivery.Attempts,
+		NextAttemptAt: webhookDelivery.NextAttemptAt.Time,
+		LastError:     fromText(webhookDelivery.LastError),
+		CreatedAt:     webhookDelivery.CreatedAt.Time,
+		DeliveredAt:   toTimePointer(webhookDelivery.DeliveredAt),
+	}
+}
+
+func toWebhookDeliveryAttempt(attempt *sqlc.WebhookDeliveryAttempt) *WebhookDeliveryAttempt {
+	return &WebhookDeliveryAttempt{
+		ID:         attempt.ID,
+		DeliveryID: attempt.DeliveryID,
+		CreatedAt:  attempt.CreatedAt.Time,
+		StatusCode: attempt.StatusCode.Int32,
+		Error:      fromText(attempt.Error),
+		Duration:   time.Duration(attempt.DurationMs) * time.Millisecond,
+	}
+}
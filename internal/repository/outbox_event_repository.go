@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type OutboxEventRepository interface {
+	AddOutboxEvent(ctx context.Context, data *OutboxEventData) (*OutboxEvent, error)
+	GetLastOutboxEventSeq(ctx context.Context) (int64, error)
+	GetNotDispatchedOutboxEvents(ctx context.Context, limit int32) ([]*OutboxEvent, error)
+	GetOutboxEventById(ctx context.Context, id pgtype.UUID) (*OutboxEvent, error)
+	GetOutboxEventsAfterSeq(ctx context.Context, seq int64, limit int32) ([]*OutboxEvent, error)
+	SetOutboxEventDispatched(ctx context.Context, id pgtype.UUID) error
+}
+
+type outboxEventRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewOutboxEventRepository(dataSource *db.DataSource) OutboxEventRepository {
+	return &outboxEventRepositoryImpl{dataSource}
+}
+
+func (o *outboxEventRepositoryImpl) AddOutboxEvent(ctx context.Context, data *OutboxEventData) (*OutboxEvent, error) {
+	outboxEvent, err := o.dataSource.ExecTx(ctx, func(q *sqlc.Queries) (interface{}, error) {
+		if err := q.LockOutboxEvents(ctx); err != nil {
+			return nil, err
+		}
+
+		created, err := q.AddOutboxEvent(ctx, sqlc.AddOutboxEventParams{
+			ID:          db2.NewUUID(),
+			CreatedAt:   db2.TimestampUTC(time.Now()),
+			EventType:   data.EventType,
+			AggregateID: data.AggregateID,
+			Payload:     data.Payload,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &created, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	createdOutboxEvent, ok := outboxEvent.(*sqlc.OutboxEvent)
+	if !ok {
+		return nil, fmt.Errorf("invalid outbox event type: %T", outboxEvent)
+	}
+
+	return toOutboxEvent(createdOutboxEvent), nil
+}
+
+func (o *outboxEventRepositoryImpl) GetLastOutboxEventSeq(ctx context.Context) (int64, error) {
+	return o.dataSource.QueriesFor(ctx).GetLastOutboxEventSeq(ctx)
+}
+
+func (o *outboxEventRepositoryImpl) GetNotDispatchedOutboxEvents(ctx context.Context, limit int32) ([]*OutboxEvent, error) {
+	outboxEvents, err := o.dataSource.QueriesFor(ctx).GetNotDispatchedOutboxEvents(ctx, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*OutboxEvent, len(outboxEvents))
+	for i, outboxEvent := range outboxEvents {
+		result[i] = toOutboxEvent(&outboxEvent)
+	}
+
+	return result, nil
+}
+
+func (o *outboxEventRepositoryImpl) GetOutboxEventById(ctx context.Context, id pgtype.UUID) (*OutboxEvent, error) {
+	outboxEvent, err := o.dataSource.QueriesFor(ctx).GetOutboxEventById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toOutboxEvent(&outboxEvent), nil
+}
+
+func (o *outboxEventRepositoryImpl) GetOutboxEventsAfterSeq(ctx context.Context, seq int64, limit int32) ([]*OutboxEvent, error) {
+	outboxEvents, err := o.dataSource.QueriesFor(ctx).GetOutboxEventsAfterSeq(ctx, sqlc.GetOutboxEventsAfterSeqParams{
+		Seq:   seq,
+		Limit: limit,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*OutboxEvent, len(outboxEvents))
+	for i, outboxEvent := range outboxEvents {
+		result[i] = toOutboxEvent(&outboxEvent)
+	}
+
+	return result, nil
+}
+
+func (o *outboxEventRepositoryImpl) SetOutboxEventDispatched(ctx context.Context, id pgtype.UUID) error {
+	return o.dataSource.QueriesFor(ctx).SetOutboxEventDispatched(ctx, sqlc.SetOutboxEventDispatchedParams{
+		ID:           id,
+		DispatchedAt: db2.TimestampUTC(time.Now()),
+	})
+}
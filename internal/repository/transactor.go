@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/janobono/auth-service/internal/db"
+)
+
+type Transactor interface {
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactorImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewTransactor(dataSource *db.DataSource) Transactor {
+	return &transactorImpl{dataSource}
+}
+
+func (t *transactorImpl) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return t.dataSource.RunInTx(ctx, fn)
+}
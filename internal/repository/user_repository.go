@@ -23,6 +23,8 @@ type UserRepository interface {
 	GetUserAuthorities(ctx context.Context, userID pgtype.UUID) ([]*Authority, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	GetUserById(ctx context.Context, id pgtype.UUID) (*User, error)
+	GetUsersByAttributeId(ctx context.Context, attributeID pgtype.UUID) ([]*User, error)
+	GetUsersByAuthorityId(ctx context.Context, authorityID pgtype.UUID) ([]*User, error)
 	SearchUsers(ctx context.Context, criteria *SearchUsersCriteria, pageable *common.Pageable) (*common.Page[*User], error)
 	SetUserAttributes(ctx context.Context, data *UserAttributesData) ([]*UserAttribute, error)
 	SetUserAuthorities(ctx context.Context, data *UserAuthoritiesData) ([]*Authority, error)
@@ -30,6 +32,7 @@ type UserRepository interface {
 	SetUserEmail(ctx context.Context, userID pgtype.UUID, email string) (*User, error)
 	SetUserEnabled(ctx context.Context, userID pgtype.UUID, enabled bool) (*User, error)
 	SetUserPassword(ctx context.Context, userID pgtype.UUID, password string) (*User, error)
+	SetUserPendingEmail(ctx context.Context, userID pgtype.UUID, pendingEmail string) (*User, error)
 }
 
 type userRepositoryImpl struct {
@@ -41,13 +44,14 @@ func NewUserRepository(dataSource *db.DataSource) UserRepository {
 }
 
 func (u *userRepositoryImpl) AddUser(ctx context.Context, data *UserData) (*User, error) {
-	user, err := u.dataSource.Queries.AddUser(ctx, sqlc.AddUserParams{
+	user, err := u.dataSource.QueriesFor(ctx).AddUser(ctx, sqlc.AddUserParams{
 		ID:        db2.NewUUID(),
 		CreatedAt: db2.NowUTC(),
 		Email:     data.Email,
 		Password:  data.Password,
 		Enabled:   data.Enabled,
 		Confirmed: data.Confirmed,
+		Locale:    toText(data.Locale),
 	})
 
 	if err != nil {
@@ -66,6 +70,7 @@ func (u *userRepositoryImpl) AddUserWithAttributesAndAuthorities(ctx context.Con
 			Password:  userData.Password,
 			Enabled:   userData.Enabled,
 			Confirmed: userData.Confirmed,
+			Locale:    toText(userData.Locale),
 		})
 
 		if err != nil {
@@ -106,28 +111,28 @@ func (u *userRepositoryImpl) AddUserWithAttributesAndAuthorities(ctx context.Con
 }
 
 func (u *userRepositoryImpl) CountById(ctx context.Context, id pgtype.UUID) (int64, error) {
-	return u.dataSource.Queries.CountUsersById(ctx, id)
+	return u.dataSource.QueriesFor(ctx).CountUsersById(ctx, id)
 }
 
 func (u *userRepositoryImpl) CountByEmail(ctx context.Context, email string) (int64, error) {
-	return u.dataSource.Queries.CountUsersByEmail(ctx, email)
+	return u.dataSource.QueriesFor(ctx).CountUsersByEmail(ctx, email)
 }
 
 func (u *userRepositoryImpl) CountByEmailAndNotId(ctx context.Context, email string, id pgtype.UUID) (int64, error) {
-	return u.dataSource.Queries.CountUsersByEmailNotId(ctx, sqlc.CountUsersByEmailNotIdParams{
+	return u.dataSource.QueriesFor(ctx).CountUsersByEmailNotId(ctx, sqlc.CountUsersByEmailNotIdParams{
 		Email: email,
 		ID:    id,
 	})
 }
 
 func (u *userRepositoryImpl) DeleteUserById(ctx context.Context, id pgtype.UUID) error {
-	return u.dataSource.Queries.DeleteUserById(ctx, id)
+	return u.dataSource.QueriesFor(ctx).DeleteUserById(ctx, id)
 }
 
 func (u *userRepositoryImpl) GetUserAttributes(ctx context.Context, userID pgtype.UUID) ([]*UserAttribute, error) {
 	var result []*UserAttribute
 
-	userAttributes, err := u.dataSource.Queries.GetUserAttributes(ctx, userID)
+	userAttributes, err := u.dataSource.QueriesFor(ctx).GetUserAttributes(ctx, userID)
 
 	if err != nil {
 		return result, err
@@ -143,7 +148,7 @@ func (u *userRepositoryImpl) GetUserAttributes(ctx context.Context, userID pgtyp
 func (u *userRepositoryImpl) GetUserAuthorities(ctx context.Context, userID pgtype.UUID) ([]*Authority, error) {
 	var result []*Authority
 
-	userAuthorities, err := u.dataSource.Queries.GetUserAuthorities(ctx, userID)
+	userAuthorities, err := u.dataSource.QueriesFor(ctx).GetUserAuthorities(ctx, userID)
 
 	if err != nil {
 		return result, err
@@ -157,7 +162,7 @@ func (u *userRepositoryImpl) GetUserAuthorities(ctx context.Context, userID pgty
 }
 
 func (u *userRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*User, error) {
-	user, err := u.dataSource.Queries.GetUserByEmail(ctx, email)
+	user, err := u.dataSource.QueriesFor(ctx).GetUserByEmail(ctx, email)
 
 	if err != nil {
 		return nil, err
@@ -167,7 +172,7 @@ func (u *userRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (
 }
 
 func (u *userRepositoryImpl) GetUserById(ctx context.Context, id pgtype.UUID) (*User, error) {
-	user, err := u.dataSource.Queries.GetUserById(ctx, id)
+	user, err := u.dataSource.QueriesFor(ctx).GetUserById(ctx, id)
 
 	if err != nil {
 		return nil, err
@@ -176,6 +181,32 @@ func (u *userRepositoryImpl) GetUserById(ctx context.Context, id pgtype.UUID) (*
 	return toUser(&user), nil
 }
 
+func (u *userRepositoryImpl) GetUsersByAttributeId(ctx context.Context, attributeID pgtype.UUID) ([]*User, error) {
+	users, err := u.dataSource.QueriesFor(ctx).GetUsersByAttributeId(ctx, attributeID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*User, len(users))
+	for i, user := range users {
+		result[i] = toUser(&user)
+	}
+	return result, nil
+}
+
+func (u *userRepositoryImpl) GetUsersByAuthorityId(ctx context.Context, authorityID pgtype.UUID) ([]*User, error) {
+	users, err := u.dataSource.QueriesFor(ctx).GetUsersByAuthorityId(ctx, authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*User, len(users))
+	for i, user := range users {
+		result[i] = toUser(&user)
+	}
+	return result, nil
+}
+
 func (u *userRepositoryImpl) SearchUsers(ctx context.Context, criteria *SearchUsersCriteria, pageable *common.Pageable) (*common.Page[*User], error) {
 	totalRows, err := u.countUsers(ctx, criteria)
 
@@ -252,7 +283,7 @@ func (u *userRepositoryImpl) SetUserAuthorities(ctx context.Context, data *UserA
 }
 
 func (u *userRepositoryImpl) SetUserConfirmed(ctx context.Context, userID pgtype.UUID, confirmed bool) (*User, error) {
-	user, err := u.dataSource.Queries.SetUserConfirmed(ctx, sqlc.SetUserConfirmedParams{
+	user, err := u.dataSource.QueriesFor(ctx).SetUserConfirmed(ctx, sqlc.SetUserConfirmedParams{
 		ID:        userID,
 		Confirmed: confirmed,
 	})
@@ -265,7 +296,7 @@ func (u *userRepositoryImpl) SetUserConfirmed(ctx context.Context, userID pgtype
 }
 
 func (u *userRepositoryImpl) SetUserEmail(ctx context.Context, userID pgtype.UUID, email string) (*User, error) {
-	user, err := u.dataSource.Queries.SetUserEmail(ctx, sqlc.SetUserEmailParams{
+	user, err := u.dataSource.QueriesFor(ctx).SetUserEmail(ctx, sqlc.SetUserEmailParams{
 		ID:    userID,
 		Email: email,
 	})
@@ -278,7 +309,7 @@ func (u *userRepositoryImpl) SetUserEmail(ctx context.Context, userID pgtype.UUI
 }
 
 func (u *userRepositoryImpl) SetUserEnabled(ctx context.Context, userID pgtype.UUID, enabled bool) (*User, error) {
-	user, err := u.dataSource.Queries.SetUserEnabled(ctx, sqlc.SetUserEnabledParams{
+	user, err := u.dataSource.QueriesFor(ctx).SetUserEnabled(ctx, sqlc.SetUserEnabledParams{
 		ID:      userID,
 		Enabled: enabled,
 	})
@@ -291,7 +322,7 @@ func (u *userRepositoryImpl) SetUserEnabled(ctx context.Context, userID pgtype.U
 }
 
 func (u *userRepositoryImpl) SetUserPassword(ctx context.Context, userID pgtype.UUID, password string) (*User, error) {
-	user, err := u.dataSource.Queries.SetUserPassword(ctx, sqlc.SetUserPasswordParams{
+	user, err := u.dataSource.QueriesFor(ctx).SetUserPassword(ctx, sqlc.SetUserPasswordParams{
 		ID:       userID,
 		Password: password,
 	})
@@ -303,6 +334,19 @@ func (u *userRepositoryImpl) SetUserPassword(ctx context.Context, userID pgtype.
 	return toUser(&user), nil
 }
 
+func (u *userRepositoryImpl) SetUserPendingEmail(ctx context.Context, userID pgtype.UUID, pendingEmail string) (*User, error) {
+	user, err := u.dataSource.QueriesFor(ctx).SetUserPendingEmail(ctx, sqlc.SetUserPendingEmailParams{
+		ID:           userID,
+		PendingEmail: toText(pendingEmail),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toUser(&user), nil
+}
+
 func (u *userRepositoryImpl) countUsers(ctx context.Context, criteria *SearchUsersCriteria) (int64, error) {
 	var query strings.Builder
 	query.WriteString(`select count(*) from "user" u`)
@@ -324,7 +368,7 @@ func (u *userRepositoryImpl) countUsers(ctx context.Context, criteria *SearchUse
 
 func (u *userRepositoryImpl) searchUsers(ctx context.Context, criteria *SearchUsersCriteria, pageable *common.Pageable) ([]*User, error) {
 	var query strings.Builder
-	query.WriteString(`select u.id, u.created_at, u.email, u.password, u.confirmed, u.enabled from "user" u`)
+	query.WriteString(`select u.id, u.created_at, u.email, u.password, u.confirmed, u.enabled, u.pending_email, u.locale from "user" u`)
 
 	paramIndex := 1
 	joins, conditions, parameters := u.buildSearchQueryParts(criteria, &paramIndex)
@@ -354,6 +398,8 @@ func (u *userRepositoryImpl) searchUsers(ctx context.Context, criteria *SearchUs
 			&user.Password,
 			&user.Confirmed,
 			&user.Enabled,
+			&user.PendingEmail,
+			&user.Locale,
 		); err != nil {
 			return nil, err
 		}
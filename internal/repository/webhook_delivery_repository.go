@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type WebhookDeliveryRepository interface {
+	AddWebhookDelivery(ctx context.Context, webhookID, outboxEventID pgtype.UUID) error
+	AddWebhookDeliveryAttempt(ctx context.Context, data *WebhookDeliveryAttemptData) (*WebhookDeliveryAttempt, error)
+	ClaimWebhookDeliveries(ctx context.Context, batchSize int32, lease time.Duration) ([]*WebhookDelivery, error)
+	GetWebhookDeliveryAttempts(ctx context.Context, deliveryID pgtype.UUID) ([]*WebhookDeliveryAttempt, error)
+	GetWebhookDeliveryById(ctx context.Context, id pgtype.UUID) (*WebhookDelivery, error)
+	SearchWebhookDeliveries(ctx context.Context, criteria *SearchWebhookDeliveriesCriteria, pageable *common.Pageable) (*common.Page[*WebhookDelivery], error)
+	SetWebhookDeliveryResult(ctx context.Context, id pgtype.UUID, data *WebhookDeliveryResultData) (*WebhookDelivery, error)
+}
+
+type webhookDeliveryRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewWebhookDeliveryRepository(dataSource *db.DataSource) WebhookDeliveryRepository {
+	return &webhookDeliveryRepositoryImpl{dataSource}
+}
+
+func (w *webhookDeliveryRepositoryImpl) AddWebhookDelivery(ctx context.Context, webhookID, outboxEventID pgtype.UUID) error {
+	now := db2.TimestampUTC(time.Now())
+
+	return w.dataSource.QueriesFor(ctx).AddWebhookDelivery(ctx, sqlc.AddWebhookDeliveryParams{
+		ID:            db2.NewUUID(),
+		WebhookID:     webhookID,
+		OutboxEventID: outboxEventID,
+		Status:        WebhookDeliveryStatusScheduled,
+		Attempts:      0,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+func (w *webhookDeliveryRepositoryImpl) AddWebhookDeliveryAttempt(ctx context.Context, data *WebhookDeliveryAttemptData) (*WebhookDeliveryAttempt, error) {
+	attempt, err := w.dataSource.QueriesFor(ctx).AddWebhookDeliveryAttempt(ctx, sqlc.AddWebhookDeliveryAttemptParams{
+		ID:         db2.NewUUID(),
+		DeliveryID: data.DeliveryID,
+		CreatedAt:  db2.TimestampUTC(time.Now()),
+		StatusCode: pgtype.Int4{Int32: data.StatusCode, Valid: data.StatusCode != 0},
+		Error:      toText(data.Error),
+		DurationMs: data.Duration.Milliseconds(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhookDeliveryAttempt(&attempt), nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) ClaimWebhookDeliveries(ctx context.Context, batchSize int32, lease time.Duration) ([]*WebhookDelivery, error) {
+	now := time.Now()
+
+	deliveries, err := w.dataSource.QueriesFor(ctx).ClaimWebhookDeliveries(ctx, sqlc.ClaimWebhookDeliveriesParams{
+		LeaseUntil: db2.TimestampUTC(now.Add(lease)),
+		Status:     WebhookDeliveryStatusScheduled,
+		Now:        db2.TimestampUTC(now),
+		BatchSize:  batchSize,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = toWebhookDelivery(&delivery)
+	}
+
+	return result, nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) GetWebhookDeliveryAttempts(ctx context.Context, deliveryID pgtype.UUID) ([]*WebhookDeliveryAttempt, error) {
+	attempts, err := w.dataSource.QueriesFor(ctx).GetWebhookDeliveryAttempts(ctx, deliveryID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*WebhookDeliveryAttempt, len(attempts))
+	for i, attempt := range attempts {
+		result[i] = toWebhookDeliveryAttempt(&attempt)
+	}
+
+	return result, nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) GetWebhookDeliveryById(ctx context.Context, id pgtype.UUID) (*WebhookDelivery, error) {
+	delivery, err := w.dataSource.QueriesFor(ctx).GetWebhookDeliveryById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhookDelivery(&delivery), nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) SearchWebhookDeliveries(ctx context.Context, criteria *SearchWebhookDeliveriesCriteria, pageable *common.Pageable) (*common.Page[*WebhookDelivery], error) {
+	totalRows, err := w.countWebhookDeliveries(ctx, criteria)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := w.searchWebhookDeliveries(ctx, criteria, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*WebhookDelivery](pageable, totalRows, content), nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) SetWebhookDeliveryResult(ctx context.Context, id pgtype.UUID, data *WebhookDeliveryResultData) (*WebhookDelivery, error) {
+	var deliveredAt pgtype.Timestamptz
+	if data.Status == WebhookDeliveryStatusDelivered {
+		deliveredAt = db2.TimestampUTC(time.Now())
+	}
+
+	delivery, err := w.dataSource.QueriesFor(ctx).SetWebhookDeliveryResult(ctx, sqlc.SetWebhookDeliveryResultParams{
+		ID:            id,
+		Status:        data.Status,
+		Attempts:      data.Attempts,
+		NextAttemptAt: db2.TimestampUTC(data.NextAttemptAt),
+		LastError:     toText(data.LastError),
+		DeliveredAt:   deliveredAt,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhookDelivery(&delivery), nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) countWebhookDeliveries(ctx context.Context, criteria *SearchWebhookDeliveriesCriteria) (int64, error) {
+	var query strings.Builder
+	query.WriteString("select count(*) from webhook_delivery d")
+
+	paramIndex := 1
+	conditions, parameters := w.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	row := w.dataSource.Pool.QueryRow(ctx, query.String(), parameters...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (w *webhookDeliveryRepositoryImpl) searchWebhookDeliveries(ctx context.Context, criteria *SearchWebhookDeliveriesCriteria, pageable *common.Pageable) ([]*WebhookDelivery, error) {
+	var query strings.Builder
+	query.WriteString("select d.id, d.webhook_id, d.outbox_event_id, d.status, d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.delivered_at from webhook_delivery d")
+
+	paramIndex := 1
+	conditions, parameters := w.buildSearchQueryParts(criteria, &paramIndex)
+
+	if len(conditions) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(conditions, " and "))
+	}
+
+	query.WriteString(" order by " + pageable.Sort)
+	query.WriteString(fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+
+	rows, err := w.dataSource.Pool.Query(ctx, query.String(), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*WebhookDelivery
+	for rows.Next() {
+		var delivery sqlc.WebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.OutboxEventID,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		content = append(content, toWebhookDelivery(&delivery))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (w *webhookDeliveryRepositoryImpl) buildSearchQueryParts(criteria *SearchWebhookDeliveriesCriteria, paramIndex *int) (conditions []string, parameters []interface{}) {
+	conditions = []string{}
+	parameters = []interface{}{}
+
+	if criteria.WebhookID.Valid {
+		conditions = append(conditions, fmt.Sprintf("d.webhook_id = $%d", *paramIndex))
+		parameters = append(parameters, criteria.WebhookID)
+		*paramIndex++
+	}
+
+	if criteria.Status != "" {
+		conditions = append(conditions, fmt.Sprintf("d.status = $%d", *paramIndex))
+		parameters = append(parameters, criteria.Status)
+		*paramIndex++
+	}
+
+	return conditions, parameters
+}
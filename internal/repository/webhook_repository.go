@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/sqlc"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type WebhookRepository interface {
+	AddWebhook(ctx context.Context, data *WebhookData) (*Webhook, error)
+	DeleteWebhookById(ctx context.Context, id pgtype.UUID) error
+	GetEnabledWebhooks(ctx context.Context) ([]*Webhook, error)
+	GetWebhookById(ctx context.Context, id pgtype.UUID) (*Webhook, error)
+	GetWebhooks(ctx context.Context, pageable *common.Pageable) (*common.Page[*Webhook], error)
+	SetWebhook(ctx context.Context, id pgtype.UUID, data *WebhookData) (*Webhook, error)
+}
+
+type webhookRepositoryImpl struct {
+	dataSource *db.DataSource
+}
+
+func NewWebhookRepository(dataSource *db.DataSource) WebhookRepository {
+	return &webhookRepositoryImpl{dataSource}
+}
+
+func (w *webhookRepositoryImpl) AddWebhook(ctx context.Context, data *WebhookData) (*Webhook, error) {
+	webhook, err := w.dataSource.QueriesFor(ctx).AddWebhook(ctx, sqlc.AddWebhookParams{
+		ID:         db2.NewUUID(),
+		Url:        data.URL,
+		Secret:     data.Secret,
+		EventTypes: toEventTypes(data.EventTypes),
+		Enabled:    data.Enabled,
+		CreatedAt:  db2.TimestampUTC(time.Now()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhook(&webhook), nil
+}
+
+func (w *webhookRepositoryImpl) DeleteWebhookById(ctx context.Context, id pgtype.UUID) error {
+	return w.dataSource.QueriesFor(ctx).DeleteWebhookById(ctx, id)
+}
+
+func (w *webhookRepositoryImpl) GetEnabledWebhooks(ctx context.Context) ([]*Webhook, error) {
+	webhooks, err := w.dataSource.QueriesFor(ctx).GetEnabledWebhooks(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Webhook, len(webhooks))
+	for i, webhook := range webhooks {
+		result[i] = toWebhook(&webhook)
+	}
+
+	return result, nil
+}
+
+func (w *webhookRepositoryImpl) GetWebhookById(ctx context.Context, id pgtype.UUID) (*Webhook, error) {
+	webhook, err := w.dataSource.QueriesFor(ctx).GetWebhookById(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhook(&webhook), nil
+}
+
+func (w *webhookRepositoryImpl) GetWebhooks(ctx context.Context, pageable *common.Pageable) (*common.Page[*Webhook], error) {
+	var totalRows int64
+	if err := w.dataSource.Pool.QueryRow(ctx, "select count(*) from webhook w").Scan(&totalRows); err != nil {
+		return nil, err
+	}
+
+	rows, err := w.dataSource.Pool.Query(ctx,
+		"select w.id, w.url, w.secret, w.event_types, w.enabled, w.created_at from webhook w"+
+			" order by "+pageable.Sort+
+			fmt.Sprintf(" limit %d offset %d", pageable.Limit(), pageable.Offset()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var content []*Webhook
+	for rows.Next() {
+		var webhook sqlc.Webhook
+		if err := rows.Scan(
+			&webhook.ID,
+			&webhook.Url,
+			&webhook.Secret,
+			&webhook.EventTypes,
+			&webhook.Enabled,
+			&webhook.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		content = append(content, toWebhook(&webhook))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return common.NewPage[*Webhook](pageable, totalRows, content), nil
+}
+
+func (w *webhookRepositoryImpl) SetWebhook(ctx context.Context, id pgtype.UUID, data *WebhookData) (*Webhook, error) {
+	webhook, err := w.dataSource.QueriesFor(ctx).SetWebhook(ctx, sqlc.SetWebhookParams{
+		ID:         id,
+		Url:        data.URL,
+		Secret:     data.Secret,
+		EventTypes: toEventTypes(data.EventTypes),
+		Enabled:    data.Enabled,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhook(&webhook), nil
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+// checkAuditCheckpoints refuses to start without an audit key when checkpoints are enabled and keys are not generated.
+func checkAuditCheckpoints(serverConfig *config.ServerConfig, jwtService *service.JwtService) {
+	if serverConfig.SecurityConfig.AuditCheckpointInterval <= 0 || serverConfig.SecurityConfig.JwkGenerationEnabled {
+		return
+	}
+
+	if err := jwtService.CheckAuditJwk(context.Background()); err != nil {
+		slog.Error("Audit checkpoints need an imported audit jwk while jwk generation is disabled", "error", err)
+		panic(err)
+	}
+}
+
+func startAuditCheckpoints(ctx context.Context, interval time.Duration, auditService *service.AuditService) {
+	if interval <= 0 {
+		slog.Info("Audit checkpoints disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := auditService.Checkpoint(ctx); err != nil {
+					slog.Error("Failed to create audit checkpoint", "error", err)
+				}
+			}
+		}
+	}()
+}
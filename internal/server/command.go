@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+const (
+	COMMAND_IMPORT_JWK  = "import-jwk"
+	COMMAND_REWRAP_JWKS = "rewrap-jwks"
+)
+
+func RunCommand(config *config.ServerConfig, args []string) error {
+	initSlog(config)
+
+	switch args[0] {
+	case COMMAND_IMPORT_JWK:
+		return importJwk(config, args[1:])
+	case COMMAND_REWRAP_JWKS:
+		return rewrapJwks(config)
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+func importJwk(config *config.ServerConfig, args []string) error {
+	flags := flag.NewFlagSet(COMMAND_IMPORT_JWK, flag.ContinueOnError)
+	file := flags.String("file", "", "PEM encoded or JWK private key file")
+	kid := flags.String("kid", "", "key id, defaults to the kid of a JWK or a generated one")
+	use := flags.String("use", "", "key use: access, refresh, confirm or audit")
+	alg := flags.String("alg", "", "signing algorithm, defaults to the alg of a JWK or one matching the key")
+	activatesAt := flags.String("activates-at", "", "RFC 3339 time the key starts signing, defaults to now")
+	expiresAt := flags.String("expires-at", "", "RFC 3339 time the key stops signing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" || *use == "" || *expiresAt == "" {
+		return errors.New("-file, -use and -expires-at are required")
+	}
+
+	privateKey, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	data := &openapi.JwkImportData{
+		Kid:        *kid,
+		Use:        openapi.JwkUse(*use),
+		Alg:        *alg,
+		PrivateKey: string(privateKey),
+	}
+	if data.ExpiresAt, err = time.Parse(time.RFC3339, *expiresAt); err != nil {
+		return fmt.Errorf("invalid -expires-at: %w", err)
+	}
+	if *activatesAt != "" {
+		if data.ActivatesAt, err = time.Parse(time.RFC3339, *activatesAt); err != nil {
+			return fmt.Errorf("invalid -activates-at: %w", err)
+		}
+	}
+
+	jwkCipher, err := newJwkCipher(config.SecurityConfig)
+	if err != nil {
+		return err
+	}
+
+	dataSource := db.NewDataSource(config.DbConfig)
+	defer dataSource.Close()
+
+	transactor := repository.NewTransactor(dataSource)
+	jwkRepository := repository.NewJwkRepository(dataSource, jwkCipher)
+	jwtService := service.NewJwtService(config.SecurityConfig, transactor, jwkRepository)
+	auditService := service.NewAuditService(
+		jwtService,
+		transactor,
+		repository.NewAuditCheckpointRepository(dataSource),
+		repository.NewAuditEventRepository(dataSource),
+	)
+
+	jwk, err := service.NewJwkService(config.SecurityConfig, auditService, jwtService, jwkRepository).ImportJwk(context.Background(), data)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Jwk imported", "id", jwk.Id, "kid", jwk.Kid, "use", jwk.Use, "alg", jwk.Alg, "status", jwk.Status)
+	return nil
+}
+
+func rewrapJwks(config *config.ServerConfig) error {
+	jwkCipher, err := newJwkCipher(config.SecurityConfig)
+	if err != nil {
+		return err
+	}
+
+	dataSource := db.NewDataSource(config.DbConfig)
+	defer dataSource.Close()
+
+	count, err := repository.NewJwkRepository(dataSource, jwkCipher).RewrapJwks(context.Background())
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Jwks re-wrapped", "count", count, "kekVersion", jwkCipher.KekVersion())
+	return nil
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/janobono/auth-service/internal/service"
+)
+
+func initDefaultMailTemplates(mailTemplateService *service.MailTemplateService) {
+	slog.Info("Initializing default mail templates")
+
+	if err := mailTemplateService.SeedMailTemplates(context.Background()); err != nil {
+		slog.Error("Failed to seed mail templates", "error", err)
+		panic(err)
+	}
+
+	slog.Info("Default mail templates initialized")
+}
@@ -29,7 +29,9 @@ func (s *GrpcServer) Start() *grpc.Server {
 		panic(err)
 	}
 
-	grpcTokenInterceptor := security.NewGrpcTokenInterceptor(impl.NewUserDetailDecoder(s.services.JwtService, s.services.UserService)).InterceptToken(
+	userDetailDecoder := impl.NewUserDetailDecoder(s.services.UserDetailService)
+
+	grpcTokenInterceptor := security.NewGrpcTokenInterceptor(userDetailDecoder).InterceptToken(
 		[]security.GrpcSecuredMethod{
 			{
 				Method:      proto.User_SearchUsers_FullMethodName,
@@ -41,10 +43,27 @@ func (s *GrpcServer) Start() *grpc.Server {
 			},
 		})
 
-	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(grpcTokenInterceptor))
+	grpcStreamTokenInterceptor := impl.NewStreamTokenInterceptor(userDetailDecoder,
+		[]security.GrpcSecuredMethod{
+			{
+				Method:      proto.Audit_ExportAuditEvents_FullMethodName,
+				Authorities: s.config.SecurityConfig.WriteAuthorities,
+			},
+			{
+				Method:      proto.User_WatchUsers_FullMethodName,
+				Authorities: append(s.config.SecurityConfig.ReadAuthorities, s.config.SecurityConfig.WriteAuthorities...),
+			},
+		})
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcTokenInterceptor, impl.NewRequestInfoInterceptor()),
+		grpc.StreamInterceptor(grpcStreamTokenInterceptor),
+	)
 
+	proto.RegisterAuditServer(grpcServer, impl.NewAuditServer(s.services.AuditService))
 	proto.RegisterAuthServer(grpcServer, impl.NewAuthServer(s.services.AuthService))
-	proto.RegisterUserServer(grpcServer, impl.NewUserServer(s.services.UserService))
+	proto.RegisterJwksServer(grpcServer, impl.NewJwksServer(s.services.JwkService))
+	proto.RegisterUserServer(grpcServer, impl.NewUserServer(s.services.UserService, s.services.OutboxService))
 
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
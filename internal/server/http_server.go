@@ -24,19 +24,26 @@ func (s *HttpServer) Start() *http.Server {
 	slog.Info("Starting http server...")
 
 	handleFunctions := openapi.ApiHandleFunctions{
-		AttributeControllerAPI: impl.NewAttributeController(s.services.AttributeService),
-		AuthControllerAPI:      impl.NewAuthController(s.services.AuthService),
-		AuthorityControllerAPI: impl.NewAuthorityController(s.services.AuthorityService),
-		HealthControllerAPI:    impl.NewHealthController(),
-		JwksControllerAPI:      impl.NewJwksController(s.services.JwkService),
-		UserControllerAPI:      impl.NewUserController(s.services.UserService),
+		AttributeControllerAPI:    impl.NewAttributeController(s.services.AttributeService),
+		AuditEventControllerAPI:   impl.NewAuditEventController(s.services.AuditService),
+		AuthControllerAPI:         impl.NewAuthController(s.services.AuthService, s.services.LoginHistoryService),
+		AuthorityControllerAPI:    impl.NewAuthorityController(s.services.AuthorityService),
+		ConfigControllerAPI:       impl.NewConfigController(s.services.ConfigService),
+		HealthControllerAPI:       impl.NewHealthController(),
+		InvitationControllerAPI:   impl.NewInvitationController(s.services.InvitationService),
+		JwkKeyControllerAPI:       impl.NewJwkKeyController(s.services.JwkService),
+		JwksControllerAPI:         impl.NewJwksController(s.services.JwkService),
+		MailControllerAPI:         impl.NewMailController(s.services.MailService),
+		MailTemplateControllerAPI: impl.NewMailTemplateController(s.services.MailTemplateService),
+		UserControllerAPI:         impl.NewUserController(s.services.LoginHistoryService, s.services.UserService),
+		WebhookControllerAPI:      impl.NewWebhookController(s.services.WebhookService),
 	}
 	router := impl.NewRouter(impl.RouterContext{
 		HandleFunctions:  handleFunctions,
 		ContextPath:      s.config.ContextPath,
 		ReadAuthorities:  s.config.SecurityConfig.ReadAuthorities,
 		WriteAuthorities: s.config.SecurityConfig.WriteAuthorities,
-		HttpHandlers:     impl.NewHttpHandlers(s.services.JwtService, s.services.UserService),
+		HttpHandlers:     impl.NewHttpHandlers(s.services.UserDetailService),
 	})
 
 	router.Use(cors.New(cors.Config{
@@ -0,0 +1,62 @@
+package impl
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+type auditEventController struct {
+	auditService *service.AuditService
+}
+
+var _ openapi.AuditEventControllerAPI = (*auditEventController)(nil)
+
+func NewAuditEventController(auditService *service.AuditService) openapi.AuditEventControllerAPI {
+	return &auditEventController{auditService}
+}
+
+func (a *auditEventController) GetAuditEvents(ctx *gin.Context) {
+	from, ok := parseTime(ctx, "from")
+	if !ok {
+		return
+	}
+
+	to, ok := parseTime(ctx, "to")
+	if !ok {
+		return
+	}
+
+	result, err := a.auditService.GetAuditEvents(
+		ctx.Request.Context(),
+		&service.SearchAuditEventCriteria{
+			ActorID:  ctx.Query("actorId"),
+			TargetID: ctx.Query("targetId"),
+			Action:   ctx.Query("action"),
+			From:     from,
+			To:       to,
+		},
+		parsePageable(ctx, "created_at DESC"))
+
+	if err != nil {
+		slog.Error("Failed to get audit events", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (a *auditEventController) VerifyAuditChain(ctx *gin.Context) {
+	result, err := a.auditService.VerifyAuditChain(ctx.Request.Context())
+	if err != nil {
+		slog.Error("Failed to verify audit chain", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
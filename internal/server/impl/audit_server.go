@@ -0,0 +1,85 @@
+package impl
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/generated/proto"
+	"github.com/janobono/auth-service/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type auditServer struct {
+	proto.UnimplementedAuditServer
+	auditService *service.AuditService
+}
+
+var _ proto.AuditServer = (*auditServer)(nil)
+
+func NewAuditServer(auditService *service.AuditService) proto.AuditServer {
+	return &auditServer{auditService: auditService}
+}
+
+func (as *auditServer) ExportAuditEvents(criteria *proto.AuditEventCriteria, stream grpc.ServerStreamingServer[proto.AuditEvent]) error {
+	var from, to *time.Time
+	if criteria.From != nil {
+		value := criteria.From.AsTime()
+		from = &value
+	}
+	if criteria.To != nil {
+		value := criteria.To.AsTime()
+		to = &value
+	}
+
+	err := as.auditService.ExportAuditEvents(stream.Context(),
+		&service.SearchAuditEventCriteria{
+			ActorID:  criteria.ActorId,
+			TargetID: criteria.TargetId,
+			Action:   criteria.Action,
+			From:     from,
+			To:       to,
+		},
+		func(auditEvent *openapi.AuditEventDetail) error {
+			return stream.Send(as.protoAuditEvent(auditEvent))
+		},
+	)
+
+	if err != nil {
+		slog.Error("ExportAuditEvents failed", "error", err)
+		return status.Errorf(codes.Internal, "%s", err.Error())
+	}
+
+	return nil
+}
+
+func (as *auditServer) protoAuditEvent(auditEvent *openapi.AuditEventDetail) *proto.AuditEvent {
+	return &proto.AuditEvent{
+		Id:         auditEvent.Id,
+		CreatedAt:  timestamppb.New(auditEvent.CreatedAt),
+		ActorId:    auditEvent.ActorId,
+		ActorEmail: auditEvent.ActorEmail,
+		Action:     auditEvent.Action,
+		TargetType: auditEvent.TargetType,
+		TargetId:   auditEvent.TargetId,
+		Ip:         auditEvent.Ip,
+		UserAgent:  auditEvent.UserAgent,
+		Before:     as.toJson(auditEvent.Before),
+		After:      as.toJson(auditEvent.After),
+	}
+}
+
+func (as *auditServer) toJson(value map[string]interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
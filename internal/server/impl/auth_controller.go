@@ -8,16 +8,44 @@ import (
 	"github.com/janobono/auth-service/generated/openapi"
 	"github.com/janobono/auth-service/internal/service"
 	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
 )
 
 type authController struct {
-	authService *service.AuthService
+	authService         *service.AuthService
+	loginHistoryService *service.LoginHistoryService
 }
 
 var _ openapi.AuthControllerAPI = (*authController)(nil)
 
-func NewAuthController(authService *service.AuthService) openapi.AuthControllerAPI {
-	return &authController{authService}
+func NewAuthController(authService *service.AuthService, loginHistoryService *service.LoginHistoryService) openapi.AuthControllerAPI {
+	return &authController{authService, loginHistoryService}
+}
+
+func (a *authController) AcceptInvitation(ctx *gin.Context) {
+	var data openapi.AcceptInvitation
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return
+	}
+
+	if common.IsBlank(data.Token) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'token' must not be blank")
+		return
+	}
+	if common.IsBlank(data.Password) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'password' must not be blank")
+		return
+	}
+
+	authentificationResponse, err := a.authService.AcceptInvitation(ctx.Request.Context(), &data)
+	if err != nil {
+		slog.Error("Failed to accept invitation", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, authentificationResponse)
 }
 
 func (a *authController) ChangeEmail(ctx *gin.Context) {
@@ -151,6 +179,28 @@ func (a *authController) Confirm(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, authentificationResponse)
 }
 
+func (a *authController) GetLoginHistory(ctx *gin.Context) {
+	userDetail, ok := getUserDetail(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := db2.ParseUUID(userDetail.Id)
+	if err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, err.Error())
+		return
+	}
+
+	result, err := a.loginHistoryService.GetLoginHistory(ctx.Request.Context(), id, parsePageable(ctx, "created_at DESC"))
+	if err != nil {
+		slog.Error("Failed to get login history", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
 func (a *authController) GetUserDetail(ctx *gin.Context) {
 	userDetail, ok := getUserDetail(ctx)
 	if !ok {
@@ -249,6 +299,54 @@ func (a *authController) ResetPassword(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *authController) ResetPasswordComplete(ctx *gin.Context) {
+	var data openapi.ResetPasswordComplete
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return
+	}
+
+	if common.IsBlank(data.Token) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'token' must not be blank")
+		return
+	}
+	if common.IsBlank(data.Password) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'password' must not be blank")
+		return
+	}
+
+	authentificationResponse, err := a.authService.ResetPasswordComplete(ctx.Request.Context(), &data)
+	if err != nil {
+		slog.Error("Failed to complete reset password", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, authentificationResponse)
+}
+
+func (a *authController) RevokeSession(ctx *gin.Context) {
+	var data openapi.RevokeSession
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return
+	}
+
+	if common.IsBlank(data.Token) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'token' must not be blank")
+		return
+	}
+
+	err := a.loginHistoryService.RevokeSession(ctx.Request.Context(), &data)
+	if err != nil {
+		slog.Error("Failed to revoke session", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
 func (a *authController) SignIn(ctx *gin.Context) {
 	var data openapi.SignIn
 	if err := ctx.ShouldBindJSON(&data); err != nil {
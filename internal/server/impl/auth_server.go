@@ -43,6 +43,13 @@ func (as *authServer) Refresh(ctx context.Context, refreshToken *wrapperspb.Stri
 		switch {
 		case common.IsCode(err, string(openapi.INVALID_FIELD)):
 			return nil, status.Errorf(codes.InvalidArgument, "%s", err.Error())
+		case common.IsCode(err, string(openapi.INVALID_TOKEN)):
+			return nil, status.Errorf(codes.Unauthenticated, "%s", err.Error())
+		case common.IsCode(err, string(openapi.NOT_FOUND)):
+			return nil, status.Errorf(codes.NotFound, "%s", err.Error())
+		case common.IsCode(err, string(openapi.USER_NOT_ENABLED)),
+			common.IsCode(err, string(openapi.USER_NOT_CONFIRMED)):
+			return nil, status.Errorf(codes.PermissionDenied, "%s", err.Error())
 		default:
 			return nil, status.Errorf(codes.Internal, "%s", err.Error())
 		}
@@ -61,9 +68,9 @@ func (as *authServer) SignIn(ctx context.Context, signInData *proto.SignInData)
 		switch {
 		case common.IsCode(err, string(openapi.NOT_FOUND)):
 			return nil, status.Errorf(codes.NotFound, "%s", err.Error())
-		case common.IsCode(err, string(openapi.INVALID_CREDENTIALS)):
-		case common.IsCode(err, string(openapi.USER_NOT_ENABLED)):
-		case common.IsCode(err, string(openapi.USER_NOT_CONFIRMED)):
+		case common.IsCode(err, string(openapi.INVALID_CREDENTIALS)),
+			common.IsCode(err, string(openapi.USER_NOT_ENABLED)),
+			common.IsCode(err, string(openapi.USER_NOT_CONFIRMED)):
 			return nil, status.Errorf(codes.PermissionDenied, "%s", err.Error())
 		default:
 			return nil, status.Errorf(codes.Internal, "%s", err.Error())
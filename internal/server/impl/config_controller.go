@@ -0,0 +1,23 @@
+package impl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+type configController struct {
+	configService *service.ConfigService
+}
+
+var _ openapi.ConfigControllerAPI = (*configController)(nil)
+
+func NewConfigController(configService *service.ConfigService) openapi.ConfigControllerAPI {
+	return &configController{configService}
+}
+
+func (c *configController) GetConfig(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.configService.GetConfig())
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
 	"github.com/janobono/go-util/security"
 )
 
@@ -22,14 +23,18 @@ func NewRouter(routerContext RouterContext) *gin.Engine {
 
 	authMiddleware := security.NewHttpTokenMiddleware[*openapi.UserDetail](security.HttpSecurityConfig{
 		PublicEndpoints: map[string]struct{}{
-			fmt.Sprintf("POST:%s/confirm", routerContext.ContextPath):              {},
-			fmt.Sprintf("POST:%s/resend-confirmation", routerContext.ContextPath):  {},
-			fmt.Sprintf("POST:%s/reset-password", routerContext.ContextPath):       {},
-			fmt.Sprintf("POST:%s/sign-in", routerContext.ContextPath):              {},
-			fmt.Sprintf("POST:%s/sign-up", routerContext.ContextPath):              {},
-			fmt.Sprintf("GET:%s/livez", routerContext.ContextPath):                 {},
-			fmt.Sprintf("GET:%s/readyz", routerContext.ContextPath):                {},
-			fmt.Sprintf("GET:%s/.well-known/jwks.json", routerContext.ContextPath): {},
+			fmt.Sprintf("POST:%s/accept-invitation", routerContext.ContextPath):       {},
+			fmt.Sprintf("POST:%s/confirm", routerContext.ContextPath):                 {},
+			fmt.Sprintf("POST:%s/resend-confirmation", routerContext.ContextPath):     {},
+			fmt.Sprintf("POST:%s/reset-password", routerContext.ContextPath):          {},
+			fmt.Sprintf("POST:%s/reset-password/complete", routerContext.ContextPath): {},
+			fmt.Sprintf("POST:%s/revoke-session", routerContext.ContextPath):          {},
+			fmt.Sprintf("POST:%s/sign-in", routerContext.ContextPath):                 {},
+			fmt.Sprintf("POST:%s/sign-up", routerContext.ContextPath):                 {},
+			fmt.Sprintf("GET:%s/config", routerContext.ContextPath):                   {},
+			fmt.Sprintf("GET:%s/livez", routerContext.ContextPath):                    {},
+			fmt.Sprintf("GET:%s/readyz", routerContext.ContextPath):                   {},
+			fmt.Sprintf("GET:%s/.well-known/jwks.json", routerContext.ContextPath):    {},
 		},
 		Authorities: map[string][]string{
 			"GET:/attributes":     append(routerContext.ReadAuthorities, routerContext.WriteAuthorities...),
@@ -37,9 +42,13 @@ func NewRouter(routerContext RouterContext) *gin.Engine {
 			"POST:/attributes":    routerContext.WriteAuthorities,
 			"PUT:/attributes/:id": routerContext.WriteAuthorities,
 
+			"GET:/audit-events":        routerContext.WriteAuthorities,
+			"GET:/audit-events/verify": routerContext.WriteAuthorities,
+
 			"POST:/auth/change-email":           {},
 			"POST:/auth/change-password":        {},
 			"POST:/auth/change-user-attributes": {},
+			"GET:/auth/login-history":           {},
 			"GET:/auth/user-detail":             {},
 
 			"GET:/authorities":     append(routerContext.ReadAuthorities, routerContext.WriteAuthorities...),
@@ -47,6 +56,26 @@ func NewRouter(routerContext RouterContext) *gin.Engine {
 			"POST:/authorities":    routerContext.WriteAuthorities,
 			"PUT:/authorities/:id": routerContext.WriteAuthorities,
 
+			"GET:/invitations":             append(routerContext.ReadAuthorities, routerContext.WriteAuthorities...),
+			"POST:/invitations/:id/resend": routerContext.WriteAuthorities,
+			"POST:/invitations/:id/revoke": routerContext.WriteAuthorities,
+
+			"GET:/jwks/keys":             routerContext.WriteAuthorities,
+			"POST:/jwks/keys":            routerContext.WriteAuthorities,
+			"POST:/jwks/keys/rotate":     routerContext.WriteAuthorities,
+			"POST:/jwks/keys/:id/revoke": routerContext.WriteAuthorities,
+
+			"GET:/mail-templates":              routerContext.WriteAuthorities,
+			"POST:/mail-templates":             routerContext.WriteAuthorities,
+			"GET:/mail-templates/:id":          routerContext.WriteAuthorities,
+			"PUT:/mail-templates/:id":          routerContext.WriteAuthorities,
+			"DELETE:/mail-templates/:id":       routerContext.WriteAuthorities,
+			"POST:/mail-templates/:id/preview": routerContext.WriteAuthorities,
+			"GET:/mail-templates/:id/versions": routerContext.WriteAuthorities,
+
+			"GET:/mails":              routerContext.WriteAuthorities,
+			"POST:/mails/:id/requeue": routerContext.WriteAuthorities,
+
 			"GET:/users":                   append(routerContext.ReadAuthorities, routerContext.WriteAuthorities...),
 			"GET:/users/:id":               append(routerContext.ReadAuthorities, routerContext.WriteAuthorities...),
 			"DELETE:/users/:id":            routerContext.WriteAuthorities,
@@ -56,12 +85,22 @@ func NewRouter(routerContext RouterContext) *gin.Engine {
 			"PATCH:/users/:id/confirm":     routerContext.WriteAuthorities,
 			"PATCH:/users/:id/email":       routerContext.WriteAuthorities,
 			"PATCH:/users/:id/enable":      routerContext.WriteAuthorities,
+			"GET:/users/:id/login-history": append(routerContext.ReadAuthorities, routerContext.WriteAuthorities...),
+
+			"GET:/webhook-deliveries/:id/attempts": routerContext.WriteAuthorities,
+			"POST:/webhook-deliveries/:id/retry":   routerContext.WriteAuthorities,
+			"GET:/webhooks":                        routerContext.WriteAuthorities,
+			"GET:/webhooks/:id":                    routerContext.WriteAuthorities,
+			"DELETE:/webhooks/:id":                 routerContext.WriteAuthorities,
+			"POST:/webhooks":                       routerContext.WriteAuthorities,
+			"PUT:/webhooks/:id":                    routerContext.WriteAuthorities,
+			"GET:/webhooks/:id/deliveries":         routerContext.WriteAuthorities,
 		},
 	}, routerContext.HttpHandlers)
 
 	// ✅ Apply to group
 	group := router.Group(routerContext.ContextPath)
-	group.Use(authMiddleware.HandlerFunc())
+	group.Use(authMiddleware.HandlerFunc(), requestInfoMiddleware())
 
 	// ✅ Register routes as usual
 	for _, route := range getRoutes(routerContext.HandleFunctions) {
@@ -86,6 +125,24 @@ func NewRouter(routerContext RouterContext) *gin.Engine {
 	return router
 }
 
+func requestInfoMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestInfo := &service.RequestInfo{
+			IP:             ctx.ClientIP(),
+			UserAgent:      ctx.Request.UserAgent(),
+			AcceptLanguage: ctx.GetHeader("Accept-Language"),
+		}
+
+		if userDetail, ok := security.GetHttpUserDetail[*openapi.UserDetail](ctx); ok && userDetail != nil {
+			requestInfo.ActorID = userDetail.Id
+			requestInfo.ActorEmail = userDetail.Email
+		}
+
+		ctx.Request = ctx.Request.WithContext(service.ContextWithRequestInfo(ctx.Request.Context(), requestInfo))
+		ctx.Next()
+	}
+}
+
 func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 	return []openapi.Route{
 		{
@@ -118,6 +175,24 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/attributes/:id",
 			handleFunctions.AttributeControllerAPI.SetAttribute,
 		},
+		{
+			"GetAuditEvents",
+			http.MethodGet,
+			"/audit-events",
+			handleFunctions.AuditEventControllerAPI.GetAuditEvents,
+		},
+		{
+			"VerifyAuditChain",
+			http.MethodGet,
+			"/audit-events/verify",
+			handleFunctions.AuditEventControllerAPI.VerifyAuditChain,
+		},
+		{
+			"AcceptInvitation",
+			http.MethodPost,
+			"/auth/accept-invitation",
+			handleFunctions.AuthControllerAPI.AcceptInvitation,
+		},
 		{
 			"ChangeEmail",
 			http.MethodPost,
@@ -142,6 +217,12 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/auth/confirm",
 			handleFunctions.AuthControllerAPI.Confirm,
 		},
+		{
+			"GetLoginHistory",
+			http.MethodGet,
+			"/auth/login-history",
+			handleFunctions.AuthControllerAPI.GetLoginHistory,
+		},
 		{
 			"GetUserDetail",
 			http.MethodGet,
@@ -166,6 +247,18 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/auth/reset-password",
 			handleFunctions.AuthControllerAPI.ResetPassword,
 		},
+		{
+			"ResetPasswordComplete",
+			http.MethodPost,
+			"/auth/reset-password/complete",
+			handleFunctions.AuthControllerAPI.ResetPasswordComplete,
+		},
+		{
+			"RevokeSession",
+			http.MethodPost,
+			"/auth/revoke-session",
+			handleFunctions.AuthControllerAPI.RevokeSession,
+		},
 		{
 			"SignIn",
 			http.MethodPost,
@@ -208,6 +301,12 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/authorities/:id",
 			handleFunctions.AuthorityControllerAPI.SetAuthority,
 		},
+		{
+			"GetConfig",
+			http.MethodGet,
+			"/config",
+			handleFunctions.ConfigControllerAPI.GetConfig,
+		},
 		{
 			"Livez",
 			http.MethodGet,
@@ -220,12 +319,108 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/readyz",
 			handleFunctions.HealthControllerAPI.Readyz,
 		},
+		{
+			"GetInvitations",
+			http.MethodGet,
+			"/invitations",
+			handleFunctions.InvitationControllerAPI.GetInvitations,
+		},
+		{
+			"ResendInvitation",
+			http.MethodPost,
+			"/invitations/:id/resend",
+			handleFunctions.InvitationControllerAPI.ResendInvitation,
+		},
+		{
+			"RevokeInvitation",
+			http.MethodPost,
+			"/invitations/:id/revoke",
+			handleFunctions.InvitationControllerAPI.RevokeInvitation,
+		},
 		{
 			"GetJwks",
 			http.MethodGet,
 			"/.well-known/jwks.json",
 			handleFunctions.JwksControllerAPI.GetJwks,
 		},
+		{
+			"GetJwkKeys",
+			http.MethodGet,
+			"/jwks/keys",
+			handleFunctions.JwkKeyControllerAPI.GetJwkKeys,
+		},
+		{
+			"ImportJwkKey",
+			http.MethodPost,
+			"/jwks/keys",
+			handleFunctions.JwkKeyControllerAPI.ImportJwkKey,
+		},
+		{
+			"RotateJwkKey",
+			http.MethodPost,
+			"/jwks/keys/rotate",
+			handleFunctions.JwkKeyControllerAPI.RotateJwkKey,
+		},
+		{
+			"RevokeJwkKey",
+			http.MethodPost,
+			"/jwks/keys/:id/revoke",
+			handleFunctions.JwkKeyControllerAPI.RevokeJwkKey,
+		},
+		{
+			"GetMailTemplates",
+			http.MethodGet,
+			"/mail-templates",
+			handleFunctions.MailTemplateControllerAPI.GetMailTemplates,
+		},
+		{
+			"AddMailTemplate",
+			http.MethodPost,
+			"/mail-templates",
+			handleFunctions.MailTemplateControllerAPI.AddMailTemplate,
+		},
+		{
+			"DeleteMailTemplate",
+			http.MethodDelete,
+			"/mail-templates/:id",
+			handleFunctions.MailTemplateControllerAPI.DeleteMailTemplate,
+		},
+		{
+			"GetMailTemplate",
+			http.MethodGet,
+			"/mail-templates/:id",
+			handleFunctions.MailTemplateControllerAPI.GetMailTemplate,
+		},
+		{
+			"SetMailTemplate",
+			http.MethodPut,
+			"/mail-templates/:id",
+			handleFunctions.MailTemplateControllerAPI.SetMailTemplate,
+		},
+		{
+			"PreviewMailTemplate",
+			http.MethodPost,
+			"/mail-templates/:id/preview",
+			handleFunctions.MailTemplateControllerAPI.PreviewMailTemplate,
+		},
+		{
+			"GetMailTemplateVersions",
+			http.MethodGet,
+			"/mail-templates/:id/versions",
+			handleFunctions.MailTemplateControllerAPI.GetMailTemplateVersions,
+		},
+		{
+			"GetMails",
+			http.MethodGet,
+			"/mails",
+			handleFunctions.MailControllerAPI.GetMails,
+		},
+		{
+			"RequeueMail",
+			http.MethodPost,
+			"/mails/:id/requeue",
+			handleFunctions.MailControllerAPI.RequeueMail,
+		},
 		{
 			"AddUser",
 			http.MethodPost,
@@ -244,6 +439,12 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/users/:id",
 			handleFunctions.UserControllerAPI.GetUser,
 		},
+		{
+			"GetUserLoginHistory",
+			http.MethodGet,
+			"/users/:id/login-history",
+			handleFunctions.UserControllerAPI.GetUserLoginHistory,
+		},
 		{
 			"GetUsers",
 			http.MethodGet,
@@ -280,5 +481,53 @@ func getRoutes(handleFunctions openapi.ApiHandleFunctions) []openapi.Route {
 			"/users/:id/enable",
 			handleFunctions.UserControllerAPI.SetEnabled,
 		},
+		{
+			"GetWebhookDeliveryAttempts",
+			http.MethodGet,
+			"/webhook-deliveries/:id/attempts",
+			handleFunctions.WebhookControllerAPI.GetWebhookDeliveryAttempts,
+		},
+		{
+			"RetryWebhookDelivery",
+			http.MethodPost,
+			"/webhook-deliveries/:id/retry",
+			handleFunctions.WebhookControllerAPI.RetryWebhookDelivery,
+		},
+		{
+			"AddWebhook",
+			http.MethodPost,
+			"/webhooks",
+			handleFunctions.WebhookControllerAPI.AddWebhook,
+		},
+		{
+			"DeleteWebhook",
+			http.MethodDelete,
+			"/webhooks/:id",
+			handleFunctions.WebhookControllerAPI.DeleteWebhook,
+		},
+		{
+			"GetWebhook",
+			http.MethodGet,
+			"/webhooks/:id",
+			handleFunctions.WebhookControllerAPI.GetWebhook,
+		},
+		{
+			"GetWebhookDeliveries",
+			http.MethodGet,
+			"/webhooks/:id/deliveries",
+			handleFunctions.WebhookControllerAPI.GetWebhookDeliveries,
+		},
+		{
+			"GetWebhooks",
+			http.MethodGet,
+			"/webhooks",
+			handleFunctions.WebhookControllerAPI.GetWebhooks,
+		},
+		{
+			"SetWebhook",
+			http.MethodPut,
+			"/webhooks/:id",
+			handleFunctions.WebhookControllerAPI.SetWebhook,
+		},
 	}
 }
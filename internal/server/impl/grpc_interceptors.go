@@ -0,0 +1,89 @@
+package impl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+	"github.com/janobono/go-util/security"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedServerStream) Context() context.Context {
+	return w.ctx
+}
+
+func NewRequestInfoInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestInfo := grpcRequestInfo(ctx)
+		if userDetail, ok := security.GetGrpcUserDetail[*openapi.UserDetail](ctx); ok && userDetail != nil {
+			requestInfo.ActorID = userDetail.Id
+			requestInfo.ActorEmail = userDetail.Email
+		}
+		return handler(service.ContextWithRequestInfo(ctx, requestInfo), req)
+	}
+}
+
+func NewStreamTokenInterceptor(
+	userDetailDecoder security.UserDetailDecoder[*openapi.UserDetail],
+	methods []security.GrpcSecuredMethod,
+) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		requestInfo := grpcRequestInfo(ctx)
+
+		securedMethod := security.FindGrpcSecuredMethod(methods, info.FullMethod)
+		if securedMethod != nil {
+			md, _ := metadata.FromIncomingContext(ctx)
+			authHeader := md.Get("authorization")
+			if len(authHeader) == 0 || !strings.HasPrefix(authHeader[0], "Bearer ") {
+				return status.Errorf(codes.Unauthenticated, "missing or invalid Bearer token")
+			}
+
+			userDetail, err := userDetailDecoder.DecodeGrpcUserDetail(ctx, strings.TrimPrefix(authHeader[0], "Bearer "))
+			if err != nil {
+				return status.Errorf(codes.Unauthenticated, "%s", err.Error())
+			}
+
+			userAuthorities, err := userDetailDecoder.GetGrpcUserAuthorities(ctx, userDetail)
+			if err != nil {
+				return status.Errorf(codes.Unauthenticated, "%s", err.Error())
+			}
+
+			if len(securedMethod.Authorities) > 0 && !security.HasAnyAuthority(securedMethod.Authorities, userAuthorities) {
+				return status.Errorf(codes.PermissionDenied, "insufficient permissions")
+			}
+
+			requestInfo.ActorID = userDetail.Id
+			requestInfo.ActorEmail = userDetail.Email
+		}
+
+		return handler(srv, &wrappedServerStream{stream, service.ContextWithRequestInfo(ctx, requestInfo)})
+	}
+}
+
+func grpcRequestInfo(ctx context.Context) *service.RequestInfo {
+	requestInfo := &service.RequestInfo{}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		requestInfo.IP = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgent := md.Get("user-agent"); len(userAgent) > 0 {
+			requestInfo.UserAgent = userAgent[0]
+		}
+		if acceptLanguage := md.Get("accept-language"); len(acceptLanguage) > 0 {
+			requestInfo.AcceptLanguage = acceptLanguage[0]
+		}
+	}
+	return requestInfo
+}
@@ -10,14 +10,13 @@ import (
 )
 
 type httpHandlers struct {
-	jwtService  *service.JwtService
-	userService *service.UserService
+	userDetailService *service.UserDetailService
 }
 
 var _ security.HttpHandlers[*openapi.UserDetail] = (*httpHandlers)(nil)
 
-func NewHttpHandlers(jwtService *service.JwtService, userService *service.UserService) security.HttpHandlers[*openapi.UserDetail] {
-	return &httpHandlers{jwtService, userService}
+func NewHttpHandlers(userDetailService *service.UserDetailService) security.HttpHandlers[*openapi.UserDetail] {
+	return &httpHandlers{userDetailService}
 }
 
 func (h *httpHandlers) MissingAuthorizationHeader(c *gin.Context) {
@@ -33,17 +32,7 @@ func (h *httpHandlers) PermissionDenied(c *gin.Context) {
 }
 
 func (h *httpHandlers) DecodeUserDetail(c *gin.Context, token string) (*openapi.UserDetail, error) {
-	jwtToken, err := h.jwtService.GetAccessJwtToken(c.Request.Context())
-	if err != nil {
-		return nil, err
-	}
-
-	id, _, err := h.jwtService.ParseAuthToken(c.Request.Context(), jwtToken, token)
-	if err != nil {
-		return nil, err
-	}
-
-	return h.userService.GetUser(c.Request.Context(), id)
+	return h.userDetailService.DecodeUserDetail(c.Request.Context(), token)
 }
 
 func (h *httpHandlers) GetUserAuthorities(c *gin.Context, userDetail *openapi.UserDetail) ([]string, error) {
@@ -0,0 +1,70 @@
+package impl
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+type invitationController struct {
+	invitationService *service.InvitationService
+}
+
+var _ openapi.InvitationControllerAPI = (*invitationController)(nil)
+
+func NewInvitationController(invitationService *service.InvitationService) openapi.InvitationControllerAPI {
+	return &invitationController{invitationService}
+}
+
+func (i *invitationController) GetInvitations(ctx *gin.Context) {
+	result, err := i.invitationService.GetInvitations(
+		ctx.Request.Context(),
+		&service.SearchInvitationCriteria{
+			SearchField: ctx.Query("searchField"),
+			Status:      ctx.Query("status"),
+		},
+		parsePageable(ctx, "created_at DESC"))
+
+	if err != nil {
+		slog.Error("Failed to get invitations", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (i *invitationController) ResendInvitation(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	invitation, err := i.invitationService.ResendInvitation(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to resend invitation", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitation)
+}
+
+func (i *invitationController) RevokeInvitation(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	invitation, err := i.invitationService.RevokeInvitation(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to revoke invitation", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitation)
+}
@@ -0,0 +1,90 @@
+package impl
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+	"github.com/janobono/go-util/common"
+)
+
+type jwkKeyController struct {
+	jwkService *service.JwkService
+}
+
+var _ openapi.JwkKeyControllerAPI = (*jwkKeyController)(nil)
+
+func NewJwkKeyController(jwkService *service.JwkService) openapi.JwkKeyControllerAPI {
+	return &jwkKeyController{jwkService}
+}
+
+func (j *jwkKeyController) GetJwkKeys(ctx *gin.Context) {
+	keys, err := j.jwkService.GetJwkKeys(ctx.Request.Context())
+	if err != nil {
+		slog.Error("Failed to get jwk keys", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+func (j *jwkKeyController) ImportJwkKey(ctx *gin.Context) {
+	var data openapi.JwkImportData
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return
+	}
+	if common.IsBlank(string(data.Use)) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'use' must not be blank")
+		return
+	}
+	if common.IsBlank(data.PrivateKey) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'privateKey' must not be blank")
+		return
+	}
+
+	key, err := j.jwkService.ImportJwk(ctx.Request.Context(), &data)
+	if err != nil {
+		slog.Error("Failed to import jwk key", "kid", data.Kid, "use", data.Use, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+func (j *jwkKeyController) RotateJwkKey(ctx *gin.Context) {
+	use := ctx.Query("use")
+	if common.IsBlank(use) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'use' must not be blank")
+		return
+	}
+
+	key, err := j.jwkService.RotateJwk(ctx.Request.Context(), openapi.JwkUse(use))
+	if err != nil {
+		slog.Error("Failed to rotate jwk key", "use", use, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, key)
+}
+
+func (j *jwkKeyController) RevokeJwkKey(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	key, err := j.jwkService.RevokeJwk(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to revoke jwk key", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, key)
+}
@@ -1,8 +1,10 @@
 package impl
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/janobono/auth-service/generated/openapi"
@@ -20,12 +22,20 @@ func NewJwksController(jwksService *service.JwkService) openapi.JwksControllerAP
 }
 
 func (j *jwksController) GetJwks(ctx *gin.Context) {
-	jwks, err := j.jwksService.GetJwks(ctx.Request.Context())
+	snapshot, err := j.jwksService.GetJwks(ctx.Request.Context())
 	if err != nil {
 		slog.Error("Failed to get jwks", "error", err)
 		RespondWithServiceError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, jwks)
+	ctx.Header("ETag", snapshot.ETag)
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", snapshot.MaxAge(time.Now())))
+
+	if snapshot.Matches(ctx.GetHeader("If-None-Match")) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, snapshot.Jwks)
 }
@@ -0,0 +1,57 @@
+package impl
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/janobono/auth-service/generated/proto"
+	"github.com/janobono/auth-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type jwksServer struct {
+	proto.UnimplementedJwksServer
+	jwkService *service.JwkService
+}
+
+var _ proto.JwksServer = (*jwksServer)(nil)
+
+func NewJwksServer(jwkService *service.JwkService) proto.JwksServer {
+	return &jwksServer{jwkService: jwkService}
+}
+
+func (js *jwksServer) GetJwks(ctx context.Context, request *proto.JwksRequest) (*proto.JwksResponse, error) {
+	snapshot, err := js.jwkService.GetJwks(ctx)
+	if err != nil {
+		slog.Error("GetJwks failed", "error", err)
+		return nil, status.Errorf(codes.Internal, "%s", err.Error())
+	}
+
+	response := &proto.JwksResponse{
+		Etag:      snapshot.ETag,
+		ExpiresAt: timestamppb.New(snapshot.ExpiresAt),
+	}
+
+	if snapshot.Matches(request.IfNoneMatch) {
+		response.NotModified = true
+		return response, nil
+	}
+
+	response.Keys = make([]*proto.Jwk, len(snapshot.Jwks.Keys))
+	for i, key := range snapshot.Jwks.Keys {
+		response.Keys[i] = &proto.Jwk{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		}
+	}
+	return response, nil
+}
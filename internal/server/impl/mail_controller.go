@@ -0,0 +1,47 @@
+package impl
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+type mailController struct {
+	mailService *service.MailService
+}
+
+var _ openapi.MailControllerAPI = (*mailController)(nil)
+
+func NewMailController(mailService *service.MailService) openapi.MailControllerAPI {
+	return &mailController{mailService}
+}
+
+func (m *mailController) GetMails(ctx *gin.Context) {
+	result, err := m.mailService.GetMails(ctx.Request.Context(), ctx.Query("status"), parsePageable(ctx, "created_at DESC"))
+	if err != nil {
+		slog.Error("Failed to get mails", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (m *mailController) RequeueMail(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	mail, err := m.mailService.RequeueMail(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to requeue mail", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, mail)
+}
@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+	"github.com/janobono/go-util/common"
+)
+
+type mailTemplateController struct {
+	mailTemplateService *service.MailTemplateService
+}
+
+var _ openapi.MailTemplateControllerAPI = (*mailTemplateController)(nil)
+
+func NewMailTemplateController(mailTemplateService *service.MailTemplateService) openapi.MailTemplateControllerAPI {
+	return &mailTemplateController{mailTemplateService}
+}
+
+func (m *mailTemplateController) AddMailTemplate(ctx *gin.Context) {
+	var data openapi.MailTemplateData
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return
+	}
+	if common.IsBlank(string(data.Code)) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'code' must not be blank")
+		return
+	}
+	if !m.validContent(ctx, data.Subject, data.HtmlBody) {
+		return
+	}
+
+	mailTemplate, err := m.mailTemplateService.AddMailTemplate(ctx.Request.Context(), &data)
+	if err != nil {
+		slog.Error("Failed to add mail template", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, mailTemplate)
+}
+
+func (m *mailTemplateController) DeleteMailTemplate(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	err := m.mailTemplateService.DeleteMailTemplate(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to delete mail template", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (m *mailTemplateController) GetMailTemplate(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	mailTemplate, err := m.mailTemplateService.GetMailTemplate(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get mail template", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, mailTemplate)
+}
+
+func (m *mailTemplateController) GetMailTemplates(ctx *gin.Context) {
+	result, err := m.mailTemplateService.GetMailTemplates(ctx.Request.Context(), ctx.Query("code"), ctx.Query("locale"), parsePageable(ctx, "code ASC, locale ASC"))
+	if err != nil {
+		slog.Error("Failed to get mail templates", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (m *mailTemplateController) GetMailTemplateVersions(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := m.mailTemplateService.GetMailTemplateVersions(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get mail template versions", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (m *mailTemplateController) PreviewMailTemplate(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	preview, err := m.mailTemplateService.PreviewMailTemplate(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to preview mail template", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preview)
+}
+
+func (m *mailTemplateController) SetMailTemplate(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	var data openapi.MailTemplateContent
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return
+	}
+	if !m.validContent(ctx, data.Subject, data.HtmlBody) {
+		return
+	}
+
+	mailTemplate, err := m.mailTemplateService.SetMailTemplate(ctx.Request.Context(), id, &data)
+	if err != nil {
+		slog.Error("Failed to set mail template", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, mailTemplate)
+}
+
+func (m *mailTemplateController) validContent(ctx *gin.Context, subject, htmlBody string) bool {
+	if common.IsBlank(subject) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'subject' must not be blank")
+		return false
+	}
+	if common.IsBlank(htmlBody) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'htmlBody' must not be blank")
+		return false
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -52,6 +53,19 @@ func parseStringSlice(ctx *gin.Context, key string) []string {
 	return strings.Split(value, ",")
 }
 
+func parseTime(ctx *gin.Context, key string) (*time.Time, bool) {
+	value := ctx.Query(key)
+	if common.IsBlank(value) {
+		return nil, true
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'"+key+"' must be RFC3339 date-time")
+		return nil, false
+	}
+	return &result, true
+}
+
 func getAccessToken(ctx *gin.Context) (string, bool) {
 	token, ok := security.GetHttpAccessToken(ctx)
 
@@ -11,13 +11,14 @@ import (
 )
 
 type userController struct {
-	userService *service.UserService
+	loginHistoryService *service.LoginHistoryService
+	userService         *service.UserService
 }
 
 var _ openapi.UserControllerAPI = (*userController)(nil)
 
-func NewUserController(userService *service.UserService) openapi.UserControllerAPI {
-	return &userController{userService}
+func NewUserController(loginHistoryService *service.LoginHistoryService, userService *service.UserService) openapi.UserControllerAPI {
+	return &userController{loginHistoryService, userService}
 }
 
 func (u userController) AddUser(ctx *gin.Context) {
@@ -82,6 +83,22 @@ func (u userController) GetUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, user)
 }
 
+func (u userController) GetUserLoginHistory(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := u.loginHistoryService.GetLoginHistory(ctx.Request.Context(), id, parsePageable(ctx, "created_at DESC"))
+	if err != nil {
+		slog.Error("Failed to get user login history", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
 func (u userController) GetUsers(ctx *gin.Context) {
 	result, err := u.userService.GetUsers(
 		ctx.Request.Context(),
@@ -9,28 +9,17 @@ import (
 )
 
 type userDetailDecoder struct {
-	jwtService  *service.JwtService
-	userService *service.UserService
+	userDetailService *service.UserDetailService
 }
 
 var _ security.UserDetailDecoder[*openapi.UserDetail] = (*userDetailDecoder)(nil)
 
-func NewUserDetailDecoder(jwtService *service.JwtService, userService *service.UserService) security.UserDetailDecoder[*openapi.UserDetail] {
-	return &userDetailDecoder{jwtService, userService}
+func NewUserDetailDecoder(userDetailService *service.UserDetailService) security.UserDetailDecoder[*openapi.UserDetail] {
+	return &userDetailDecoder{userDetailService}
 }
 
 func (ud *userDetailDecoder) DecodeGrpcUserDetail(ctx context.Context, token string) (*openapi.UserDetail, error) {
-	jwtToken, err := ud.jwtService.GetAccessJwtToken(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	id, _, err := ud.jwtService.ParseAuthToken(ctx, jwtToken, token)
-	if err != nil {
-		return nil, err
-	}
-
-	return ud.userService.GetUser(ctx, id)
+	return ud.userDetailService.DecodeUserDetail(ctx, token)
 }
 
 func (ud *userDetailDecoder) GetGrpcUserAuthorities(ctx context.Context, userDetail *openapi.UserDetail) ([]string, error) {
@@ -9,6 +9,7 @@ import (
 	"github.com/janobono/auth-service/internal/service"
 	"github.com/janobono/go-util/common"
 	db2 "github.com/janobono/go-util/db"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -17,13 +18,14 @@ import (
 
 type userServer struct {
 	proto.UnimplementedUserServer
-	userService *service.UserService
+	userService   *service.UserService
+	outboxService *service.OutboxService
 }
 
 var _ proto.UserServer = (*userServer)(nil)
 
-func NewUserServer(userService *service.UserService) proto.UserServer {
-	return &userServer{userService: userService}
+func NewUserServer(userService *service.UserService, outboxService *service.OutboxService) proto.UserServer {
+	return &userServer{userService: userService, outboxService: outboxService}
 }
 
 func (us *userServer) SearchUsers(ctx context.Context, searchCriteria *proto.SearchCriteria) (*proto.UserPage, error) {
@@ -96,6 +98,29 @@ func (us *userServer) GetUser(ctx context.Context, id *wrapperspb.StringValue) (
 	return us.protoUser(userDetail), err
 }
 
+func (us *userServer) WatchUsers(request *proto.WatchUsersRequest, stream grpc.ServerStreamingServer[proto.UserChangeEvent]) error {
+	if request.FromSeq < 0 {
+		return status.Errorf(codes.InvalidArgument, "from_seq must not be negative")
+	}
+
+	err := us.outboxService.WatchUserEvents(stream.Context(), request.FromSeq, func(userChangeEvent *service.UserChangeEvent) error {
+		return stream.Send(&proto.UserChangeEvent{
+			Seq:       userChangeEvent.Seq,
+			Id:        userChangeEvent.ID,
+			Type:      userChangeEvent.Type,
+			CreatedAt: timestamppb.New(userChangeEvent.CreatedAt),
+			User:      us.protoUser(userChangeEvent.User),
+		})
+	})
+
+	if err != nil {
+		slog.Error("WatchUsers failed", "fromSeq", request.FromSeq, "error", err)
+		return status.Errorf(codes.Internal, "%s", err.Error())
+	}
+
+	return nil
+}
+
 func (us *userServer) protoUser(userDetail *openapi.UserDetail) *proto.UserDetail {
 	var authorities = make([]string, len(userDetail.Authorities))
 	for i, authority := range userDetail.Authorities {
@@ -108,12 +133,14 @@ func (us *userServer) protoUser(userDetail *openapi.UserDetail) *proto.UserDetai
 	}
 
 	return &proto.UserDetail{
-		Id:          userDetail.Id,
-		Email:       userDetail.Email,
-		CreatedAt:   timestamppb.New(userDetail.CreatedAt),
-		Confirmed:   userDetail.Confirmed,
-		Enabled:     userDetail.Enabled,
-		Authorities: authorities,
-		Attributes:  attributes,
+		Id:           userDetail.Id,
+		Email:        userDetail.Email,
+		CreatedAt:    timestamppb.New(userDetail.CreatedAt),
+		Confirmed:    userDetail.Confirmed,
+		Enabled:      userDetail.Enabled,
+		Authorities:  authorities,
+		Attributes:   attributes,
+		PendingEmail: userDetail.PendingEmail,
+		Locale:       userDetail.Locale,
 	}
 }
@@ -0,0 +1,166 @@
+package impl
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/service"
+	"github.com/janobono/go-util/common"
+)
+
+type webhookController struct {
+	webhookService *service.WebhookService
+}
+
+var _ openapi.WebhookControllerAPI = (*webhookController)(nil)
+
+func NewWebhookController(webhookService *service.WebhookService) openapi.WebhookControllerAPI {
+	return &webhookController{webhookService}
+}
+
+func (w *webhookController) AddWebhook(ctx *gin.Context) {
+	data, ok := w.bindWebhookData(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := w.webhookService.AddWebhook(ctx.Request.Context(), data)
+	if err != nil {
+		slog.Error("Failed to add webhook", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+func (w *webhookController) DeleteWebhook(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	err := w.webhookService.DeleteWebhook(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to delete webhook", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (w *webhookController) GetWebhook(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := w.webhookService.GetWebhook(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get webhook", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+func (w *webhookController) GetWebhookDeliveries(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := w.webhookService.GetWebhookDeliveries(ctx.Request.Context(), id, ctx.Query("status"), parsePageable(ctx, "created_at DESC"))
+	if err != nil {
+		slog.Error("Failed to get webhook deliveries", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (w *webhookController) GetWebhookDeliveryAttempts(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := w.webhookService.GetWebhookDeliveryAttempts(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get webhook delivery attempts", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (w *webhookController) GetWebhooks(ctx *gin.Context) {
+	result, err := w.webhookService.GetWebhooks(ctx.Request.Context(), parsePageable(ctx, "created_at DESC"))
+	if err != nil {
+		slog.Error("Failed to get webhooks", "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (w *webhookController) RetryWebhookDelivery(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	delivery, err := w.webhookService.RetryWebhookDelivery(ctx.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to retry webhook delivery", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+func (w *webhookController) SetWebhook(ctx *gin.Context) {
+	id, ok := parseId(ctx)
+	if !ok {
+		return
+	}
+
+	data, ok := w.bindWebhookData(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := w.webhookService.SetWebhook(ctx.Request.Context(), id, data)
+	if err != nil {
+		slog.Error("Failed to set webhook", "id", id, "error", err)
+		RespondWithServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+func (w *webhookController) bindWebhookData(ctx *gin.Context) (*openapi.WebhookData, bool) {
+	var data openapi.WebhookData
+	if err := ctx.ShouldBindJSON(&data); err != nil {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_BODY, "Invalid request body")
+		return nil, false
+	}
+	if common.IsBlank(data.Url) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'url' must not be blank")
+		return nil, false
+	}
+	if common.IsBlank(data.Secret) {
+		RespondWithError(ctx, http.StatusBadRequest, openapi.INVALID_FIELD, "'secret' must not be blank")
+		return nil, false
+	}
+	return &data, true
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+)
+
+const jwkKeksScheme = "file://"
+
+func newJwkCipher(securityConfig *config.SecurityConfig) (*repository.JwkCipher, error) {
+	keks, err := parseJwkKeks(securityConfig.JwkKeks)
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewJwkCipher(securityConfig.JwkKekVersion, keks)
+}
+
+func parseJwkKeks(value string) (map[string][]byte, error) {
+	if strings.HasPrefix(value, jwkKeksScheme) {
+		content, err := os.ReadFile(strings.TrimPrefix(value, jwkKeksScheme))
+		if err != nil {
+			return nil, err
+		}
+		value = string(content)
+	}
+
+	entries := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+
+	keks := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		version, encoded, ok := strings.Cut(entry, "=")
+		version = strings.TrimSpace(version)
+		if !ok || version == "" {
+			return nil, errors.New("invalid kek entry, expected version=base64 key")
+		}
+
+		kek, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("kek version %s: %w", version, err)
+		}
+		keks[version] = kek
+	}
+
+	if len(keks) == 0 {
+		return nil, errors.New("no kek configured")
+	}
+	return keks, nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/internal/service"
+)
+
+// startJwkRevocationWatch loads the revoked keys right away and then polls them, so keys revoked by other replicas are
+// rejected here within interval.
+func startJwkRevocationWatch(ctx context.Context, interval time.Duration, jwtService *service.JwtService) {
+	if err := jwtService.RefreshRevokedJwks(ctx); err != nil {
+		slog.Error("Failed to load revoked jwks", "error", err)
+	}
+
+	if interval <= 0 {
+		slog.Info("Jwk revocation watch disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := jwtService.RefreshRevokedJwks(ctx); err != nil {
+					slog.Error("Failed to refresh revoked jwks", "error", err)
+				}
+			}
+		}
+	}()
+}
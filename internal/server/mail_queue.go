@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/internal/service"
+)
+
+func startMailQueue(ctx context.Context, interval time.Duration, mailService *service.MailService) <-chan struct{} {
+	done := make(chan struct{})
+
+	if interval <= 0 {
+		slog.Info("Mail queue disabled")
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := mailService.ProcessQueue(ctx); err != nil && ctx.Err() == nil {
+					slog.Error("Failed to process mail queue", "error", err)
+				}
+			}
+		}
+	}()
+
+	return done
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/internal/service"
+)
+
+const mailRetentionInterval = time.Hour
+
+func startMailRetention(ctx context.Context, retention time.Duration, mailService *service.MailService) {
+	if retention <= 0 {
+		slog.Info("Mail retention disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(min(retention, mailRetentionInterval))
+		defer ticker.Stop()
+
+		for {
+			if err := mailService.PurgeMailData(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to purge mail data", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -33,7 +33,7 @@ func (s *Server) Start() {
 
 	initDefaultCredentials(s.config, dataSource)
 
-	repositories := s.initializer.Repositories(dataSource)
+	repositories := s.initializer.Repositories(s.config, dataSource)
 
 	utils := s.initializer.Utils(s.config)
 
@@ -42,6 +42,34 @@ func (s *Server) Start() {
 
 	services := s.initializer.Services(s.config, repositories, utils, clients)
 
+	initDefaultMailTemplates(services.MailTemplateService)
+
+	checkAuditCheckpoints(s.config, services.JwtService)
+
+	jwkRevocationCtx, stopJwkRevocationWatch := context.WithCancel(context.Background())
+	defer stopJwkRevocationWatch()
+	startJwkRevocationWatch(jwkRevocationCtx, s.config.SecurityConfig.JwkRevocationPollInterval, services.JwtService)
+
+	checkpointCtx, stopCheckpoints := context.WithCancel(context.Background())
+	defer stopCheckpoints()
+	startAuditCheckpoints(checkpointCtx, s.config.SecurityConfig.AuditCheckpointInterval, services.AuditService)
+
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+	startWebhookDispatcher(dispatcherCtx, s.config.WebhookConfig.DispatchInterval, services.WebhookService)
+
+	userDetailCacheCtx, stopUserDetailCache := context.WithCancel(context.Background())
+	defer stopUserDetailCache()
+	startUserDetailCache(userDetailCacheCtx, s.config.SecurityConfig.UserCacheTtl, services.UserDetailService)
+
+	mailQueueCtx, stopMailQueue := context.WithCancel(context.Background())
+	defer stopMailQueue()
+	mailQueueDone := startMailQueue(mailQueueCtx, s.config.MailConfig.QueueInterval, services.MailService)
+
+	mailRetentionCtx, stopMailRetention := context.WithCancel(context.Background())
+	defer stopMailRetention()
+	startMailRetention(mailRetentionCtx, s.config.MailConfig.Retention, services.MailService)
+
 	grpcServer := NewGrpcServer(s.config, services).Start()
 	httpServer := NewHttpServer(s.config, services).Start()
 
@@ -64,5 +92,9 @@ func (s *Server) Start() {
 		slog.Info("Http server stopped gracefully")
 	}
 
+	stopMailQueue()
+	<-mailQueueDone
+	slog.Info("Mail queue stopped")
+
 	slog.Info("Server shut down")
 }
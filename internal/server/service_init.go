@@ -3,6 +3,7 @@ package server
 import (
 	"log/slog"
 
+	"github.com/janobono/auth-service/generated/openapi"
 	"github.com/janobono/auth-service/internal/config"
 	"github.com/janobono/auth-service/internal/db"
 	"github.com/janobono/auth-service/internal/repository"
@@ -13,10 +14,21 @@ import (
 )
 
 type Repositories struct {
-	AttributeRepository repository.AttributeRepository
-	AuthorityRepository repository.AuthorityRepository
-	JwkRepository       repository.JwkRepository
-	UserRepository      repository.UserRepository
+	AttributeRepository         repository.AttributeRepository
+	AuditCheckpointRepository   repository.AuditCheckpointRepository
+	AuditEventRepository        repository.AuditEventRepository
+	AuthorityRepository         repository.AuthorityRepository
+	ConfirmationTokenRepository repository.ConfirmationTokenRepository
+	InvitationRepository        repository.InvitationRepository
+	JwkRepository               repository.JwkRepository
+	LoginEventRepository        repository.LoginEventRepository
+	MailRepository              repository.MailRepository
+	MailTemplateRepository      repository.MailTemplateRepository
+	OutboxEventRepository       repository.OutboxEventRepository
+	Transactor                  repository.Transactor
+	UserRepository              repository.UserRepository
+	WebhookDeliveryRepository   repository.WebhookDeliveryRepository
+	WebhookRepository           repository.WebhookRepository
 }
 
 type Utils struct {
@@ -27,19 +39,30 @@ type Utils struct {
 type Clients struct {
 	CaptchaClient client2.CaptchaClient
 	MailClient    client2.MailClient
+	WebhookClient client2.WebhookClient
 }
 
 type Services struct {
-	AttributeService *service.AttributeService
-	AuthService      *service.AuthService
-	AuthorityService *service.AuthorityService
-	JwkService       *service.JwkService
-	JwtService       *service.JwtService
-	UserService      *service.UserService
+	AttributeService    *service.AttributeService
+	AuditService        *service.AuditService
+	AuthService         *service.AuthService
+	AuthorityService    *service.AuthorityService
+	ConfigService       *service.ConfigService
+	ConfirmationService *service.ConfirmationService
+	InvitationService   *service.InvitationService
+	JwkService          *service.JwkService
+	JwtService          *service.JwtService
+	LoginHistoryService *service.LoginHistoryService
+	MailService         *service.MailService
+	MailTemplateService *service.MailTemplateService
+	OutboxService       *service.OutboxService
+	UserDetailService   *service.UserDetailService
+	UserService         *service.UserService
+	WebhookService      *service.WebhookService
 }
 
 type Initializer interface {
-	Repositories(dataSource *db.DataSource) *Repositories
+	Repositories(serverConfig *config.ServerConfig, dataSource *db.DataSource) *Repositories
 	Utils(serverConfig *config.ServerConfig) *Utils
 	Clients(serverConfig *config.ServerConfig) *Clients
 	Services(serverConfig *config.ServerConfig, repositories *Repositories, utils *Utils, clients *Clients) *Services
@@ -54,12 +77,29 @@ func NewInitializer() Initializer {
 	return &defaultInitializer{}
 }
 
-func (di *defaultInitializer) Repositories(dataSource *db.DataSource) *Repositories {
+func (di *defaultInitializer) Repositories(serverConfig *config.ServerConfig, dataSource *db.DataSource) *Repositories {
+	jwkCipher, err := newJwkCipher(serverConfig.SecurityConfig)
+	if err != nil {
+		slog.Error("Failed to initialize jwk cipher", "error", err)
+		panic(err)
+	}
+
 	return &Repositories{
 		repository.NewAttributeRepository(dataSource),
+		repository.NewAuditCheckpointRepository(dataSource),
+		repository.NewAuditEventRepository(dataSource),
 		repository.NewAuthorityRepository(dataSource),
-		repository.NewJwkRepository(dataSource),
+		repository.NewConfirmationTokenRepository(dataSource),
+		repository.NewInvitationRepository(dataSource),
+		repository.NewJwkRepository(dataSource, jwkCipher),
+		repository.NewLoginEventRepository(dataSource),
+		repository.NewMailRepository(dataSource),
+		repository.NewMailTemplateRepository(dataSource),
+		repository.NewOutboxEventRepository(dataSource),
+		repository.NewTransactor(dataSource),
 		repository.NewUserRepository(dataSource),
+		repository.NewWebhookDeliveryRepository(dataSource),
+		repository.NewWebhookRepository(dataSource),
 	}
 }
 
@@ -77,38 +117,158 @@ func (di *defaultInitializer) Clients(serverConfig *config.ServerConfig) *Client
 		panic(err)
 	}
 
+	mailClient, err := client2.NewMailClient(serverConfig.MailConfig)
+	if err != nil {
+		slog.Error("Failed to create mail client", "error", err)
+		panic(err)
+	}
+
 	return &Clients{
 		captchaClient,
-		client2.NewMailClient(serverConfig.MailConfig),
+		mailClient,
+		client2.NewWebhookClient(serverConfig.WebhookConfig.Timeout),
 	}
 }
 
 func (di *defaultInitializer) Services(serverConfig *config.ServerConfig, repositories *Repositories, utils *Utils, clients *Clients) *Services {
-	jwtService := service.NewJwtService(serverConfig.SecurityConfig, repositories.JwkRepository)
+	jwtService := service.NewJwtService(serverConfig.SecurityConfig, repositories.Transactor, repositories.JwkRepository)
+	auditService := service.NewAuditService(
+		jwtService,
+		repositories.Transactor,
+		repositories.AuditCheckpointRepository,
+		repositories.AuditEventRepository,
+	)
+	confirmationService := service.NewConfirmationService(
+		serverConfig.AppConfig,
+		serverConfig.SecurityConfig,
+		jwtService,
+		repositories.ConfirmationTokenRepository,
+	)
+	mailTemplates, err := service.NewMailTemplates(
+		service.MailTemplateFile{
+			Code:        openapi.SIGN_UP,
+			Subject:     serverConfig.MailConfig.SignUpMailSubject,
+			TemplateUrl: serverConfig.MailConfig.SignUpMailTemplateUrl,
+		},
+		service.MailTemplateFile{
+			Code:        openapi.RESET_PASSWORD,
+			Subject:     serverConfig.MailConfig.ResetPasswordMailSubject,
+			TemplateUrl: serverConfig.MailConfig.ResetPasswordMailTemplateUrl,
+		},
+		service.MailTemplateFile{
+			Code:        openapi.CHANGE_EMAIL,
+			Subject:     serverConfig.MailConfig.ChangeEmailMailSubject,
+			TemplateUrl: serverConfig.MailConfig.ChangeEmailMailTemplateUrl,
+		},
+		service.MailTemplateFile{
+			Code:        openapi.CHANGE_EMAIL_NOTICE,
+			Subject:     serverConfig.MailConfig.ChangeEmailNoticeMailSubject,
+			TemplateUrl: serverConfig.MailConfig.ChangeEmailNoticeMailTemplateUrl,
+		},
+		service.MailTemplateFile{
+			Code:        openapi.INVITATION,
+			Subject:     serverConfig.MailConfig.InvitationMailSubject,
+			TemplateUrl: serverConfig.MailConfig.InvitationMailTemplateUrl,
+		},
+		service.MailTemplateFile{
+			Code:        openapi.NEW_SIGN_IN,
+			Subject:     serverConfig.MailConfig.NewSignInMailSubject,
+			TemplateUrl: serverConfig.MailConfig.NewSignInMailTemplateUrl,
+		},
+	)
+	if err != nil {
+		slog.Error("Failed to load mail templates", "error", err)
+		panic(err)
+	}
+	mailTemplateService := service.NewMailTemplateService(
+		serverConfig.AppConfig,
+		auditService,
+		mailTemplates,
+		repositories.MailTemplateRepository,
+	)
+
+	mailService := service.NewMailService(
+		serverConfig.AppConfig,
+		serverConfig.MailConfig,
+		auditService,
+		clients.MailClient,
+		mailTemplateService,
+		confirmationService,
+		repositories.MailRepository,
+	)
+	loginHistoryService := service.NewLoginHistoryService(
+		serverConfig.AppConfig,
+		auditService,
+		confirmationService,
+		mailService,
+		repositories.LoginEventRepository,
+	)
+	outboxService := service.NewOutboxService(repositories.OutboxEventRepository, repositories.UserRepository)
+	invitationService := service.NewInvitationService(
+		serverConfig.AppConfig,
+		serverConfig.SecurityConfig,
+		auditService,
+		confirmationService,
+		mailService,
+		repositories.ConfirmationTokenRepository,
+		repositories.InvitationRepository,
+		repositories.UserRepository,
+	)
 
 	return &Services{
-		AttributeService: service.NewAttributeService(repositories.AttributeRepository),
+		AttributeService: service.NewAttributeService(auditService, outboxService, repositories.AttributeRepository, repositories.UserRepository),
+		AuditService:     auditService,
 		AuthService: service.NewAuthService(
 			serverConfig.AppConfig,
-			serverConfig.MailConfig,
 			utils.PasswordEncoder,
 			utils.RandomString,
 			clients.CaptchaClient,
-			clients.MailClient,
 			jwtService,
+			confirmationService,
+			auditService,
+			loginHistoryService,
+			mailService,
+			outboxService,
 			repositories.AttributeRepository,
 			repositories.AuthorityRepository,
+			repositories.InvitationRepository,
+			repositories.UserRepository,
+		),
+		AuthorityService:    service.NewAuthorityService(auditService, outboxService, repositories.AuthorityRepository, repositories.UserRepository),
+		ConfigService:       service.NewConfigService(serverConfig.AppConfig),
+		ConfirmationService: confirmationService,
+		InvitationService:   invitationService,
+		JwkService:          service.NewJwkService(serverConfig.SecurityConfig, auditService, jwtService, repositories.JwkRepository),
+		JwtService:          jwtService,
+		LoginHistoryService: loginHistoryService,
+		MailService:         mailService,
+		MailTemplateService: mailTemplateService,
+		OutboxService:       outboxService,
+		UserDetailService: service.NewUserDetailService(
+			serverConfig.SecurityConfig,
+			jwtService,
+			confirmationService,
+			outboxService,
 			repositories.UserRepository,
 		),
-		AuthorityService: service.NewAuthorityService(repositories.AuthorityRepository),
-		JwkService:       service.NewJwkService(repositories.JwkRepository),
-		JwtService:       jwtService,
 		UserService: service.NewUserService(
 			utils.PasswordEncoder,
 			utils.RandomString,
+			auditService,
+			mailService,
+			invitationService,
+			outboxService,
 			repositories.AttributeRepository,
 			repositories.AuthorityRepository,
 			repositories.UserRepository,
 		),
+		WebhookService: service.NewWebhookService(
+			serverConfig.WebhookConfig,
+			auditService,
+			clients.WebhookClient,
+			repositories.OutboxEventRepository,
+			repositories.WebhookDeliveryRepository,
+			repositories.WebhookRepository,
+		),
 	}
 }
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/internal/service"
+)
+
+// startUserDetailCache keeps the user detail cache in sync with user changes, a failed watch is restarted after
+// retryInterval while cached entries still expire on their own.
+func startUserDetailCache(ctx context.Context, retryInterval time.Duration, userDetailService *service.UserDetailService) {
+	if !userDetailService.CacheEnabled() {
+		slog.Info("User detail cache disabled")
+		return
+	}
+
+	go func() {
+		for {
+			if err := userDetailService.WatchUserChanges(ctx); err != nil {
+				slog.Error("Failed to watch user changes", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}()
+}
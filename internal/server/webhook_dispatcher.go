@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/janobono/auth-service/internal/service"
+)
+
+func startWebhookDispatcher(ctx context.Context, interval time.Duration, webhookService *service.WebhookService) {
+	if interval <= 0 {
+		slog.Info("Webhook dispatcher disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := webhookService.Dispatch(ctx); err != nil {
+					slog.Error("Failed to dispatch webhooks", "error", err)
+				}
+			}
+		}
+	}()
+}
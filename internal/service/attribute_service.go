@@ -13,14 +13,28 @@ import (
 )
 
 type AttributeService struct {
+	auditService        *AuditService
+	outboxService       *OutboxService
 	attributeRepository repository.AttributeRepository
+	userRepository      repository.UserRepository
 }
 
-func NewAttributeService(attributeRepository repository.AttributeRepository) *AttributeService {
-	return &AttributeService{attributeRepository}
+func NewAttributeService(
+	auditService *AuditService,
+	outboxService *OutboxService,
+	attributeRepository repository.AttributeRepository,
+	userRepository repository.UserRepository,
+) *AttributeService {
+	return &AttributeService{auditService, outboxService, attributeRepository, userRepository}
 }
 
 func (as *AttributeService) AddAttribute(ctx context.Context, data *openapi.AttributeData) (*openapi.AttributeDetail, error) {
+	return audited(ctx, as.auditService, AUDIT_ATTRIBUTE_ADDED, AUDIT_TARGET_ATTRIBUTE, "", nil, func(ctx context.Context) (*openapi.AttributeDetail, error) {
+		return as.addAttribute(ctx, data)
+	})
+}
+
+func (as *AttributeService) addAttribute(ctx context.Context, data *openapi.AttributeData) (*openapi.AttributeDetail, error) {
 	count, err := as.attributeRepository.CountByKey(ctx, data.Key)
 	if err != nil {
 		return nil, err
@@ -48,6 +62,18 @@ func (as *AttributeService) AddAttribute(ctx context.Context, data *openapi.Attr
 }
 
 func (as *AttributeService) DeleteAttribute(ctx context.Context, id pgtype.UUID) error {
+	before, err := as.GetAttribute(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = audited(ctx, as.auditService, AUDIT_ATTRIBUTE_DELETED, AUDIT_TARGET_ATTRIBUTE, id.String(), before, func(ctx context.Context) (interface{}, error) {
+		return nil, as.deleteAttribute(ctx, id)
+	})
+	return err
+}
+
+func (as *AttributeService) deleteAttribute(ctx context.Context, id pgtype.UUID) error {
 	count, err := as.attributeRepository.CountById(ctx, id)
 	if err != nil {
 		return err
@@ -57,12 +83,21 @@ func (as *AttributeService) DeleteAttribute(ctx context.Context, id pgtype.UUID)
 		return common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "attribute does not exist")
 	}
 
-	return as.attributeRepository.DeleteAttributeById(ctx, id)
+	users, err := as.userRepository.GetUsersByAttributeId(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := as.attributeRepository.DeleteAttributeById(ctx, id); err != nil {
+		return err
+	}
+
+	return as.publishUserEvents(ctx, users)
 }
 
 func (as *AttributeService) GetAttribute(ctx context.Context, id pgtype.UUID) (*openapi.AttributeDetail, error) {
 	attribute, err := as.attributeRepository.GetAttributeById(ctx, id)
-	if err != nil {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return nil, err
 	}
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -107,6 +142,17 @@ func (as *AttributeService) GetAttributes(ctx context.Context, criteria *SearchA
 }
 
 func (as *AttributeService) SetAttribute(ctx context.Context, id pgtype.UUID, data *openapi.AttributeData) (*openapi.AttributeDetail, error) {
+	before, err := as.GetAttribute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, as.auditService, AUDIT_ATTRIBUTE_UPDATED, AUDIT_TARGET_ATTRIBUTE, id.String(), before, func(ctx context.Context) (*openapi.AttributeDetail, error) {
+		return as.setAttribute(ctx, id, data)
+	})
+}
+
+func (as *AttributeService) setAttribute(ctx context.Context, id pgtype.UUID, data *openapi.AttributeData) (*openapi.AttributeDetail, error) {
 	count, err := as.attributeRepository.CountById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -134,6 +180,15 @@ func (as *AttributeService) SetAttribute(ctx context.Context, id pgtype.UUID, da
 		return nil, err
 	}
 
+	users, err := as.userRepository.GetUsersByAttributeId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.publishUserEvents(ctx, users); err != nil {
+		return nil, err
+	}
+
 	return &openapi.AttributeDetail{
 		Id:       attribute.ID.String(),
 		Key:      attribute.Key,
@@ -141,3 +196,13 @@ func (as *AttributeService) SetAttribute(ctx context.Context, id pgtype.UUID, da
 		Hidden:   attribute.Hidden,
 	}, nil
 }
+
+// publishUserEvents tells cached user details and subscribers that the users of a changed attribute changed too
+func (as *AttributeService) publishUserEvents(ctx context.Context, users []*repository.User) error {
+	for _, user := range users {
+		if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_ATTRIBUTES_CHANGED, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+)
+
+const (
+	AUDIT_TARGET_ATTRIBUTE     = "ATTRIBUTE"
+	AUDIT_TARGET_AUTHORITY     = "AUTHORITY"
+	AUDIT_TARGET_INVITATION    = "INVITATION"
+	AUDIT_TARGET_JWK           = "JWK"
+	AUDIT_TARGET_MAIL          = "MAIL"
+	AUDIT_TARGET_MAIL_TEMPLATE = "MAIL_TEMPLATE"
+	AUDIT_TARGET_USER          = "USER"
+	AUDIT_TARGET_WEBHOOK       = "WEBHOOK"
+
+	AUDIT_ATTRIBUTE_ADDED          = "ATTRIBUTE_ADDED"
+	AUDIT_ATTRIBUTE_UPDATED        = "ATTRIBUTE_UPDATED"
+	AUDIT_ATTRIBUTE_DELETED        = "ATTRIBUTE_DELETED"
+	AUDIT_AUTHORITY_ADDED          = "AUTHORITY_ADDED"
+	AUDIT_AUTHORITY_UPDATED        = "AUTHORITY_UPDATED"
+	AUDIT_AUTHORITY_DELETED        = "AUTHORITY_DELETED"
+	AUDIT_INVITATION_ACCEPTED      = "INVITATION_ACCEPTED"
+	AUDIT_INVITATION_RESENT        = "INVITATION_RESENT"
+	AUDIT_INVITATION_REVOKED       = "INVITATION_REVOKED"
+	AUDIT_JWK_IMPORTED             = "JWK_IMPORTED"
+	AUDIT_JWK_ROTATED              = "JWK_ROTATED"
+	AUDIT_JWK_REVOKED              = "JWK_REVOKED"
+	AUDIT_MAIL_REQUEUED            = "MAIL_REQUEUED"
+	AUDIT_MAIL_TEMPLATE_ADDED      = "MAIL_TEMPLATE_ADDED"
+	AUDIT_MAIL_TEMPLATE_UPDATED    = "MAIL_TEMPLATE_UPDATED"
+	AUDIT_MAIL_TEMPLATE_DELETED    = "MAIL_TEMPLATE_DELETED"
+	AUDIT_USER_ADDED               = "USER_ADDED"
+	AUDIT_USER_DELETED             = "USER_DELETED"
+	AUDIT_USER_ATTRIBUTES_SET      = "USER_ATTRIBUTES_SET"
+	AUDIT_USER_AUTHORITIES_SET     = "USER_AUTHORITIES_SET"
+	AUDIT_USER_CONFIRMED_SET       = "USER_CONFIRMED_SET"
+	AUDIT_USER_EMAIL_SET           = "USER_EMAIL_SET"
+	AUDIT_USER_ENABLED_SET         = "USER_ENABLED_SET"
+	AUDIT_SIGN_UP                  = "SIGN_UP"
+	AUDIT_SIGN_IN                  = "SIGN_IN"
+	AUDIT_SIGN_IN_FAILED           = "SIGN_IN_FAILED"
+	AUDIT_CONFIRM                  = "CONFIRM"
+	AUDIT_CHANGE_EMAIL             = "CHANGE_EMAIL"
+	AUDIT_CHANGE_PASSWORD          = "CHANGE_PASSWORD"
+	AUDIT_CHANGE_USER_ATTRIBUTES   = "CHANGE_USER_ATTRIBUTES"
+	AUDIT_RESET_PASSWORD           = "RESET_PASSWORD"
+	AUDIT_RESET_PASSWORD_COMPLETED = "RESET_PASSWORD_COMPLETED"
+	AUDIT_SESSION_REVOKED          = "SESSION_REVOKED"
+	AUDIT_WEBHOOK_ADDED            = "WEBHOOK_ADDED"
+	AUDIT_WEBHOOK_UPDATED          = "WEBHOOK_UPDATED"
+	AUDIT_WEBHOOK_DELETED          = "WEBHOOK_DELETED"
+	AUDIT_WEBHOOK_DELIVERY_RETRIED = "WEBHOOK_DELIVERY_RETRIED"
+)
+
+type RequestInfo struct {
+	ActorID        string
+	ActorEmail     string
+	IP             string
+	UserAgent      string
+	AcceptLanguage string
+}
+
+type requestInfoContextKey struct{}
+
+func ContextWithRequestInfo(ctx context.Context, requestInfo *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoContextKey{}, requestInfo)
+}
+
+func GetRequestInfo(ctx context.Context) *RequestInfo {
+	if requestInfo, ok := ctx.Value(requestInfoContextKey{}).(*RequestInfo); ok && requestInfo != nil {
+		return requestInfo
+	}
+	return &RequestInfo{}
+}
+
+func contextWithActor(ctx context.Context, actorID, actorEmail string) context.Context {
+	requestInfo := *GetRequestInfo(ctx)
+	requestInfo.ActorID = actorID
+	requestInfo.ActorEmail = actorEmail
+	return ContextWithRequestInfo(ctx, &requestInfo)
+}
+
+var errAuditChainBroken = errors.New("audit chain broken")
+
+type AuditService struct {
+	jwtService                *JwtService
+	transactor                repository.Transactor
+	auditCheckpointRepository repository.AuditCheckpointRepository
+	auditEventRepository      repository.AuditEventRepository
+}
+
+func NewAuditService(
+	jwtService *JwtService,
+	transactor repository.Transactor,
+	auditCheckpointRepository repository.AuditCheckpointRepository,
+	auditEventRepository repository.AuditEventRepository,
+) *AuditService {
+	return &AuditService{jwtService, transactor, auditCheckpointRepository, auditEventRepository}
+}
+
+func (as *AuditService) Checkpoint(ctx context.Context) error {
+	auditEvent, err := as.auditEventRepository.GetLastAuditEvent(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if auditEvent.Hash == "" {
+		return nil
+	}
+
+	auditCheckpoint, err := as.auditCheckpointRepository.GetLastAuditCheckpoint(ctx)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err == nil && auditCheckpoint.Seq == auditEvent.Seq {
+		return nil
+	}
+
+	kid, signature, err := as.jwtService.SignAuditCheckpoint(ctx, auditEvent.Seq, auditEvent.Hash)
+	if err != nil {
+		return err
+	}
+
+	_, err = as.auditCheckpointRepository.AddAuditCheckpoint(ctx, &repository.AuditCheckpointData{
+		Seq:       auditEvent.Seq,
+		Hash:      auditEvent.Hash,
+		Kid:       kid,
+		Signature: signature,
+	})
+	return err
+}
+
+func (as *AuditService) ExportAuditEvents(ctx context.Context, criteria *SearchAuditEventCriteria, fn func(*openapi.AuditEventDetail) error) error {
+	return as.auditEventRepository.ExportAuditEvents(ctx, as.toSearchCriteria(criteria), func(auditEvent *repository.AuditEvent) error {
+		return fn(as.mapAuditEventDetail(auditEvent))
+	})
+}
+
+func (as *AuditService) GetAuditEvents(ctx context.Context, criteria *SearchAuditEventCriteria, pageable *common.Pageable) (*common.Page[*openapi.AuditEventDetail], error) {
+	page, err := as.auditEventRepository.SearchAuditEvents(ctx, as.toSearchCriteria(criteria), pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.AuditEventDetail, len(page.Content))
+	for i, auditEvent := range page.Content {
+		content[i] = as.mapAuditEventDetail(auditEvent)
+	}
+
+	return &common.Page[*openapi.AuditEventDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (as *AuditService) Record(ctx context.Context, action, targetType, targetID string, before, after interface{}) error {
+	beforeMap, err := toAuditMap(before)
+	if err != nil {
+		return err
+	}
+
+	afterMap, err := toAuditMap(after)
+	if err != nil {
+		return err
+	}
+
+	if targetID == "" {
+		if id, ok := afterMap["id"].(string); ok {
+			targetID = id
+		} else if id, ok := beforeMap["id"].(string); ok {
+			targetID = id
+		}
+	}
+
+	if beforeMap != nil && afterMap != nil {
+		for k, v := range beforeMap {
+			if reflect.DeepEqual(v, afterMap[k]) {
+				delete(beforeMap, k)
+				delete(afterMap, k)
+			}
+		}
+	}
+
+	beforeJson, err := marshalAuditMap(beforeMap)
+	if err != nil {
+		return err
+	}
+
+	afterJson, err := marshalAuditMap(afterMap)
+	if err != nil {
+		return err
+	}
+
+	requestInfo := GetRequestInfo(ctx)
+
+	_, err = as.auditEventRepository.AddAuditEvent(ctx, &repository.AuditEventData{
+		ActorID:    requestInfo.ActorID,
+		ActorEmail: requestInfo.ActorEmail,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         requestInfo.IP,
+		UserAgent:  requestInfo.UserAgent,
+		Before:     beforeJson,
+		After:      afterJson,
+	})
+	return err
+}
+
+func (as *AuditService) VerifyAuditChain(ctx context.Context) (*openapi.AuditChainVerification, error) {
+	auditCheckpoints, err := as.auditCheckpointRepository.GetAuditCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &openapi.AuditChainVerification{Valid: true}
+	broken := func(auditEvent *repository.AuditEvent, reason string) error {
+		result.Valid = false
+		result.BrokenSeq = auditEvent.Seq
+		result.BrokenEventId = auditEvent.ID.String()
+		result.Reason = reason
+		return errAuditChainBroken
+	}
+
+	chained := false
+	prevHash := ""
+	index := 0
+
+	err = as.auditEventRepository.ExportAuditChain(ctx, func(auditEvent *repository.AuditEvent) error {
+		if auditEvent.Hash == "" {
+			if chained {
+				return broken(auditEvent, "missing hash")
+			}
+			return nil
+		}
+		chained = true
+
+		if auditEvent.PrevHash != prevHash {
+			return broken(auditEvent, "previous hash mismatch")
+		}
+
+		hash, err := repository.AuditEventHash(auditEvent)
+		if err != nil {
+			return err
+		}
+		if hash != auditEvent.Hash {
+			return broken(auditEvent, "hash mismatch")
+		}
+
+		for ; index < len(auditCheckpoints) && auditCheckpoints[index].Seq <= auditEvent.Seq; index++ {
+			if err := as.verifyCheckpoint(ctx, auditCheckpoints[index], auditEvent); err != nil {
+				return broken(auditEvent, err.Error())
+			}
+			result.CheckedCheckpoints++
+		}
+
+		prevHash = auditEvent.Hash
+		result.CheckedEvents++
+		result.LastSeq = auditEvent.Seq
+		return nil
+	})
+
+	if errors.Is(err, errAuditChainBroken) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if index < len(auditCheckpoints) {
+		result.Valid = false
+		result.BrokenSeq = auditCheckpoints[index].Seq
+		result.Reason = fmt.Sprintf("checkpoint %d references missing audit event", auditCheckpoints[index].Seq)
+	}
+
+	return result, nil
+}
+
+func (as *AuditService) verifyCheckpoint(ctx context.Context, auditCheckpoint *repository.AuditCheckpoint, auditEvent *repository.AuditEvent) error {
+	if auditCheckpoint.Seq != auditEvent.Seq {
+		return fmt.Errorf("checkpoint %d references missing audit event", auditCheckpoint.Seq)
+	}
+
+	if auditCheckpoint.Hash != auditEvent.Hash {
+		return fmt.Errorf("checkpoint %d hash mismatch", auditCheckpoint.Seq)
+	}
+
+	seq, hash, err := as.jwtService.VerifyAuditCheckpoint(ctx, auditCheckpoint.Kid, auditCheckpoint.Signature)
+	if err != nil {
+		return fmt.Errorf("checkpoint %d signature invalid", auditCheckpoint.Seq)
+	}
+
+	if seq != auditCheckpoint.Seq || hash != auditCheckpoint.Hash {
+		return fmt.Errorf("checkpoint %d signature mismatch", auditCheckpoint.Seq)
+	}
+
+	return nil
+}
+
+func (as *AuditService) recordUserEvent(ctx context.Context, action string, user *repository.User, before, after interface{}) error {
+	if GetRequestInfo(ctx).ActorID == "" {
+		ctx = contextWithActor(ctx, user.ID.String(), user.Email)
+	}
+	return as.Record(ctx, action, AUDIT_TARGET_USER, user.ID.String(), before, after)
+}
+
+func (as *AuditService) mapAuditEventDetail(auditEvent *repository.AuditEvent) *openapi.AuditEventDetail {
+	result := &openapi.AuditEventDetail{
+		Id:         auditEvent.ID.String(),
+		CreatedAt:  auditEvent.CreatedAt,
+		ActorId:    auditEvent.ActorID,
+		ActorEmail: auditEvent.ActorEmail,
+		Action:     auditEvent.Action,
+		TargetType: auditEvent.TargetType,
+		TargetId:   auditEvent.TargetID,
+		Ip:         auditEvent.IP,
+		UserAgent:  auditEvent.UserAgent,
+	}
+	if len(auditEvent.Before) > 0 {
+		_ = json.Unmarshal(auditEvent.Before, &result.Before)
+	}
+	if len(auditEvent.After) > 0 {
+		_ = json.Unmarshal(auditEvent.After, &result.After)
+	}
+	return result
+}
+
+func (as *AuditService) toSearchCriteria(criteria *SearchAuditEventCriteria) *repository.SearchAuditEventsCriteria {
+	return &repository.SearchAuditEventsCriteria{
+		ActorID:  criteria.ActorID,
+		TargetID: criteria.TargetID,
+		Action:   criteria.Action,
+		From:     criteria.From,
+		To:       criteria.To,
+	}
+}
+
+func audited[T any](
+	ctx context.Context,
+	auditService *AuditService,
+	action, targetType, targetID string,
+	before interface{},
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	return inTx(ctx, auditService, func(ctx context.Context) (T, error) {
+		result, err := fn(ctx)
+		if err != nil {
+			return result, err
+		}
+		return result, auditService.Record(ctx, action, targetType, targetID, before, result)
+	})
+}
+
+func inTx[T any](ctx context.Context, auditService *AuditService, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := auditService.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+func toAuditMap(value interface{}) (map[string]interface{}, error) {
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil()) {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return map[string]interface{}{"value": value}, nil
+	}
+	return result, nil
+}
+
+func marshalAuditMap(value map[string]interface{}) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
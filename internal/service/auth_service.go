@@ -1,16 +1,14 @@
 package service
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"html/template"
 	"net/http"
-	"net/url"
-	"os"
+	"unicode"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/janobono/auth-service/generated/openapi"
@@ -24,53 +22,175 @@ import (
 )
 
 const (
-	CONFIRMATION_TYPE = "CONFIRMATION_TYPE"
-	ID                = "ID"
-	PASSWORD          = "PASSWORD"
-	CONFIRM_USER      = "CONFIRM_USER"
-	RESET_PASSWORD    = "RESET_PASSWORD"
+	CONFIRMATION_TYPE    = "CONFIRMATION_TYPE"
+	TOKEN_ID             = "TOKEN_ID"
+	ID                   = "ID"
+	PASSWORD             = "PASSWORD"
+	PASSWORD_FINGERPRINT = "PASSWORD_FINGERPRINT"
+	EMAIL                = "EMAIL"
+	CONFIRM_USER         = "CONFIRM_USER"
+	RESET_PASSWORD       = "RESET_PASSWORD"
+	CHANGE_EMAIL         = "CHANGE_EMAIL"
+	REVERT_EMAIL         = "REVERT_EMAIL"
+	INVITATION           = "INVITATION"
+	REVOKE_SESSION       = "REVOKE_SESSION"
+	SESSION_ID           = "SESSION_ID"
 )
 
 type AuthService struct {
-	appConfig           *config.AppConfig
-	mailConfig          *config.MailConfig
-	passwordEncoder     *security.PasswordEncoder
-	randomString        *security.RandomString
-	captchaClient       client.CaptchaClient
-	mailClient          client.MailClient
-	jwtService          *JwtService
-	attributeRepository repository.AttributeRepository
-	authorityRepository repository.AuthorityRepository
-	userRepository      repository.UserRepository
+	appConfig            *config.AppConfig
+	passwordEncoder      *security.PasswordEncoder
+	randomString         *security.RandomString
+	captchaClient        client.CaptchaClient
+	jwtService           *JwtService
+	confirmationService  *ConfirmationService
+	auditService         *AuditService
+	loginHistoryService  *LoginHistoryService
+	mailService          *MailService
+	outboxService        *OutboxService
+	attributeRepository  repository.AttributeRepository
+	authorityRepository  repository.AuthorityRepository
+	invitationRepository repository.InvitationRepository
+	userRepository       repository.UserRepository
 }
 
 func NewAuthService(
 	appConfig *config.AppConfig,
-	mailConfig *config.MailConfig,
 	passwordEncoder *security.PasswordEncoder,
 	randomString *security.RandomString,
 	captchaClient client.CaptchaClient,
-	mailClient client.MailClient,
 	jwtService *JwtService,
+	confirmationService *ConfirmationService,
+	auditService *AuditService,
+	loginHistoryService *LoginHistoryService,
+	mailService *MailService,
+	outboxService *OutboxService,
 	attributeRepository repository.AttributeRepository,
 	authorityRepository repository.AuthorityRepository,
+	invitationRepository repository.InvitationRepository,
 	userRepository repository.UserRepository,
 ) *AuthService {
 	return &AuthService{
-		appConfig:           appConfig,
-		mailConfig:          mailConfig,
-		passwordEncoder:     passwordEncoder,
-		randomString:        randomString,
-		captchaClient:       captchaClient,
-		mailClient:          mailClient,
-		jwtService:          jwtService,
-		attributeRepository: attributeRepository,
-		authorityRepository: authorityRepository,
-		userRepository:      userRepository,
+		appConfig:            appConfig,
+		passwordEncoder:      passwordEncoder,
+		randomString:         randomString,
+		captchaClient:        captchaClient,
+		jwtService:           jwtService,
+		confirmationService:  confirmationService,
+		auditService:         auditService,
+		loginHistoryService:  loginHistoryService,
+		mailService:          mailService,
+		outboxService:        outboxService,
+		attributeRepository:  attributeRepository,
+		authorityRepository:  authorityRepository,
+		invitationRepository: invitationRepository,
+		userRepository:       userRepository,
 	}
 }
 
+func (as *AuthService) AcceptInvitation(ctx context.Context, data *openapi.AcceptInvitation) (*openapi.AuthenticationResponse, error) {
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.acceptInvitation(ctx, data)
+	})
+}
+
+func (as *AuthService) acceptInvitation(ctx context.Context, data *openapi.AcceptInvitation) (*openapi.AuthenticationResponse, error) {
+	confirmationData, err := as.confirmationService.ParseToken(ctx, data.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmationData[CONFIRMATION_TYPE] != INVITATION {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	tokenId, ok := confirmationData[ID]
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	user, err := as.getUser(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := as.invitationRepository.GetInvitationByUserId(ctx, user.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) || invitation.Status() != repository.InvitationStatusPending {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVITATION_NOT_PENDING), "invitation is not pending")
+	}
+
+	savedAttributes, err := as.userRepository.GetUserAttributes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	mandatoryAttributes := make(map[string]string, len(savedAttributes))
+	for _, savedAttribute := range savedAttributes {
+		mandatoryAttributes[savedAttribute.Attribute.Key] = savedAttribute.Value
+	}
+
+	userAttributes, err := as.createAttributes(ctx, data.Attributes, mandatoryAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.confirmationService.ConsumeToken(ctx, confirmationData); err != nil {
+		return nil, err
+	}
+
+	password, err := as.passwordEncoder.Encode(data.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = as.userRepository.SetUserPassword(ctx, user.ID, password); err != nil {
+		return nil, err
+	}
+
+	if _, err = as.userRepository.SetUserAttributes(ctx, &repository.UserAttributesData{
+		UserID:     user.ID,
+		Attributes: userAttributes,
+	}); err != nil {
+		return nil, err
+	}
+
+	confirmedUser, err := as.userRepository.SetUserConfirmed(ctx, user.ID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = as.invitationRepository.SetInvitationAccepted(ctx, invitation.ID); err != nil {
+		return nil, err
+	}
+
+	if !user.Confirmed {
+		if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_CONFIRMED, confirmedUser); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_INVITATION_ACCEPTED, user, nil, nil); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
+}
+
 func (as *AuthService) ChangeEmail(ctx context.Context, userDetail *openapi.UserDetail, data *openapi.ChangeEmail) (*openapi.AuthenticationResponse, error) {
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.requestEmailChange(ctx, userDetail, data)
+	})
+}
+
+func (as *AuthService) requestEmailChange(ctx context.Context, userDetail *openapi.UserDetail, data *openapi.ChangeEmail) (*openapi.AuthenticationResponse, error) {
 	if err := as.checkCaptcha(ctx, data.CaptchaText, data.CaptchaToken); err != nil {
 		return nil, err
 	}
@@ -80,7 +200,9 @@ func (as *AuthService) ChangeEmail(ctx context.Context, userDetail *openapi.User
 		return nil, err
 	}
 
-	count, err := as.userRepository.CountByEmailAndNotId(ctx, data.Email, user.ID)
+	email := common.ToScDf(data.Email)
+
+	count, err := as.userRepository.CountByEmailAndNotId(ctx, email, user.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -92,20 +214,41 @@ func (as *AuthService) ChangeEmail(ctx context.Context, userDetail *openapi.User
 		return nil, err
 	}
 
-	user, err = as.userRepository.SetUserEmail(ctx, user.ID, data.Email)
-	if err != nil {
+	if email == user.Email {
+		user, err = as.userRepository.SetUserPendingEmail(ctx, user.ID, "")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = as.userRepository.SetUserPendingEmail(ctx, user.ID, email)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := as.mailService.SendChangeEmailMails(ctx, user, email); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_CHANGE_EMAIL, user, nil, map[string]string{"pendingEmail": user.PendingEmail}); err != nil {
 		return nil, err
 	}
 
-	authorities, err := as.getAuthorities(ctx, user.ID)
+	userClaims, err := as.getUserClaims(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return as.createAuthenticationResponse(ctx, user.ID, authorities)
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
 }
 
 func (as *AuthService) ChangePassword(ctx context.Context, userDetail *openapi.UserDetail, data *openapi.ChangePassword) (*openapi.AuthenticationResponse, error) {
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.changePassword(ctx, userDetail, data)
+	})
+}
+
+func (as *AuthService) changePassword(ctx context.Context, userDetail *openapi.UserDetail, data *openapi.ChangePassword) (*openapi.AuthenticationResponse, error) {
 	if err := as.checkCaptcha(ctx, data.CaptchaText, data.CaptchaToken); err != nil {
 		return nil, err
 	}
@@ -129,15 +272,25 @@ func (as *AuthService) ChangePassword(ctx context.Context, userDetail *openapi.U
 		return nil, err
 	}
 
-	authorities, err := as.getAuthorities(ctx, user.ID)
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_CHANGE_PASSWORD, user, nil, nil); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return as.createAuthenticationResponse(ctx, user.ID, authorities)
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
 }
 
-func (as *AuthService) ChangeUserAttributes(
+func (as *AuthService) ChangeUserAttributes(ctx context.Context, userDetail *openapi.UserDetail, data *openapi.ChangeUserAttributes) (*openapi.AuthenticationResponse, error) {
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.changeUserAttributes(ctx, userDetail, data)
+	})
+}
+
+func (as *AuthService) changeUserAttributes(
 	ctx context.Context,
 	userDetail *openapi.UserDetail,
 	data *openapi.ChangeUserAttributes,
@@ -173,16 +326,30 @@ func (as *AuthService) ChangeUserAttributes(
 		return nil, err
 	}
 
-	authorities, err := as.getAuthorities(ctx, user.ID)
+	if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_ATTRIBUTES_CHANGED, user); err != nil {
+		return nil, err
+	}
+
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_CHANGE_USER_ATTRIBUTES, user, nil, map[string][]openapi.AttributeValueData{"attributes": data.Attributes}); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return as.createAuthenticationResponse(ctx, user.ID, authorities)
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
 }
 
 func (as *AuthService) Confirm(ctx context.Context, data *openapi.Confirmation) (*openapi.AuthenticationResponse, error) {
-	confirmationData, err := as.parseConfirmationToken(ctx, data.Token)
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.confirm(ctx, data)
+	})
+}
+
+func (as *AuthService) confirm(ctx context.Context, data *openapi.Confirmation) (*openapi.AuthenticationResponse, error) {
+	confirmationData, err := as.confirmationService.ParseToken(ctx, data.Token)
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +368,10 @@ func (as *AuthService) Confirm(ctx context.Context, data *openapi.Confirmation)
 		user, err = as.confirmUser(ctx, confirmationData)
 	case RESET_PASSWORD:
 		user, err = as.resetPassword(ctx, confirmationData)
+	case CHANGE_EMAIL:
+		user, err = as.changeEmail(ctx, confirmationData)
+	case REVERT_EMAIL:
+		user, err = as.revertEmail(ctx, confirmationData)
 	default:
 		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "unsupported confirmation type")
 	}
@@ -208,12 +379,16 @@ func (as *AuthService) Confirm(ctx context.Context, data *openapi.Confirmation)
 		return nil, err
 	}
 
-	authorities, err := as.getAuthorities(ctx, user.ID)
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_CONFIRM, user, nil, map[string]string{"confirmationType": confirmationType, "email": user.Email}); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return as.createAuthenticationResponse(ctx, user.ID, authorities)
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
 }
 
 func (as *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*openapi.AuthenticationResponse, error) {
@@ -222,17 +397,35 @@ func (as *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*
 		return nil, err
 	}
 
-	id, authorities, err := as.jwtService.ParseAuthToken(ctx, refreshJwt, refreshToken)
+	id, sessionID, _, err := as.jwtService.ParseSessionToken(ctx, refreshJwt, refreshToken)
 	if err != nil {
 		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), err.Error())
 	}
 
+	if err := as.loginHistoryService.CheckSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	user, err := as.getUser(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.confirmationService.CheckConfirmed(user.Confirmed, user.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
 	accessJwt, err := as.jwtService.GetAccessJwtToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := as.jwtService.GenerateAuthToken(accessJwt, id, authorities)
+	accessToken, err := as.jwtService.GenerateSessionToken(accessJwt, id, sessionID, userClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -244,6 +437,13 @@ func (as *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*
 }
 
 func (as *AuthService) ResendConfirmation(ctx context.Context, data *openapi.ResendConfirmation) error {
+	_, err := inTx(ctx, as.auditService, func(ctx context.Context) (interface{}, error) {
+		return nil, as.resendConfirmation(ctx, data)
+	})
+	return err
+}
+
+func (as *AuthService) resendConfirmation(ctx context.Context, data *openapi.ResendConfirmation) error {
 	if err := as.checkCaptcha(ctx, data.CaptchaText, data.CaptchaToken); err != nil {
 		return err
 	}
@@ -251,7 +451,11 @@ func (as *AuthService) ResendConfirmation(ctx context.Context, data *openapi.Res
 	email := common.ToScDf(data.Email)
 
 	user, err := as.userRepository.GetUserByEmail(ctx, email)
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+	if err != nil {
+		// an unknown email gets the same answer, so the endpoint does not tell which emails are registered
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
 		return err
 	}
 
@@ -259,6 +463,13 @@ func (as *AuthService) ResendConfirmation(ctx context.Context, data *openapi.Res
 }
 
 func (as *AuthService) ResetPassword(ctx context.Context, data *openapi.ResetPassword) error {
+	_, err := inTx(ctx, as.auditService, func(ctx context.Context) (interface{}, error) {
+		return nil, as.requestPasswordReset(ctx, data)
+	})
+	return err
+}
+
+func (as *AuthService) requestPasswordReset(ctx context.Context, data *openapi.ResetPassword) error {
 	if err := as.checkCaptcha(ctx, data.CaptchaText, data.CaptchaToken); err != nil {
 		return err
 	}
@@ -277,10 +488,108 @@ func (as *AuthService) ResetPassword(ctx context.Context, data *openapi.ResetPas
 		return err
 	}
 
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_RESET_PASSWORD, user, nil, nil); err != nil {
+		return err
+	}
+
 	return as.sendResetPasswordMail(ctx, user)
 }
 
+func (as *AuthService) ResetPasswordComplete(ctx context.Context, data *openapi.ResetPasswordComplete) (*openapi.AuthenticationResponse, error) {
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.resetPasswordComplete(ctx, data)
+	})
+}
+
+func (as *AuthService) resetPasswordComplete(ctx context.Context, data *openapi.ResetPasswordComplete) (*openapi.AuthenticationResponse, error) {
+	confirmationData, err := as.confirmationService.ParseToken(ctx, data.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmationData[CONFIRMATION_TYPE] != RESET_PASSWORD {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	if _, ok := confirmationData[PASSWORD]; ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	tokenId, ok := confirmationData[ID]
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	user, err := as.getUser(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmationData[PASSWORD_FINGERPRINT] != as.passwordFingerprint(user) {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "token already used")
+	}
+
+	if err := as.checkPasswordPolicy(data.Password); err != nil {
+		return nil, err
+	}
+
+	if err := as.confirmationService.ConsumeToken(ctx, confirmationData); err != nil {
+		return nil, err
+	}
+
+	password, err := as.passwordEncoder.Encode(data.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = as.userRepository.SetUserPassword(ctx, user.ID, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_RESET_PASSWORD_COMPLETED, user, nil, nil); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
+}
+
+// signInRejection is a rejected sign-in attempt, unlike other errors it does not roll back the records of the attempt.
+type signInRejection struct {
+	err error
+}
+
+func (r *signInRejection) Error() string {
+	return r.err.Error()
+}
+
 func (as *AuthService) SignIn(ctx context.Context, data *openapi.SignIn) (*openapi.AuthenticationResponse, error) {
+	var signInErr error
+	response, err := inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		response, err := as.signIn(ctx, data)
+		var rejection *signInRejection
+		if errors.As(err, &rejection) {
+			// a rejected attempt is committed with its audit and login records, the error is returned afterwards
+			signInErr = rejection.err
+			return nil, nil
+		}
+		return response, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if signInErr != nil {
+		return nil, signInErr
+	}
+	return response, nil
+}
+
+func (as *AuthService) signIn(ctx context.Context, data *openapi.SignIn) (*openapi.AuthenticationResponse, error) {
 	email := common.ToScDf(data.Email)
 
 	user, err := as.userRepository.GetUserByEmail(ctx, email)
@@ -288,26 +597,52 @@ func (as *AuthService) SignIn(ctx context.Context, data *openapi.SignIn) (*opena
 		return nil, err
 	}
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "user not found")
+		err := common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "user not found")
+		if auditErr := as.auditService.Record(ctx, AUDIT_SIGN_IN_FAILED, AUDIT_TARGET_USER, "", nil, map[string]string{
+			"email":  email,
+			"reason": err.Code,
+		}); auditErr != nil {
+			return nil, auditErr
+		}
+		return nil, &signInRejection{err}
 	}
 
 	if err := as.checkEnabled(user); err != nil {
-		return nil, err
+		return nil, as.signInFailed(ctx, user, err)
 	}
 
 	if err := as.checkPassword(user, data.Password); err != nil {
+		return nil, as.signInFailed(ctx, user, err)
+	}
+
+	if err := as.confirmationService.CheckConfirmed(user.Confirmed, user.CreatedAt); err != nil {
+		return nil, as.signInFailed(ctx, user, err)
+	}
+
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_SIGN_IN, user, nil, nil); err != nil {
+		return nil, err
+	}
+
+	loginEvent, err := as.loginHistoryService.RecordSignIn(ctx, user, LOGIN_METHOD_PASSWORD, true)
+	if err != nil {
 		return nil, err
 	}
 
-	authorities, sErr := as.getAuthorities(ctx, user.ID)
+	userClaims, err := as.getUserClaims(ctx, user)
 	if err != nil {
-		return nil, sErr
+		return nil, err
 	}
 
-	return as.createAuthenticationResponse(ctx, user.ID, authorities)
+	return as.createSessionAuthenticationResponse(ctx, user.ID, loginEvent.ID.String(), userClaims)
 }
 
 func (as *AuthService) SignUp(ctx context.Context, data *openapi.SignUp) (*openapi.AuthenticationResponse, error) {
+	return inTx(ctx, as.auditService, func(ctx context.Context) (*openapi.AuthenticationResponse, error) {
+		return as.signUp(ctx, data)
+	})
+}
+
+func (as *AuthService) signUp(ctx context.Context, data *openapi.SignUp) (*openapi.AuthenticationResponse, error) {
 	if err := as.checkCaptcha(ctx, data.CaptchaText, data.CaptchaToken); err != nil {
 		return nil, err
 	}
@@ -341,22 +676,31 @@ func (as *AuthService) SignUp(ctx context.Context, data *openapi.SignUp) (*opena
 		Password:  password,
 		Confirmed: false,
 		Enabled:   true,
+		Locale:    PreferredLocale(GetRequestInfo(ctx).AcceptLanguage),
 	}, userAttributes, userAuthorities)
 	if err != nil {
 		return nil, err
 	}
 
-	authorities := make([]string, len(userAuthorities))
-	for i, saAuthority := range userAuthorities {
-		authorities[i] = saAuthority.Authority
+	err = as.sendConfirmationMail(ctx, user)
+	if err != nil {
+		return nil, err
 	}
 
-	err = as.sendConfirmationMail(ctx, user)
+	if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_CREATED, user); err != nil {
+		return nil, err
+	}
+
+	if err := as.auditService.recordUserEvent(ctx, AUDIT_SIGN_UP, user, nil, map[string]string{"email": user.Email}); err != nil {
+		return nil, err
+	}
+
+	userClaims, err := as.getUserClaims(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return as.createAuthenticationResponse(ctx, user.ID, authorities)
+	return as.createAuthenticationResponse(ctx, user.ID, userClaims)
 }
 
 func (as *AuthService) checkCaptcha(ctx context.Context, captchaText string, captchaToken string) error {
@@ -375,6 +719,22 @@ func (as *AuthService) checkCaptcha(ctx context.Context, captchaText string, cap
 	return nil
 }
 
+func (as *AuthService) signInFailed(ctx context.Context, user *repository.User, err error) error {
+	after := map[string]string{}
+	var serviceErr *common.ServiceError
+	if errors.As(err, &serviceErr) {
+		after["reason"] = serviceErr.Code
+	}
+	if auditErr := as.auditService.recordUserEvent(ctx, AUDIT_SIGN_IN_FAILED, user, nil, after); auditErr != nil {
+		return auditErr
+	}
+
+	if _, recordErr := as.loginHistoryService.RecordSignIn(ctx, user, LOGIN_METHOD_PASSWORD, false); recordErr != nil {
+		return recordErr
+	}
+	return &signInRejection{err}
+}
+
 func (as *AuthService) checkEnabled(user *repository.User) error {
 	if !user.Enabled {
 		return common.NewServiceError(http.StatusForbidden, string(openapi.USER_NOT_ENABLED), "account not enabled")
@@ -389,13 +749,49 @@ func (as *AuthService) checkPassword(user *repository.User, password string) err
 	return nil
 }
 
-func (as *AuthService) createAuthenticationResponse(ctx context.Context, id pgtype.UUID, authorities []string) (*openapi.AuthenticationResponse, error) {
+func (as *AuthService) checkPasswordPolicy(password string) error {
+	if len([]rune(password)) < as.appConfig.PasswordMinLength {
+		return common.NewServiceError(
+			http.StatusBadRequest, string(openapi.WEAK_PASSWORD),
+			fmt.Sprintf("password must be at least %d characters long", as.appConfig.PasswordMinLength),
+		)
+	}
+
+	var hasUppercase, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUppercase = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSpecial = true
+		}
+	}
+
+	if as.appConfig.PasswordRequireUppercase && !hasUppercase {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.WEAK_PASSWORD), "password must contain an uppercase letter")
+	}
+	if as.appConfig.PasswordRequireDigit && !hasDigit {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.WEAK_PASSWORD), "password must contain a digit")
+	}
+	if as.appConfig.PasswordRequireSpecial && !hasSpecial {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.WEAK_PASSWORD), "password must contain a special character")
+	}
+	return nil
+}
+
+func (as *AuthService) createAuthenticationResponse(ctx context.Context, id pgtype.UUID, userClaims *UserClaims) (*openapi.AuthenticationResponse, error) {
+	return as.createSessionAuthenticationResponse(ctx, id, "", userClaims)
+}
+
+func (as *AuthService) createSessionAuthenticationResponse(ctx context.Context, id pgtype.UUID, sessionID string, userClaims *UserClaims) (*openapi.AuthenticationResponse, error) {
 	accessJwt, err := as.jwtService.GetAccessJwtToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := as.jwtService.GenerateAuthToken(accessJwt, id, authorities)
+	accessToken, err := as.jwtService.GenerateSessionToken(accessJwt, id, sessionID, userClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -405,7 +801,7 @@ func (as *AuthService) createAuthenticationResponse(ctx context.Context, id pgty
 		return nil, err
 	}
 
-	refreshToken, err := as.jwtService.GenerateAuthToken(refreshJwt, id, authorities)
+	refreshToken, err := as.jwtService.GenerateSessionToken(refreshJwt, id, sessionID, userClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -552,8 +948,8 @@ func (as *AuthService) getAllAuthoritiesMap(ctx context.Context) (map[string]*re
 	return allAuthoritiesMap, nil
 }
 
-func (as *AuthService) getAuthorities(ctx context.Context, id pgtype.UUID) ([]string, error) {
-	userAuthorities, err := as.userRepository.GetUserAuthorities(ctx, id)
+func (as *AuthService) getUserClaims(ctx context.Context, user *repository.User) (*UserClaims, error) {
+	userAuthorities, err := as.userRepository.GetUserAuthorities(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -562,7 +958,26 @@ func (as *AuthService) getAuthorities(ctx context.Context, id pgtype.UUID) ([]st
 	for i, saAuthority := range userAuthorities {
 		authorities[i] = saAuthority.Authority
 	}
-	return authorities, nil
+
+	userAttributes, err := as.userRepository.GetUserAttributes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]string, len(userAttributes))
+	for _, userAttribute := range userAttributes {
+		if !userAttribute.Attribute.Hidden {
+			attributes[userAttribute.Attribute.Key] = userAttribute.Value
+		}
+	}
+
+	return &UserClaims{
+		Email:       user.Email,
+		Confirmed:   user.Confirmed,
+		CreatedAt:   user.CreatedAt,
+		Attributes:  attributes,
+		Authorities: authorities,
+	}, nil
 }
 
 func (as *AuthService) getUser(ctx context.Context, id string) (*repository.User, error) {
@@ -586,71 +1001,114 @@ func (as *AuthService) getUser(ctx context.Context, id string) (*repository.User
 	return user, nil
 }
 
-func (as *AuthService) generateConfirmationToken(ctx context.Context, data map[string]string) (string, error) {
-	jwtToken, err := as.jwtService.GetConfirmJwtToken(ctx)
+func (as *AuthService) confirmUser(ctx context.Context, confirmData map[string]string) (*repository.User, error) {
+	tokenId, ok := confirmData[ID]
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	user, err := as.getUser(ctx, tokenId)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	claims := make(jwt.MapClaims, len(data))
-	for k, v := range claims {
-		claims[k] = v.(string)
+	if err := as.confirmationService.ConsumeToken(ctx, confirmData); err != nil {
+		return nil, err
 	}
 
-	token, err := jwtToken.GenerateToken(claims)
+	confirmedUser, err := as.userRepository.SetUserConfirmed(ctx, user.ID, true)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if !user.Confirmed {
+		if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_CONFIRMED, confirmedUser); err != nil {
+			return nil, err
+		}
 	}
 
-	return token, nil
+	return confirmedUser, nil
 }
 
-func (as *AuthService) parseConfirmationToken(ctx context.Context, token string) (map[string]string, error) {
-	jwtToken, err := as.jwtService.GetConfirmJwtToken(ctx)
+func (as *AuthService) resetPassword(ctx context.Context, confirmData map[string]string) (*repository.User, error) {
+	if !as.appConfig.ResetPasswordLegacyMode {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "password reset must be completed with a new password")
+	}
+
+	tokenId, ok := confirmData[ID]
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	password, ok := confirmData[PASSWORD]
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	user, err := as.getUser(ctx, tokenId)
 	if err != nil {
 		return nil, err
 	}
 
-	claims, err := jwtToken.ParseToken(ctx, token)
-	if err != nil {
+	if err := as.confirmationService.ConsumeToken(ctx, confirmData); err != nil {
 		return nil, err
 	}
 
-	out := make(map[string]string, len(claims))
+	encodedPassword, err := as.passwordEncoder.Encode(password)
+	if err != nil {
+		return nil, err
+	}
 
-	for k, v := range claims {
-		out[k] = v.(string)
+	user, err = as.userRepository.SetUserPassword(ctx, user.ID, encodedPassword)
+	if err != nil {
+		return nil, err
 	}
 
-	return out, nil
+	return user, nil
 }
 
-func (as *AuthService) confirmUser(ctx context.Context, confirmData map[string]string) (*repository.User, error) {
+func (as *AuthService) changeEmail(ctx context.Context, confirmData map[string]string) (*repository.User, error) {
 	tokenId, ok := confirmData[ID]
 	if !ok {
 		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
 	}
 
+	email, ok := confirmData[EMAIL]
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
 	user, err := as.getUser(ctx, tokenId)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err = as.userRepository.SetUserConfirmed(ctx, user.ID, true)
+	if user.PendingEmail != email {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "email change is no longer pending")
+	}
+
+	count, err := as.userRepository.CountByEmailAndNotId(ctx, email, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	if count > 0 {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.EMAIL_ALREADY_EXISTS), "'email' already exists")
+	}
 
-	return user, nil
+	if err := as.confirmationService.ConsumeToken(ctx, confirmData); err != nil {
+		return nil, err
+	}
+
+	return as.setEmail(ctx, user, email)
 }
 
-func (as *AuthService) resetPassword(ctx context.Context, confirmData map[string]string) (*repository.User, error) {
+func (as *AuthService) revertEmail(ctx context.Context, confirmData map[string]string) (*repository.User, error) {
 	tokenId, ok := confirmData[ID]
 	if !ok {
 		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
 	}
 
-	password, ok := confirmData[PASSWORD]
+	email, ok := confirmData[EMAIL]
 	if !ok {
 		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
 	}
@@ -660,35 +1118,39 @@ func (as *AuthService) resetPassword(ctx context.Context, confirmData map[string
 		return nil, err
 	}
 
-	user, err = as.userRepository.SetUserPassword(ctx, user.ID, password)
+	count, err := as.userRepository.CountByEmailAndNotId(ctx, email, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	if count > 0 {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.EMAIL_ALREADY_EXISTS), "'email' already exists")
+	}
 
-	return user, nil
-}
+	if err := as.confirmationService.ConsumeToken(ctx, confirmData); err != nil {
+		return nil, err
+	}
 
-func (as *AuthService) tokenURL(token string) string {
-	encodedToken := url.QueryEscape(token)
-	return as.appConfig.ConfirmationWebUrl + as.appConfig.ConfirmationPath + encodedToken
+	return as.setEmail(ctx, user, email)
 }
 
-func (as *AuthService) formatBody(templateUrl string, data interface{}) (string, error) {
-	mailTemplateString, err := os.ReadFile(templateUrl)
+func (as *AuthService) setEmail(ctx context.Context, user *repository.User, email string) (*repository.User, error) {
+	changedUser, err := as.userRepository.SetUserEmail(ctx, user.ID, email)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	mailTemplate, err := template.New("mail").Parse(string(mailTemplateString))
-	if err != nil {
-		return "", err
+	if changedUser.Email != user.Email {
+		if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_EMAIL_CHANGED, changedUser); err != nil {
+			return nil, err
+		}
 	}
 
-	var buffer bytes.Buffer
-	if err := mailTemplate.Execute(&buffer, data); err != nil {
-		return "", err
-	}
-	return buffer.String(), nil
+	return changedUser, nil
+}
+
+func (as *AuthService) passwordFingerprint(user *repository.User) string {
+	hash := sha256.Sum256([]byte(user.Password))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
 }
 
 func (as *AuthService) sendConfirmationMail(ctx context.Context, user *repository.User) error {
@@ -696,71 +1158,48 @@ func (as *AuthService) sendConfirmationMail(ctx context.Context, user *repositor
 		return nil
 	}
 
-	tokenData := make(map[string]string)
-	tokenData[ID] = user.ID.String()
-
-	token, err := as.generateConfirmationToken(ctx, tokenData)
+	token, err := as.confirmationService.GenerateToken(ctx, user.ID, CONFIRM_USER, nil)
 	if err != nil {
 		return err
 	}
 
-	confirmationUrl := as.tokenURL(token)
+	return as.mailService.SendMail(ctx, user.Email, user.Locale, openapi.SIGN_UP, &confirmationMailData{
+		ConfirmationUrl: as.mailService.TokenURL(as.appConfig.ConfirmationPath, token),
+	})
+}
+
+func (as *AuthService) sendResetPasswordMail(ctx context.Context, user *repository.User) error {
+	if as.appConfig.ResetPasswordLegacyMode {
+		return as.sendLegacyResetPasswordMail(ctx, user)
+	}
 
-	body, err := as.formatBody(as.mailConfig.SignUpMailTemplateUrl, struct {
-		ConfirmationUrl string
-	}{
-		ConfirmationUrl: confirmationUrl,
+	token, err := as.confirmationService.GenerateToken(ctx, user.ID, RESET_PASSWORD, map[string]string{
+		PASSWORD_FINGERPRINT: as.passwordFingerprint(user),
 	})
 	if err != nil {
 		return err
 	}
 
-	mailData := client.NewMailData()
-
-	mailData.From = as.mailConfig.User
-	mailData.Recipients = []string{user.Email}
-	mailData.Subject = as.mailConfig.SignUpMailSubject
-	mailData.Body = body
-
-	as.mailClient.SendEmail(mailData)
-	return nil
+	return as.mailService.SendMail(ctx, user.Email, user.Locale, openapi.RESET_PASSWORD, &resetPasswordMailData{
+		ConfirmationUrl: as.mailService.TokenURL(as.appConfig.ResetPasswordPath, token),
+	})
 }
 
-func (as *AuthService) sendResetPasswordMail(ctx context.Context, user *repository.User) error {
+func (as *AuthService) sendLegacyResetPasswordMail(ctx context.Context, user *repository.User) error {
 	newPassword, err := as.randomString.Generate()
 	if err != nil {
 		return err
 	}
 
-	tokenData := make(map[string]string)
-	tokenData[ID] = user.ID.String()
-	tokenData[PASSWORD] = newPassword
-
-	token, err := as.generateConfirmationToken(ctx, tokenData)
+	token, err := as.confirmationService.GenerateToken(ctx, user.ID, RESET_PASSWORD, map[string]string{
+		PASSWORD: newPassword,
+	})
 	if err != nil {
 		return err
 	}
 
-	confirmationUrl := as.tokenURL(token)
-
-	body, err := as.formatBody(as.mailConfig.ResetPasswordMailTemplateUrl, struct {
-		NewPassword     string
-		ConfirmationUrl string
-	}{
+	return as.mailService.SendMail(ctx, user.Email, user.Locale, openapi.RESET_PASSWORD, &resetPasswordMailData{
 		NewPassword:     newPassword,
-		ConfirmationUrl: confirmationUrl,
+		ConfirmationUrl: as.mailService.TokenURL(as.appConfig.ConfirmationPath, token),
 	})
-	if err != nil {
-		return err
-	}
-
-	mailData := client.NewMailData()
-
-	mailData.From = as.mailConfig.User
-	mailData.Recipients = []string{user.Email}
-	mailData.Subject = as.mailConfig.ResetPasswordMailSubject
-	mailData.Body = body
-
-	as.mailClient.SendEmail(mailData)
-	return nil
 }
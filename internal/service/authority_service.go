@@ -13,14 +13,28 @@ import (
 )
 
 type AuthorityService struct {
+	auditService        *AuditService
+	outboxService       *OutboxService
 	authorityRepository repository.AuthorityRepository
+	userRepository      repository.UserRepository
 }
 
-func NewAuthorityService(authorityRepository repository.AuthorityRepository) *AuthorityService {
-	return &AuthorityService{authorityRepository}
+func NewAuthorityService(
+	auditService *AuditService,
+	outboxService *OutboxService,
+	authorityRepository repository.AuthorityRepository,
+	userRepository repository.UserRepository,
+) *AuthorityService {
+	return &AuthorityService{auditService, outboxService, authorityRepository, userRepository}
 }
 
 func (as *AuthorityService) AddAuthority(ctx context.Context, data *openapi.AuthorityData) (*openapi.AuthorityDetail, error) {
+	return audited(ctx, as.auditService, AUDIT_AUTHORITY_ADDED, AUDIT_TARGET_AUTHORITY, "", nil, func(ctx context.Context) (*openapi.AuthorityDetail, error) {
+		return as.addAuthority(ctx, data)
+	})
+}
+
+func (as *AuthorityService) addAuthority(ctx context.Context, data *openapi.AuthorityData) (*openapi.AuthorityDetail, error) {
 	count, err := as.authorityRepository.CountByAuthority(ctx, data.Authority)
 	if err != nil {
 		return nil, err
@@ -44,6 +58,18 @@ func (as *AuthorityService) AddAuthority(ctx context.Context, data *openapi.Auth
 }
 
 func (as *AuthorityService) DeleteAuthority(ctx context.Context, id pgtype.UUID) error {
+	before, err := as.GetAuthority(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = audited(ctx, as.auditService, AUDIT_AUTHORITY_DELETED, AUDIT_TARGET_AUTHORITY, id.String(), before, func(ctx context.Context) (interface{}, error) {
+		return nil, as.deleteAuthority(ctx, id)
+	})
+	return err
+}
+
+func (as *AuthorityService) deleteAuthority(ctx context.Context, id pgtype.UUID) error {
 	count, err := as.authorityRepository.CountById(ctx, id)
 	if err != nil {
 		return err
@@ -53,12 +79,21 @@ func (as *AuthorityService) DeleteAuthority(ctx context.Context, id pgtype.UUID)
 		return common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "authority does not exist")
 	}
 
-	return as.authorityRepository.DeleteAuthorityById(ctx, id)
+	users, err := as.userRepository.GetUsersByAuthorityId(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := as.authorityRepository.DeleteAuthorityById(ctx, id); err != nil {
+		return err
+	}
+
+	return as.publishUserEvents(ctx, users)
 }
 
 func (as *AuthorityService) GetAuthority(ctx context.Context, id pgtype.UUID) (*openapi.AuthorityDetail, error) {
 	authority, err := as.authorityRepository.GetAuthorityById(ctx, id)
-	if err != nil {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return nil, err
 	}
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -99,6 +134,17 @@ func (as *AuthorityService) GetAuthorities(ctx context.Context, criteria *Search
 }
 
 func (as *AuthorityService) SetAuthority(ctx context.Context, id pgtype.UUID, data *openapi.AuthorityData) (*openapi.AuthorityDetail, error) {
+	before, err := as.GetAuthority(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, as.auditService, AUDIT_AUTHORITY_UPDATED, AUDIT_TARGET_AUTHORITY, id.String(), before, func(ctx context.Context) (*openapi.AuthorityDetail, error) {
+		return as.setAuthority(ctx, id, data)
+	})
+}
+
+func (as *AuthorityService) setAuthority(ctx context.Context, id pgtype.UUID, data *openapi.AuthorityData) (*openapi.AuthorityDetail, error) {
 	count, err := as.authorityRepository.CountById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -124,8 +170,27 @@ func (as *AuthorityService) SetAuthority(ctx context.Context, id pgtype.UUID, da
 		return nil, err
 	}
 
+	users, err := as.userRepository.GetUsersByAuthorityId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.publishUserEvents(ctx, users); err != nil {
+		return nil, err
+	}
+
 	return &openapi.AuthorityDetail{
 		Id:        authority.ID.String(),
 		Authority: authority.Authority,
 	}, nil
 }
+
+// publishUserEvents tells cached user details and subscribers that the users of a changed authority changed too
+func (as *AuthorityService) publishUserEvents(ctx context.Context, users []*repository.User) error {
+	for _, user := range users {
+		if err := as.outboxService.PublishUserEvent(ctx, openapi.USER_AUTHORITIES_CHANGED, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,8 +3,10 @@ package client
 import (
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/janobono/auth-service/internal/config"
+	"github.com/sony/gobreaker"
 	"gopkg.in/gomail.v2"
 )
 
@@ -16,6 +18,7 @@ type MailData struct {
 	Subject     string
 	ContentType string
 	Body        string
+	TextBody    string
 	Attachments map[string]string // filename -> file path
 }
 
@@ -26,23 +29,38 @@ func NewMailData() *MailData {
 }
 
 type MailClient interface {
-	SendEmail(data *MailData)
+	SendEmail(data *MailData) error
 }
 
 type mailClient struct {
-	mailConfig *config.MailConfig
+	transport mailTransport
+	breaker   *gobreaker.CircuitBreaker
 }
 
 var _ MailClient = (*mailClient)(nil)
 
-func NewMailClient(mailConfig *config.MailConfig) MailClient {
-	return &mailClient{mailConfig: mailConfig}
-}
+func NewMailClient(mailConfig *config.MailConfig) (MailClient, error) {
+	transport, err := newMailTransport(mailConfig)
+	if err != nil {
+		return nil, err
+	}
 
-func (mc *mailClient) SendEmail(data *MailData) {
-	go func() {
-		defer mc.cleanUp(data)
+	breaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "MailService",
+		MaxRequests: 5,
+		Interval:    60 * time.Second,
+		Timeout:     30 * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
+			return counts.Requests >= 5 && failureRatio >= 0.6
+		},
+	})
+
+	return &mailClient{transport: transport, breaker: breaker}, nil
+}
 
+func (mc *mailClient) SendEmail(data *MailData) error {
+	_, err := mc.breaker.Execute(func() (interface{}, error) {
 		message := gomail.NewMessage()
 		message.SetHeader("From", data.From)
 		message.SetHeader("To", data.Recipients...)
@@ -56,24 +74,25 @@ func (mc *mailClient) SendEmail(data *MailData) {
 
 		message.SetHeader("Subject", data.Subject)
 
-		message.SetBody(data.ContentType, data.Body)
+		if data.TextBody != "" {
+			message.SetBody("text/plain", data.TextBody)
+			message.AddAlternative(data.ContentType, data.Body)
+		} else {
+			message.SetBody(data.ContentType, data.Body)
+		}
 
 		for name, path := range data.Attachments {
 			message.Attach(path, gomail.Rename(name))
 		}
 
-		dialer := gomail.NewDialer(mc.mailConfig.Host, mc.mailConfig.Port, mc.mailConfig.User, mc.mailConfig.Password)
-		dialer.SSL = mc.mailConfig.TlsEnabled
-
-		if !mc.mailConfig.AuthEnabled {
-			dialer.Username = ""
-			dialer.Password = ""
-		}
+		return nil, mc.transport.Send(message, data)
+	})
+	if err != nil {
+		return err
+	}
 
-		if err := dialer.DialAndSend(message); err != nil {
-			slog.Error("Email send failed", "error", err)
-		}
-	}()
+	mc.cleanUp(data)
+	return nil
 }
 
 func (mc *mailClient) cleanUp(data *MailData) {
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/janobono/auth-service/internal/config"
+	"gopkg.in/gomail.v2"
+)
+
+const (
+	MAIL_TRANSPORT_SMTP = "smtp"
+	MAIL_TRANSPORT_FILE = "file"
+	MAIL_TRANSPORT_LOG  = "log"
+)
+
+type mailTransport interface {
+	Send(message *gomail.Message, data *MailData) error
+}
+
+func newMailTransport(mailConfig *config.MailConfig) (mailTransport, error) {
+	switch strings.ToLower(mailConfig.Transport) {
+	case "", MAIL_TRANSPORT_SMTP:
+		return &smtpMailTransport{mailConfig}, nil
+	case MAIL_TRANSPORT_FILE:
+		return newFileMailTransport(mailConfig.FileDir)
+	case MAIL_TRANSPORT_LOG:
+		return &logMailTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", mailConfig.Transport)
+	}
+}
+
+type smtpMailTransport struct {
+	mailConfig *config.MailConfig
+}
+
+func (st *smtpMailTransport) Send(message *gomail.Message, _ *MailData) error {
+	dialer := gomail.NewDialer(st.mailConfig.Host, st.mailConfig.Port, st.mailConfig.User, st.mailConfig.Password)
+	dialer.SSL = st.mailConfig.TlsEnabled
+
+	if !st.mailConfig.AuthEnabled {
+		dialer.Username = ""
+		dialer.Password = ""
+	}
+
+	return dialer.DialAndSend(message)
+}
+
+// fileMailTransport writes every message as an .eml file into a maildir: the file is created in tmp/ and renamed into
+// new/ so readers never see partially written messages.
+type fileMailTransport struct {
+	dir string
+	seq atomic.Uint64
+}
+
+func newFileMailTransport(dir string) (*fileMailTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail file dir is not configured")
+	}
+
+	for _, subDir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, subDir), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &fileMailTransport{dir: dir}, nil
+}
+
+func (ft *fileMailTransport) Send(message *gomail.Message, _ *MailData) error {
+	name := fmt.Sprintf("%d.%d_%d.eml", time.Now().UnixNano(), os.Getpid(), ft.seq.Add(1))
+	tmpPath := filepath.Join(ft.dir, "tmp", name)
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = message.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(ft.dir, "new", name))
+}
+
+type logMailTransport struct {
+}
+
+func (lt *logMailTransport) Send(_ *gomail.Message, data *MailData) error {
+	slog.Info("Mail sent",
+		"from", data.From,
+		"to", data.Recipients,
+		"cc", data.Cc,
+		"subject", data.Subject,
+	)
+
+	// the body carries tokens and, in legacy reset mode, passwords, so it is left out of production logs
+	body := data.TextBody
+	if body == "" {
+		body = data.Body
+	}
+	slog.Debug("Mail body", "subject", data.Subject, "body", body)
+	return nil
+}
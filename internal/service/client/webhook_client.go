@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type WebhookRequest struct {
+	URL     string
+	Headers map[string]string
+	Body    []byte
+}
+
+type WebhookClient interface {
+	Send(ctx context.Context, request *WebhookRequest) (int, error)
+}
+
+type webhookClient struct {
+	client *http.Client
+}
+
+var _ WebhookClient = (*webhookClient)(nil)
+
+func NewWebhookClient(timeout time.Duration) WebhookClient {
+	return &webhookClient{client: &http.Client{Timeout: timeout}}
+}
+
+func (wc *webhookClient) Send(ctx context.Context, request *WebhookRequest) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := wc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+)
+
+type ConfigService struct {
+	appConfig *config.AppConfig
+}
+
+func NewConfigService(appConfig *config.AppConfig) *ConfigService {
+	return &ConfigService{appConfig}
+}
+
+func (cs *ConfigService) GetConfig() *openapi.ClientConfig {
+	return &openapi.ClientConfig{
+		ConfirmationPolicy:    confirmationPolicy(cs.appConfig),
+		ConfirmationGraceDays: int32(cs.appConfig.ConfirmationGraceDays),
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type ConfirmationService struct {
+	appConfig                   *config.AppConfig
+	securityConfig              *config.SecurityConfig
+	jwtService                  *JwtService
+	confirmationTokenRepository repository.ConfirmationTokenRepository
+}
+
+func NewConfirmationService(
+	appConfig *config.AppConfig,
+	securityConfig *config.SecurityConfig,
+	jwtService *JwtService,
+	confirmationTokenRepository repository.ConfirmationTokenRepository,
+) *ConfirmationService {
+	return &ConfirmationService{
+		appConfig:                   appConfig,
+		securityConfig:              securityConfig,
+		jwtService:                  jwtService,
+		confirmationTokenRepository: confirmationTokenRepository,
+	}
+}
+
+func (cs *ConfirmationService) CheckConfirmed(confirmed bool, createdAt time.Time) error {
+	if confirmed {
+		return nil
+	}
+
+	switch confirmationPolicy(cs.appConfig) {
+	case openapi.NONE:
+		return nil
+	case openapi.GRACE:
+		if time.Now().Before(createdAt.Add(confirmationGracePeriod(cs.appConfig))) {
+			return nil
+		}
+	}
+
+	return common.NewServiceError(http.StatusForbidden, string(openapi.USER_NOT_CONFIRMED), "account not confirmed")
+}
+
+func (cs *ConfirmationService) GenerateToken(ctx context.Context, userID pgtype.UUID, confirmationType string, data map[string]string) (string, error) {
+	expiration, err := cs.tokenExpiration(confirmationType)
+	if err != nil {
+		return "", err
+	}
+
+	confirmationToken, err := cs.confirmationTokenRepository.AddConfirmationToken(ctx, repository.ConfirmationTokenData{
+		UserID:          userID,
+		Purpose:         confirmationType,
+		Expiration:      expiration,
+		KeepOutstanding: confirmationType == REVERT_EMAIL || confirmationType == REVOKE_SESSION,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	jwtToken, err := cs.jwtService.GetConfirmJwtToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	claims := make(jwt.MapClaims, len(data)+4)
+	for k, v := range data {
+		claims[k] = v
+	}
+	claims[TOKEN_ID] = confirmationToken.ID.String()
+	claims[CONFIRMATION_TYPE] = confirmationType
+	claims[ID] = userID.String()
+	claims["exp"] = confirmationToken.ExpiresAt.Unix()
+
+	return jwtToken.GenerateToken(claims)
+}
+
+func (cs *ConfirmationService) ParseToken(ctx context.Context, token string) (map[string]string, error) {
+	jwtToken, err := cs.jwtService.GetConfirmJwtToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := jwtToken.ParseToken(ctx, token)
+	if err != nil {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	out := make(map[string]string, len(claims))
+
+	for k, v := range claims {
+		if value, ok := v.(string); ok {
+			out[k] = value
+		}
+	}
+
+	return out, nil
+}
+
+func (cs *ConfirmationService) ConsumeToken(ctx context.Context, confirmationData map[string]string) error {
+	tokenId, err := db2.ParseUUID(confirmationData[TOKEN_ID])
+	if err != nil {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	userId, err := db2.ParseUUID(confirmationData[ID])
+	if err != nil {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	_, err = cs.confirmationTokenRepository.ConsumeConfirmationToken(ctx, repository.ConsumeConfirmationTokenData{
+		ID:      tokenId,
+		UserID:  userId,
+		Purpose: confirmationData[CONFIRMATION_TYPE],
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "token already used or expired")
+	}
+
+	return nil
+}
+
+func (cs *ConfirmationService) tokenExpiration(confirmationType string) (time.Duration, error) {
+	switch confirmationType {
+	case CONFIRM_USER:
+		return cs.securityConfig.ConfirmUserTokenExpiresIn, nil
+	case RESET_PASSWORD:
+		return cs.securityConfig.ResetPasswordTokenExpiresIn, nil
+	case CHANGE_EMAIL:
+		return cs.securityConfig.ChangeEmailTokenExpiresIn, nil
+	case REVERT_EMAIL:
+		return cs.securityConfig.RevertEmailTokenExpiresIn, nil
+	case INVITATION:
+		return cs.securityConfig.InvitationTokenExpiresIn, nil
+	case REVOKE_SESSION:
+		return cs.securityConfig.RevokeSessionTokenExpiresIn, nil
+	default:
+		return 0, fmt.Errorf("unsupported confirmation type: %s", confirmationType)
+	}
+}
+
+func confirmationPolicy(appConfig *config.AppConfig) openapi.ConfirmationPolicy {
+	switch openapi.ConfirmationPolicy(strings.ToUpper(appConfig.ConfirmationPolicy)) {
+	case openapi.GRACE:
+		return openapi.GRACE
+	case openapi.NONE:
+		return openapi.NONE
+	default:
+		return openapi.STRICT
+	}
+}
+
+func confirmationGracePeriod(appConfig *config.AppConfig) time.Duration {
+	return time.Duration(appConfig.ConfirmationGraceDays) * 24 * time.Hour
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+)
+
+type InvitationService struct {
+	appConfig                   *config.AppConfig
+	securityConfig              *config.SecurityConfig
+	auditService                *AuditService
+	confirmationService         *ConfirmationService
+	mailService                 *MailService
+	confirmationTokenRepository repository.ConfirmationTokenRepository
+	invitationRepository        repository.InvitationRepository
+	userRepository              repository.UserRepository
+}
+
+func NewInvitationService(
+	appConfig *config.AppConfig,
+	securityConfig *config.SecurityConfig,
+	auditService *AuditService,
+	confirmationService *ConfirmationService,
+	mailService *MailService,
+	confirmationTokenRepository repository.ConfirmationTokenRepository,
+	invitationRepository repository.InvitationRepository,
+	userRepository repository.UserRepository,
+) *InvitationService {
+	return &InvitationService{
+		appConfig:                   appConfig,
+		securityConfig:              securityConfig,
+		auditService:                auditService,
+		confirmationService:         confirmationService,
+		mailService:                 mailService,
+		confirmationTokenRepository: confirmationTokenRepository,
+		invitationRepository:        invitationRepository,
+		userRepository:              userRepository,
+	}
+}
+
+func (is *InvitationService) GetInvitations(ctx context.Context, criteria *SearchInvitationCriteria, pageable *common.Pageable) (*common.Page[*openapi.InvitationDetail], error) {
+	page, err := is.invitationRepository.SearchInvitations(ctx, &repository.SearchInvitationsCriteria{
+		SearchField: criteria.SearchField,
+		Status:      criteria.Status,
+	}, pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.InvitationDetail, len(page.Content))
+	for i, invitation := range page.Content {
+		content[i] = is.mapInvitationDetail(invitation)
+	}
+
+	return &common.Page[*openapi.InvitationDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (is *InvitationService) Invite(ctx context.Context, user *repository.User) (*openapi.InvitationDetail, error) {
+	invitation, err := is.invitationRepository.AddInvitation(ctx, &repository.InvitationData{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Expiration: is.securityConfig.InvitationTokenExpiresIn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := is.sendInvitationMail(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return is.mapInvitationDetail(invitation), nil
+}
+
+func (is *InvitationService) ResendInvitation(ctx context.Context, id pgtype.UUID) (*openapi.InvitationDetail, error) {
+	invitation, err := is.getInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, is.auditService, AUDIT_INVITATION_RESENT, AUDIT_TARGET_INVITATION, id.String(), is.mapInvitationDetail(invitation), func(ctx context.Context) (*openapi.InvitationDetail, error) {
+		return is.resendInvitation(ctx, id)
+	})
+}
+
+func (is *InvitationService) resendInvitation(ctx context.Context, id pgtype.UUID) (*openapi.InvitationDetail, error) {
+	invitation, err := is.getInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := invitation.Status()
+	if status != repository.InvitationStatusPending && status != repository.InvitationStatusExpired {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVITATION_NOT_PENDING), "invitation is not pending")
+	}
+
+	user, err := is.userRepository.GetUserById(ctx, invitation.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err = is.invitationRepository.SetInvitationExpiresAt(ctx, invitation.ID, time.Now().Add(is.securityConfig.InvitationTokenExpiresIn))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := is.sendInvitationMail(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return is.mapInvitationDetail(invitation), nil
+}
+
+func (is *InvitationService) RevokeInvitation(ctx context.Context, id pgtype.UUID) (*openapi.InvitationDetail, error) {
+	invitation, err := is.getInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, is.auditService, AUDIT_INVITATION_REVOKED, AUDIT_TARGET_INVITATION, id.String(), is.mapInvitationDetail(invitation), func(ctx context.Context) (*openapi.InvitationDetail, error) {
+		return is.revokeInvitation(ctx, id)
+	})
+}
+
+func (is *InvitationService) revokeInvitation(ctx context.Context, id pgtype.UUID) (*openapi.InvitationDetail, error) {
+	invitation, err := is.getInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := invitation.Status()
+	if status != repository.InvitationStatusPending && status != repository.InvitationStatusExpired {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVITATION_NOT_PENDING), "invitation is not pending")
+	}
+
+	if err := is.confirmationTokenRepository.InvalidateConfirmationTokens(ctx, invitation.UserID, INVITATION); err != nil {
+		return nil, err
+	}
+
+	invitation, err = is.invitationRepository.SetInvitationRevoked(ctx, invitation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return is.mapInvitationDetail(invitation), nil
+}
+
+func (is *InvitationService) getInvitation(ctx context.Context, id pgtype.UUID) (*repository.Invitation, error) {
+	invitation, err := is.invitationRepository.GetInvitationById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "Invitation not found")
+	}
+	return invitation, nil
+}
+
+func (is *InvitationService) mapInvitationDetail(invitation *repository.Invitation) *openapi.InvitationDetail {
+	result := &openapi.InvitationDetail{
+		Id:        invitation.ID.String(),
+		UserId:    invitation.UserID.String(),
+		Email:     invitation.Email,
+		Status:    openapi.InvitationStatus(invitation.Status()),
+		CreatedAt: invitation.CreatedAt,
+		ExpiresAt: invitation.ExpiresAt,
+	}
+	if invitation.AcceptedAt != nil {
+		result.AcceptedAt = *invitation.AcceptedAt
+	}
+	if invitation.RevokedAt != nil {
+		result.RevokedAt = *invitation.RevokedAt
+	}
+	return result
+}
+
+func (is *InvitationService) sendInvitationMail(ctx context.Context, user *repository.User) error {
+	token, err := is.confirmationService.GenerateToken(ctx, user.ID, INVITATION, map[string]string{})
+	if err != nil {
+		return err
+	}
+
+	return is.mailService.SendMail(ctx, user.Email, user.Locale, openapi.INVITATION, &invitationMailData{
+		InvitationUrl: is.mailService.TokenURL(is.appConfig.InvitationPath, token),
+	})
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/janobono/auth-service/internal/repository"
+)
+
+// parsePrivateKey reads a PEM encoded or JWK private key, the kid and alg of a JWK are returned as well.
+func parsePrivateKey(value string) (crypto.Signer, string, string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		privateKey, err := repository.ParsePrivateKey([]byte(value))
+		return privateKey, "", "", err
+	}
+
+	var jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		D   string `json:"d"`
+		P   string `json:"p"`
+		Q   string `json:"q"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal([]byte(value), &jwk); err != nil {
+		return nil, "", "", err
+	}
+
+	var privateKey crypto.Signer
+	var err error
+	switch jwk.Kty {
+	case "RSA":
+		privateKey, err = parseRsaJwk(jwk.N, jwk.E, jwk.D, jwk.P, jwk.Q)
+	case "EC":
+		privateKey, err = parseEcJwk(jwk.Crv, jwk.X, jwk.Y, jwk.D)
+	case "OKP":
+		privateKey, err = parseOkpJwk(jwk.Crv, jwk.X, jwk.D)
+	default:
+		err = fmt.Errorf("unsupported key type: %s", jwk.Kty)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return privateKey, jwk.Kid, jwk.Alg, nil
+}
+
+func parseRsaJwk(n, e, d, p, q string) (crypto.Signer, error) {
+	values, err := decodeJwkValues(n, e, d, p, q)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(values[1])
+	if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	privateKey := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).SetBytes(values[0]), E: int(exponent.Int64())},
+		D:         new(big.Int).SetBytes(values[2]),
+		Primes:    []*big.Int{new(big.Int).SetBytes(values[3]), new(big.Int).SetBytes(values[4])},
+	}
+	if err := privateKey.Validate(); err != nil {
+		return nil, err
+	}
+	privateKey.Precompute()
+	return privateKey, nil
+}
+
+func parseEcJwk(crv, x, y, d string) (crypto.Signer, error) {
+	values, err := decodeJwkValues(x, y, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var curve ecdh.Curve
+	switch crv {
+	case "P-256":
+		curve = ecdh.P256()
+	case "P-384":
+		curve = ecdh.P384()
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", crv)
+	}
+
+	ecdhKey, err := curve.NewPrivateKey(values[2])
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(ecdhKey.PublicKey().Bytes(), append(append([]byte{4}, values[0]...), values[1]...)) {
+		return nil, errors.New("public key does not match the private key")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(ecdhKey)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", privateKey)
+	}
+	return signer, nil
+}
+
+func parseOkpJwk(crv, x, d string) (crypto.Signer, error) {
+	if crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported curve: %s", crv)
+	}
+
+	values, err := decodeJwkValues(x, d)
+	if err != nil {
+		return nil, err
+	}
+	if len(values[1]) != ed25519.SeedSize {
+		return nil, errors.New("invalid private key size")
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(values[1])
+	if !bytes.Equal(privateKey.Public().(ed25519.PublicKey), values[0]) {
+		return nil, errors.New("public key does not match the private key")
+	}
+	return privateKey, nil
+}
+
+func decodeJwkValues(values ...string) ([][]byte, error) {
+	result := make([][]byte, len(values))
+	for i, value := range values {
+		if value == "" {
+			return nil, errors.New("missing key parameter")
+		}
+
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = decoded
+	}
+	return result, nil
+}
+
+func defaultJwkAlg(privateKey crypto.Signer) string {
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return repository.JwkAlgRS256
+	case *ecdsa.PrivateKey:
+		if privateKey.Curve == elliptic.P384() {
+			return repository.JwkAlgES384
+		}
+		return repository.JwkAlgES256
+	case ed25519.PrivateKey:
+		return repository.JwkAlgEdDSA
+	default:
+		return ""
+	}
+}
+
+func jwkAlgMatches(alg string, privateKey crypto.Signer) bool {
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return alg == repository.JwkAlgRS256 || alg == repository.JwkAlgPS256
+	case *ecdsa.PrivateKey:
+		return (alg == repository.JwkAlgES256 && privateKey.Curve == elliptic.P256()) ||
+			(alg == repository.JwkAlgES384 && privateKey.Curve == elliptic.P384())
+	case ed25519.PrivateKey:
+		return alg == repository.JwkAlgEdDSA
+	default:
+		return false
+	}
+}
@@ -2,49 +2,386 @@ package service
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
 	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
 )
 
+var jwkUses = []openapi.JwkUse{openapi.ACCESS, openapi.REFRESH, openapi.CONFIRM, openapi.AUDIT}
+
 type JwkService struct {
-	jwkRepository repository.JwkRepository
+	securityConfig *config.SecurityConfig
+	auditService   *AuditService
+	jwtService     *JwtService
+	jwkRepository  repository.JwkRepository
+
+	mutex    sync.Mutex
+	snapshot *jwksSnapshot
+}
+
+// JwksSnapshot is the published key set, ExpiresAt is the next planned change of the set (a key published ahead of
+// its activation or removed after its tokens expired).
+type JwksSnapshot struct {
+	Jwks      *openapi.Jwks
+	ETag      string
+	ExpiresAt time.Time
 }
 
-func NewJwkService(jwkRepository repository.JwkRepository) *JwkService {
-	return &JwkService{jwkRepository}
+type jwksSnapshot struct {
+	*JwksSnapshot
+	version   uint64
+	refreshAt time.Time
 }
 
-func (js *JwkService) GetJwks(ctx context.Context) (*openapi.Jwks, error) {
-	activeJwks, err := js.jwkRepository.GetActiveJwks(ctx)
+func NewJwkService(
+	securityConfig *config.SecurityConfig,
+	auditService *AuditService,
+	jwtService *JwtService,
+	jwkRepository repository.JwkRepository,
+) *JwkService {
+	return &JwkService{
+		securityConfig: securityConfig,
+		auditService:   auditService,
+		jwtService:     jwtService,
+		jwkRepository:  jwkRepository,
+	}
+}
+
+// GetJwks returns the published key set from memory. It is reloaded when this replica changed keys, at the next
+// planned change and, to pick up changes of other replicas, after the jwk refresh interval.
+func (js *JwkService) GetJwks(ctx context.Context) (*JwksSnapshot, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	version := js.jwtService.JwksVersion()
+	now := time.Now().UTC()
+	if js.snapshot != nil && js.snapshot.version == version && now.Before(js.snapshot.refreshAt) {
+		return js.snapshot.JwksSnapshot, nil
+	}
+
+	snapshot, err := js.loadJwks(ctx, now)
 	if err != nil {
 		return nil, err
 	}
 
-	keys := make([]openapi.Jwk, 0, len(activeJwks))
+	refreshAt := now.Add(js.securityConfig.JwkRefreshInterval)
+	if snapshot.ExpiresAt.Before(refreshAt) {
+		refreshAt = snapshot.ExpiresAt
+	}
 
-	for i, jwk := range activeJwks {
+	js.snapshot = &jwksSnapshot{JwksSnapshot: snapshot, version: version, refreshAt: refreshAt}
+	return snapshot, nil
+}
 
-		n := base64.RawURLEncoding.EncodeToString(jwk.PublicKey.N.Bytes())
+func (js *JwkService) loadJwks(ctx context.Context, now time.Time) (*JwksSnapshot, error) {
+	storedJwks, err := js.jwkRepository.GetPublishedJwks(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		eBytes := big.NewInt(int64(jwk.PublicKey.E)).Bytes()
-		if len(eBytes) < 4 {
-			padding := make([]byte, 4-len(eBytes))
-			eBytes = append(padding, eBytes...)
+	// only access tokens are verified by third parties, the other uses stay internal
+	publishedJwks := make([]*repository.Jwk, 0, len(storedJwks))
+	for _, jwk := range storedJwks {
+		if jwk.Use == string(openapi.ACCESS) {
+			publishedJwks = append(publishedJwks, jwk)
 		}
-		e := base64.RawURLEncoding.EncodeToString(eBytes)
+	}
+
+	keys := make([]openapi.Jwk, 0, len(publishedJwks))
 
-		keys[i] = openapi.Jwk{
+	for _, jwk := range publishedJwks {
+		key := openapi.Jwk{
 			Kty: jwk.Kty,
-			Kid: jwk.ID.String(),
+			Kid: jwk.Kid,
 			Use: jwk.Use,
 			Alg: jwk.Alg,
-			N:   n,
-			E:   e,
 		}
+
+		if err := setPublicKey(&key, jwk.PublicKey); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
 	}
 
-	return &openapi.Jwks{Keys: keys}, nil
+	jwks := &openapi.Jwks{Keys: keys}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+
+	return &JwksSnapshot{
+		Jwks:      jwks,
+		ETag:      `"` + base64.RawURLEncoding.EncodeToString(hash[:16]) + `"`,
+		ExpiresAt: js.nextJwksChange(publishedJwks, now),
+	}, nil
+}
+
+// nextJwksChange returns when a key is removed from the set or the next key of a use is published, a key set without
+// planned changes is rechecked after the jwk refresh interval.
+func (js *JwkService) nextJwksChange(publishedJwks []*repository.Jwk, now time.Time) time.Time {
+	var next time.Time
+	consider := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	pendingUses := make(map[string]bool)
+	for _, jwk := range publishedJwks {
+		consider(jwk.PublishedUntil)
+		if jwk.ActivatesAt.After(now) {
+			pendingUses[jwk.Use] = true
+		}
+	}
+
+	if js.securityConfig.JwkGenerationEnabled {
+		for _, jwk := range publishedJwks {
+			if !pendingUses[jwk.Use] && !jwk.ActivatesAt.After(now) && now.Before(jwk.ExpiresAt) {
+				consider(jwk.ExpiresAt.Add(-js.securityConfig.JwkPublishLeadTime))
+			}
+		}
+	}
+
+	if next.IsZero() {
+		next = now.Add(js.securityConfig.JwkRefreshInterval)
+	}
+	return next
+}
+
+// Matches reports whether an If-None-Match value names the current key set.
+func (s *JwksSnapshot) Matches(ifNoneMatch string) bool {
+	for _, etag := range strings.Split(ifNoneMatch, ",") {
+		etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+		if etag == "*" || etag == s.ETag {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxAge returns the seconds the key set can be cached, up to its next planned change.
+func (s *JwksSnapshot) MaxAge(now time.Time) int64 {
+	return max(int64(s.ExpiresAt.Sub(now)/time.Second), 0)
+}
+
+func (js *JwkService) GetJwkKeys(ctx context.Context) ([]*openapi.JwkDetail, error) {
+	jwks, err := js.jwkRepository.GetJwks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*openapi.JwkDetail, len(jwks))
+	for i, jwk := range jwks {
+		result[i] = js.mapJwkDetail(jwk)
+	}
+	return result, nil
+}
+
+func (js *JwkService) ImportJwk(ctx context.Context, data *openapi.JwkImportData) (*openapi.JwkDetail, error) {
+	if !slices.Contains(jwkUses, data.Use) {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'use' is invalid")
+	}
+
+	privateKey, kid, alg, err := parsePrivateKey(data.PrivateKey)
+	if err != nil {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'privateKey' is invalid: "+err.Error())
+	}
+	if !common.IsBlank(data.Kid) {
+		kid = data.Kid
+	}
+	if !common.IsBlank(data.Alg) {
+		alg = data.Alg
+	}
+	if common.IsBlank(alg) {
+		alg = defaultJwkAlg(privateKey)
+	}
+	if !jwkAlgMatches(alg, privateKey) {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'alg' is invalid for the private key")
+	}
+
+	activatesAt := data.ActivatesAt
+	if activatesAt.IsZero() {
+		activatesAt = time.Now().UTC()
+	}
+	if !data.ExpiresAt.After(activatesAt) || !data.ExpiresAt.After(time.Now()) {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'expiresAt' must be in the future and after 'activatesAt'")
+	}
+
+	if kid != "" {
+		_, err = js.jwkRepository.GetJwkByKid(ctx, kid)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			return nil, common.NewServiceError(http.StatusConflict, string(openapi.JWK_ALREADY_EXISTS), "jwk with this kid already exists")
+		}
+	}
+
+	result, err := audited(ctx, js.auditService, AUDIT_JWK_IMPORTED, AUDIT_TARGET_JWK, "", nil, func(ctx context.Context) (*openapi.JwkDetail, error) {
+		jwk, err := js.jwtService.ImportJwk(ctx, repository.JwkImportData{
+			Kid:         kid,
+			Use:         string(data.Use),
+			Alg:         alg,
+			PrivateKey:  privateKey,
+			ActivatesAt: activatesAt,
+			ExpiresAt:   data.ExpiresAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return js.mapJwkDetail(jwk), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	js.jwtService.ResetJwtTokens()
+	if err := js.jwtService.RefreshRevokedJwks(ctx); err != nil {
+		slog.Error("Failed to refresh revoked jwks", "error", err)
+	}
+	return result, nil
+}
+
+func (js *JwkService) RotateJwk(ctx context.Context, use openapi.JwkUse) (*openapi.JwkDetail, error) {
+	if !slices.Contains(jwkUses, use) {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'use' is invalid")
+	}
+
+	result, err := audited(ctx, js.auditService, AUDIT_JWK_ROTATED, AUDIT_TARGET_JWK, "", nil, func(ctx context.Context) (*openapi.JwkDetail, error) {
+		jwk, err := js.jwtService.RotateJwk(ctx, string(use))
+		if err != nil {
+			return nil, err
+		}
+		return js.mapJwkDetail(jwk), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	js.jwtService.ResetJwtTokens()
+	if err := js.jwtService.RefreshRevokedJwks(ctx); err != nil {
+		slog.Error("Failed to refresh revoked jwks", "error", err)
+	}
+	return result, nil
+}
+
+func (js *JwkService) RevokeJwk(ctx context.Context, id pgtype.UUID) (*openapi.JwkDetail, error) {
+	jwk, err := js.getJwk(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.RevokedAt != nil {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVALID_FIELD), "jwk already revoked")
+	}
+
+	result, err := audited(ctx, js.auditService, AUDIT_JWK_REVOKED, AUDIT_TARGET_JWK, id.String(), js.mapJwkDetail(jwk), func(ctx context.Context) (*openapi.JwkDetail, error) {
+		jwk, err := js.jwkRepository.SetJwkRevoked(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return js.mapJwkDetail(jwk), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	js.jwtService.ResetJwtTokens()
+	if err := js.jwtService.RefreshRevokedJwks(ctx); err != nil {
+		slog.Error("Failed to refresh revoked jwks", "error", err)
+	}
+	return result, nil
+}
+
+func (js *JwkService) getJwk(ctx context.Context, id pgtype.UUID) (*repository.Jwk, error) {
+	jwk, err := js.jwkRepository.GetJwk(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "Jwk not found")
+	}
+	return jwk, nil
+}
+
+func (js *JwkService) mapJwkDetail(jwk *repository.Jwk) *openapi.JwkDetail {
+	result := &openapi.JwkDetail{
+		Id:             jwk.ID.String(),
+		Kid:            jwk.Kid,
+		Kty:            jwk.Kty,
+		Use:            openapi.JwkUse(jwk.Use),
+		Alg:            jwk.Alg,
+		Status:         jwkStatus(jwk, time.Now().UTC()),
+		CreatedAt:      jwk.CreatedAt,
+		ActivatesAt:    jwk.ActivatesAt,
+		ExpiresAt:      jwk.ExpiresAt,
+		PublishedUntil: jwk.PublishedUntil,
+	}
+	if jwk.RevokedAt != nil {
+		result.RevokedAt = *jwk.RevokedAt
+	}
+	return result
+}
+
+func jwkStatus(jwk *repository.Jwk, now time.Time) openapi.JwkStatus {
+	switch {
+	case jwk.RevokedAt != nil:
+		return openapi.KEY_REVOKED
+	case now.Before(jwk.ActivatesAt):
+		return openapi.KEY_PENDING
+	case now.Before(jwk.ExpiresAt):
+		return openapi.KEY_ACTIVE
+	case now.Before(jwk.PublishedUntil):
+		return openapi.KEY_RETIRING
+	default:
+		return openapi.KEY_EXPIRED
+	}
+}
+
+func setPublicKey(key *openapi.Jwk, publicKey crypto.PublicKey) error {
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		eBytes := big.NewInt(int64(publicKey.E)).Bytes()
+		if len(eBytes) < 4 {
+			padding := make([]byte, 4-len(eBytes))
+			eBytes = append(padding, eBytes...)
+		}
+
+		key.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(eBytes)
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+
+		key.Crv = publicKey.Curve.Params().Name
+		key.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+		key.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return fmt.Errorf("unsupported public key type: %T", publicKey)
+	}
+	return nil
 }
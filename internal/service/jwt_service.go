@@ -2,159 +2,645 @@ package service
 
 import (
 	"context"
+	"crypto"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
 	"github.com/janobono/auth-service/internal/config"
 	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
 	db2 "github.com/janobono/go-util/db"
-	"github.com/janobono/go-util/security"
 )
 
+const (
+	CLAIM_AUTHORITIES      = "authorities"
+	CLAIM_EMAIL            = "email"
+	CLAIM_CONFIRMED        = "confirmed"
+	CLAIM_ATTRIBUTE_PREFIX = "attribute."
+	CLAIM_CREATED_AT       = "created_at"
+)
+
+// registeredClaims are set by the token itself and cannot be mapped
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "sid", CLAIM_CREATED_AT}
+
 type JwtService struct {
 	securityConfig *config.SecurityConfig
+	transactor     repository.Transactor
 	jwkRepository  repository.JwkRepository
 
 	mutex        sync.Mutex
-	accessToken  *security.JwtToken
-	refreshToken *security.JwtToken
-	confirmToken *security.JwtToken
+	accessToken  *cachedJwtToken
+	refreshToken *cachedJwtToken
+	confirmToken *cachedJwtToken
+	jwksVersion  atomic.Uint64
+	revokedKids  atomic.Pointer[[]string]
+}
+
+type cachedJwtToken struct {
+	token     *JwtToken
+	refreshAt time.Time
+}
+
+type jwkSettings struct {
+	alg             string
+	expiration      time.Duration
+	tokenExpiration time.Duration
 }
 
-func NewJwtService(securityConfig *config.SecurityConfig, jwkRepository repository.JwkRepository) *JwtService {
+func NewJwtService(
+	securityConfig *config.SecurityConfig,
+	transactor repository.Transactor,
+	jwkRepository repository.JwkRepository,
+) *JwtService {
 	return &JwtService{
 		securityConfig: securityConfig,
+		transactor:     transactor,
 		jwkRepository:  jwkRepository,
 	}
 }
 
-func (js *JwtService) GetAccessJwtToken(ctx context.Context) (*security.JwtToken, error) {
-	return js.getJwtToken(
-		ctx,
-		"access",
-		js.securityConfig.AccessTokenExpiresIn,
-		js.securityConfig.AccessTokenJwkExpiresIn,
-		&js.accessToken,
-	)
+func (js *JwtService) GetAccessJwtToken(ctx context.Context) (*JwtToken, error) {
+	return js.getJwtToken(ctx, string(openapi.ACCESS), &js.accessToken)
 }
 
-func (js *JwtService) GetRefreshJwtToken(ctx context.Context) (*security.JwtToken, error) {
-	return js.getJwtToken(
-		ctx,
-		"refresh",
-		js.securityConfig.RefreshTokenExpiresIn,
-		js.securityConfig.RefreshTokenJwkExpiresIn,
-		&js.refreshToken,
-	)
+func (js *JwtService) GetRefreshJwtToken(ctx context.Context) (*JwtToken, error) {
+	return js.getJwtToken(ctx, string(openapi.REFRESH), &js.refreshToken)
 }
 
-func (js *JwtService) GetConfirmJwtToken(ctx context.Context) (*security.JwtToken, error) {
-	return js.getJwtToken(
-		ctx,
-		"confirm",
-		js.securityConfig.ContentTokenExpiresIn,
-		js.securityConfig.ContentTokenJwkExpiresIn,
-		&js.confirmToken,
-	)
+func (js *JwtService) GetConfirmJwtToken(ctx context.Context) (*JwtToken, error) {
+	return js.getJwtToken(ctx, string(openapi.CONFIRM), &js.confirmToken)
 }
 
-func (js *JwtService) getJwtToken(
-	ctx context.Context,
-	use string,
-	tokenExpiration, jwkExpiration time.Duration,
-	cached **security.JwtToken,
-) (*security.JwtToken, error) {
+func (js *JwtService) getJwtToken(ctx context.Context, use string, cached **cachedJwtToken) (*JwtToken, error) {
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
 
-	now := time.Now().UTC()
-
-	if *cached != nil && now.Before((*cached).KeyExpiration()) {
-		return *cached, nil
+	if *cached != nil && time.Now().UTC().Before((*cached).refreshAt) {
+		return (*cached).token, nil
 	}
 
-	jwk, err := js.jwkRepository.GetActiveJwk(ctx, use)
-
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+	settings, err := js.jwkSettings(use)
+	if err != nil {
 		return nil, err
 	}
-	if (err == nil && now.After(jwk.ExpiresAt)) || errors.Is(err, pgx.ErrNoRows) {
-		jwk, err = js.jwkRepository.AddJwk(ctx, repository.JwkData{
-			Use:        use,
-			Expiration: jwkExpiration,
-		})
-	}
 
+	jwk, refreshAt, err := js.getJwk(ctx, use)
 	if err != nil {
 		return nil, err
 	}
 
-	token := security.NewJwtToken(
-		jwt.SigningMethodRS256,
+	token, err := NewJwtToken(
+		jwk.Alg,
 		jwk.PrivateKey,
 		jwk.PublicKey,
-		jwk.ID.String(),
+		jwk.Kid,
 		js.securityConfig.TokenIssuer,
-		tokenExpiration,
+		settings.tokenExpiration,
 		jwk.ExpiresAt,
 		js.GetPublicKey,
+		js.isRevoked,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	*cached = token
+	*cached = &cachedJwtToken{token: token, refreshAt: refreshAt}
 	return token, nil
 }
 
-func (js *JwtService) GetPublicKey(ctx context.Context, kid string) (interface{}, error) {
-	id, err := db2.ParseUUID(kid)
+// getJwk returns the key currently signing tokens of the use and the time it has to be looked up again. The next key is
+// added as pending once the active key is within the publish lead time of its expiration (or uses an outdated
+// algorithm), so resource servers see it in JWKS before the first token is signed with it. Keys are added under a
+// database lock, so replicas agree on a single key instead of replacing each other's keys. With key generation disabled
+// only imported keys are used.
+func (js *JwtService) getJwk(ctx context.Context, use string) (*repository.Jwk, time.Time, error) {
+	settings, err := js.jwkSettings(use)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	now := time.Now().UTC()
+
+	active, pending, err := js.getJwkState(ctx, use)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	switch {
+	case !js.securityConfig.JwkGenerationEnabled:
+		if err := js.checkImportedJwk(use, active, pending, now); err != nil {
+			return nil, time.Time{}, err
+		}
+	case active == nil || js.needsNextJwk(settings, active, pending, now):
+		active, pending, err = js.rotateJwk(ctx, use, settings, now)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	renewAt := active.ExpiresAt
+	if pending != nil {
+		renewAt = pending.ActivatesAt
+	} else if js.securityConfig.JwkGenerationEnabled {
+		renewAt = active.ExpiresAt.Add(-js.securityConfig.JwkPublishLeadTime)
+	}
+
+	// keys rotated or revoked by other replicas are picked up after the refresh interval at the latest
+	refreshAt := now.Add(js.securityConfig.JwkRefreshInterval)
+	if renewAt.Before(refreshAt) {
+		refreshAt = renewAt
+	}
+
+	return active, refreshAt, nil
+}
+
+func (js *JwtService) checkImportedJwk(use string, active, pending *repository.Jwk, now time.Time) error {
+	if active == nil {
+		slog.Error("No valid jwk to sign with and jwk generation is disabled", "use", use)
+		return fmt.Errorf("no valid %s jwk, jwk generation is disabled", use)
+	}
+
+	if pending == nil && !now.Before(active.ExpiresAt.Add(-js.securityConfig.JwkPublishLeadTime)) {
+		slog.Warn("Jwk expires soon and no next jwk is imported", "use", use, "kid", active.Kid, "expiresAt", active.ExpiresAt)
+	}
+	return nil
+}
+
+func (js *JwtService) rotateJwk(ctx context.Context, use string, settings *jwkSettings, now time.Time) (*repository.Jwk, *repository.Jwk, error) {
+	var active, pending *repository.Jwk
+
+	err := js.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := js.jwkRepository.LockJwks(ctx, use); err != nil {
+			return err
+		}
+
+		// another replica may have rotated the keys while this one was waiting for the lock
+		var err error
+		active, pending, err = js.getJwkState(ctx, use)
+		if err != nil {
+			return err
+		}
+
+		if active == nil {
+			// nothing can verify tokens of this use yet, there is no point in waiting for the lead time
+			if active, err = js.addJwk(ctx, use, settings, now); err != nil {
+				return err
+			}
+		}
+
+		if js.needsNextJwk(settings, active, pending, now) {
+			if pending, err = js.addJwk(ctx, use, settings, js.nextActivation(active, now)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	js.jwksVersion.Add(1)
+	return active, pending, nil
+}
+
+// RotateJwk replaces the signing key of the use right away, e.g. when it is compromised. Replicas still signing with
+// the previous key switch within the refresh interval.
+func (js *JwtService) RotateJwk(ctx context.Context, use string) (*repository.Jwk, error) {
+	if !js.securityConfig.JwkGenerationEnabled {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.JWK_GENERATION_DISABLED), "jwk generation is disabled")
+	}
+
+	settings, err := js.jwkSettings(use)
 	if err != nil {
 		return nil, err
 	}
 
-	jwk, err := js.jwkRepository.GetJwk(ctx, id)
+	var jwk *repository.Jwk
+	err = js.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := js.jwkRepository.LockJwks(ctx, use); err != nil {
+			return err
+		}
+
+		// the new key signs right away, the active one is retired and stays published until its tokens expire
+		var err error
+		jwk, err = js.addJwk(ctx, use, settings, time.Now().UTC())
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return jwk.PublicKey, nil
+	js.ResetJwtTokens()
+	return jwk, nil
 }
 
-func (js *JwtService) GenerateAuthToken(token *security.JwtToken, id pgtype.UUID, authorities []string) (string, error) {
-	claims := jwt.MapClaims{
-		"sub": id.String(),
-		"aud": authorities,
+// ImportJwk stores an externally generated key, it is published right away and signs within its validity window.
+func (js *JwtService) ImportJwk(ctx context.Context, data repository.JwkImportData) (*repository.Jwk, error) {
+	settings, err := js.jwkSettings(data.Use)
+	if err != nil {
+		return nil, err
+	}
+	data.TokenExpiration = settings.tokenExpiration
+
+	var jwk *repository.Jwk
+	err = js.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := js.jwkRepository.LockJwks(ctx, data.Use); err != nil {
+			return err
+		}
+
+		jwk, err = js.jwkRepository.ImportJwk(ctx, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	js.jwksVersion.Add(1)
+	return jwk, nil
+}
+
+// ResetJwtTokens drops the cached signing keys, the next token is signed with the key currently active in the database.
+func (js *JwtService) ResetJwtTokens() {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	js.accessToken = nil
+	js.refreshToken = nil
+	js.confirmToken = nil
+	js.jwksVersion.Add(1)
+}
+
+// RefreshRevokedJwks reloads the revoked keys, so a revocation on any replica rejects tokens signed with the key
+// here too. Cached signing keys are dropped when the revoked keys change.
+func (js *JwtService) RefreshRevokedJwks(ctx context.Context) error {
+	kids, err := js.jwkRepository.GetRevokedJwkKids(ctx)
+	if err != nil {
+		return err
+	}
+
+	previous := js.revokedKids.Swap(&kids)
+	if previous != nil && !slices.Equal(*previous, kids) {
+		js.ResetJwtTokens()
+	}
+	return nil
+}
+
+func (js *JwtService) isRevoked(kid string) bool {
+	kids := js.revokedKids.Load()
+	return kids != nil && slices.Contains(*kids, kid)
+}
+
+// JwksVersion changes whenever this replica adds, imports or revokes keys, so a cached key set can tell it is outdated.
+func (js *JwtService) JwksVersion() uint64 {
+	return js.jwksVersion.Load()
+}
+
+func (js *JwtService) getJwkState(ctx context.Context, use string) (*repository.Jwk, *repository.Jwk, error) {
+	active, err := js.getActiveJwk(ctx, use)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pending, err := js.jwkRepository.GetPendingJwk(ctx, use)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, err
+	}
+
+	return active, pending, nil
+}
+
+func (js *JwtService) needsNextJwk(settings *jwkSettings, active, pending *repository.Jwk, now time.Time) bool {
+	if pending != nil {
+		return pending.Alg != settings.alg
+	}
+	return active.Alg != settings.alg || !now.Before(active.ExpiresAt.Add(-js.securityConfig.JwkPublishLeadTime))
+}
+
+func (js *JwtService) getActiveJwk(ctx context.Context, use string) (*repository.Jwk, error) {
+	jwk, err := js.jwkRepository.GetActiveJwk(ctx, use)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return jwk, err
+}
+
+func (js *JwtService) addJwk(ctx context.Context, use string, settings *jwkSettings, activatesAt time.Time) (*repository.Jwk, error) {
+	return js.jwkRepository.AddJwk(ctx, repository.JwkData{
+		Use:             use,
+		Alg:             settings.alg,
+		RsaKeySize:      js.securityConfig.JwkRsaKeySize,
+		ActivatesAt:     activatesAt,
+		Expiration:      settings.expiration,
+		TokenExpiration: settings.tokenExpiration,
+	})
+}
+
+func (js *JwtService) nextActivation(active *repository.Jwk, now time.Time) time.Time {
+	if active == nil {
+		return now
+	}
+
+	activatesAt := now.Add(js.securityConfig.JwkPublishLeadTime)
+	if active.ExpiresAt.Before(activatesAt) {
+		return active.ExpiresAt
+	}
+	return activatesAt
+}
+
+func (js *JwtService) jwkSettings(use string) (*jwkSettings, error) {
+	switch openapi.JwkUse(use) {
+	case openapi.ACCESS:
+		return &jwkSettings{
+			alg:             js.securityConfig.AccessTokenJwkAlgorithm,
+			expiration:      js.securityConfig.AccessTokenJwkExpiresIn,
+			tokenExpiration: js.securityConfig.AccessTokenExpiresIn,
+		}, nil
+	case openapi.REFRESH:
+		return &jwkSettings{
+			alg:             js.securityConfig.RefreshTokenJwkAlgorithm,
+			expiration:      js.securityConfig.RefreshTokenJwkExpiresIn,
+			tokenExpiration: js.securityConfig.RefreshTokenExpiresIn,
+		}, nil
+	case openapi.CONFIRM:
+		return &jwkSettings{
+			alg:        js.securityConfig.ContentTokenJwkAlgorithm,
+			expiration: js.securityConfig.ContentTokenJwkExpiresIn,
+			tokenExpiration: max(
+				js.securityConfig.ConfirmUserTokenExpiresIn,
+				js.securityConfig.ResetPasswordTokenExpiresIn,
+				js.securityConfig.ChangeEmailTokenExpiresIn,
+				js.securityConfig.RevertEmailTokenExpiresIn,
+				js.securityConfig.InvitationTokenExpiresIn,
+				js.securityConfig.RevokeSessionTokenExpiresIn,
+			),
+		}, nil
+	case openapi.AUDIT:
+		return &jwkSettings{
+			alg:        repository.JwkAlgRS256,
+			expiration: js.securityConfig.AuditJwkExpiresIn,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown jwk use: %s", use)
+	}
+}
+
+func (js *JwtService) GetPublicKey(ctx context.Context, kid string) (string, crypto.PublicKey, error) {
+	jwk, err := js.jwkRepository.GetJwkByKid(ctx, kid)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if jwk.RevokedAt != nil {
+		return "", nil, errors.New("key revoked")
+	}
+
+	return jwk.Alg, jwk.PublicKey, nil
+}
+
+func (js *JwtService) GenerateAuthToken(token *JwtToken, id pgtype.UUID, userClaims *UserClaims) (string, error) {
+	return js.GenerateSessionToken(token, id, "", userClaims)
+}
+
+func (js *JwtService) GenerateSessionToken(token *JwtToken, id pgtype.UUID, sessionID string, userClaims *UserClaims) (string, error) {
+	claims := js.mapUserClaims(userClaims)
+	claims["sub"] = id.String()
+	if sessionID != "" {
+		claims["sid"] = sessionID
 	}
 	return token.GenerateToken(claims)
 }
 
-func (js *JwtService) ParseAuthToken(ctx context.Context, jwtToken *security.JwtToken, token string) (pgtype.UUID, []string, error) {
+func (js *JwtService) ParseAuthToken(ctx context.Context, jwtToken *JwtToken, token string) (pgtype.UUID, []string, error) {
+	id, _, authorities, err := js.ParseSessionToken(ctx, jwtToken, token)
+	return id, authorities, err
+}
+
+func (js *JwtService) ParseSessionToken(ctx context.Context, jwtToken *JwtToken, token string) (pgtype.UUID, string, []string, error) {
+	claims, err := jwtToken.ParseToken(ctx, token)
+	if err != nil {
+		return pgtype.UUID{}, "", nil, err
+	}
+
+	id, err := subjectClaim(claims)
+	if err != nil {
+		return pgtype.UUID{}, "", nil, err
+	}
+
+	sessionID, _ := (claims)["sid"].(string)
+
+	return id, sessionID, js.authorities(claims), nil
+}
+
+func (js *JwtService) ParseUserClaims(ctx context.Context, jwtToken *JwtToken, token string) (pgtype.UUID, *UserClaims, error) {
 	claims, err := jwtToken.ParseToken(ctx, token)
 	if err != nil {
 		return pgtype.UUID{}, nil, err
 	}
 
+	id, err := subjectClaim(claims)
+	if err != nil {
+		return pgtype.UUID{}, nil, err
+	}
+
+	userClaims := &UserClaims{
+		Attributes:  make(map[string]string),
+		Authorities: js.authorities(claims),
+	}
+	userClaims.Confirmed, _ = claims[js.confirmedClaim()].(bool)
+	// tokens issued before the confirmation state was always added have no creation time
+	if createdAt, ok := claims[CLAIM_CREATED_AT].(float64); ok {
+		userClaims.CreatedAt = time.Unix(int64(createdAt), 0).UTC()
+	}
+
+	for source, name := range js.securityConfig.TokenClaims {
+		if slices.Contains(registeredClaims, name) {
+			continue
+		}
+
+		switch {
+		case source == CLAIM_EMAIL:
+			userClaims.Email, _ = claims[name].(string)
+		case strings.HasPrefix(source, CLAIM_ATTRIBUTE_PREFIX):
+			if value, ok := claims[name].(string); ok {
+				userClaims.Attributes[strings.TrimPrefix(source, CLAIM_ATTRIBUTE_PREFIX)] = value
+			}
+		}
+	}
+
+	return id, userClaims, nil
+}
+
+func (js *JwtService) authorities(claims jwt.MapClaims) []string {
+	authorities, ok := stringsClaim(claims, js.authoritiesClaim())
+	// tokens issued before the switch to the authorities claim carry them in aud only
+	if !ok && js.securityConfig.TokenAudienceLegacyMode {
+		authorities, _ = stringsClaim(claims, "aud")
+	}
+	return authorities
+}
+
+func (js *JwtService) mapUserClaims(userClaims *UserClaims) jwt.MapClaims {
+	// the confirmation state is always added, so the confirmation policy can be checked from the claims alone
+	claims := jwt.MapClaims{
+		js.authoritiesClaim(): userClaims.Authorities,
+		js.confirmedClaim():   userClaims.Confirmed,
+		CLAIM_CREATED_AT:      userClaims.CreatedAt.Unix(),
+	}
+
+	if js.securityConfig.TokenAudienceLegacyMode {
+		claims["aud"] = userClaims.Authorities
+	} else {
+		claims["aud"] = js.securityConfig.TokenAudience
+	}
+
+	for source, name := range js.securityConfig.TokenClaims {
+		if slices.Contains(registeredClaims, name) {
+			continue
+		}
+
+		switch {
+		case source == CLAIM_EMAIL:
+			claims[name] = userClaims.Email
+		case strings.HasPrefix(source, CLAIM_ATTRIBUTE_PREFIX):
+			if value, ok := userClaims.Attributes[strings.TrimPrefix(source, CLAIM_ATTRIBUTE_PREFIX)]; ok {
+				claims[name] = value
+			}
+		}
+	}
+
+	return claims
+}
+
+func (js *JwtService) authoritiesClaim() string {
+	if name, ok := js.securityConfig.TokenClaims[CLAIM_AUTHORITIES]; ok && !slices.Contains(registeredClaims, name) {
+		return name
+	}
+	return CLAIM_AUTHORITIES
+}
+
+func (js *JwtService) confirmedClaim() string {
+	if name, ok := js.securityConfig.TokenClaims[CLAIM_CONFIRMED]; ok && !slices.Contains(registeredClaims, name) {
+		return name
+	}
+	return CLAIM_CONFIRMED
+}
+
+func subjectClaim(claims jwt.MapClaims) (pgtype.UUID, error) {
 	idString, ok := (claims)["sub"].(string)
 
 	if !ok {
-		return pgtype.UUID{}, nil, errors.New("invalid access token")
+		return pgtype.UUID{}, errors.New("invalid access token")
 	}
 
-	id, err := db2.ParseUUID(idString)
+	return db2.ParseUUID(idString)
+}
+
+func stringsClaim(claims jwt.MapClaims, name string) ([]string, bool) {
+	values, ok := claims[name].([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result, true
+}
+
+func (js *JwtService) SignAuditCheckpoint(ctx context.Context, seq int64, hash string) (pgtype.UUID, string, error) {
+	js.mutex.Lock()
+	jwk, _, err := js.getJwk(ctx, string(openapi.AUDIT))
+	js.mutex.Unlock()
+
 	if err != nil {
-		return pgtype.UUID{}, nil, err
+		return pgtype.UUID{}, "", err
 	}
 
-	var authorities []string
-	if aud, ok := (claims)["aud"].([]interface{}); ok {
-		for _, a := range aud {
-			if aStr, ok := a.(string); ok {
-				authorities = append(authorities, aStr)
-			}
+	method := jwt.GetSigningMethod(jwk.Alg)
+	if method == nil {
+		return pgtype.UUID{}, "", fmt.Errorf("unsupported signing algorithm: %s", jwk.Alg)
+	}
+
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"iss":  js.securityConfig.TokenIssuer,
+		"iat":  time.Now().UTC().Unix(),
+		"seq":  seq,
+		"hash": hash,
+	})
+	token.Header["kid"] = jwk.Kid
+
+	signature, err := token.SignedString(jwk.PrivateKey)
+	if err != nil {
+		return pgtype.UUID{}, "", err
+	}
+
+	return jwk.ID, signature, nil
+}
+
+// CheckAuditJwk returns an error when no audit key can sign a checkpoint.
+func (js *JwtService) CheckAuditJwk(ctx context.Context) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	_, _, err := js.getJwk(ctx, string(openapi.AUDIT))
+	return err
+}
+
+func (js *JwtService) VerifyAuditCheckpoint(ctx context.Context, kid pgtype.UUID, signature string) (int64, string, error) {
+	token, err := jwt.Parse(signature, func(token *jwt.Token) (interface{}, error) {
+		jwk, err := js.jwkRepository.GetJwk(ctx, kid)
+		if err != nil {
+			return nil, err
 		}
+
+		// checkpoints signed before imported keys kept their own kid name the key by its id
+		if token.Header["kid"] != jwk.Kid && token.Header["kid"] != jwk.ID.String() {
+			return nil, errors.New("invalid kid")
+		}
+
+		if jwk.Use != string(openapi.AUDIT) {
+			return nil, errors.New("invalid key use")
+		}
+
+		if token.Method.Alg() != jwk.Alg {
+			return nil, fmt.Errorf("unexpected signing algorithm: %s", token.Method.Alg())
+		}
+
+		return jwk.PublicKey, nil
+	}, jwt.WithIssuer(js.securityConfig.TokenIssuer))
+	if err != nil {
+		return 0, "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, "", errors.New("invalid signature")
+	}
+
+	seq, ok := claims["seq"].(float64)
+	if !ok {
+		return 0, "", errors.New("invalid seq")
+	}
+
+	hash, ok := claims["hash"].(string)
+	if !ok {
+		return 0, "", errors.New("invalid hash")
 	}
 
-	return id, authorities, nil
+	return int64(seq), hash, nil
 }
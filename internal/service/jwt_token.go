@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type GetPublicKey func(ctx context.Context, kid string) (string, crypto.PublicKey, error)
+
+type IsRevoked func(kid string) bool
+
+type JwtToken struct {
+	method          jwt.SigningMethod
+	privateKey      crypto.Signer
+	publicKey       crypto.PublicKey
+	kid             string
+	issuer          string
+	tokenExpiration time.Duration
+	keyExpiration   time.Time
+	getPublicKey    GetPublicKey
+	isRevoked       IsRevoked
+}
+
+func NewJwtToken(
+	alg string,
+	privateKey crypto.Signer,
+	publicKey crypto.PublicKey,
+	kid string,
+	issuer string,
+	tokenExpiration time.Duration,
+	keyExpiration time.Time,
+	getPublicKey GetPublicKey,
+	isRevoked IsRevoked,
+) (*JwtToken, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+
+	return &JwtToken{
+		method:          method,
+		privateKey:      privateKey,
+		publicKey:       publicKey,
+		kid:             kid,
+		issuer:          issuer,
+		tokenExpiration: tokenExpiration,
+		keyExpiration:   keyExpiration,
+		getPublicKey:    getPublicKey,
+		isRevoked:       isRevoked,
+	}, nil
+}
+
+func (t *JwtToken) KeyID() string {
+	return t.kid
+}
+
+func (t *JwtToken) Algorithm() string {
+	return t.method.Alg()
+}
+
+func (t *JwtToken) TokenExpiration() time.Duration {
+	return t.tokenExpiration
+}
+
+func (t *JwtToken) KeyExpiration() time.Time {
+	return t.keyExpiration
+}
+
+func (t *JwtToken) GenerateToken(claims jwt.MapClaims) (string, error) {
+	now := time.Now().UTC()
+
+	jwtClaims := jwt.MapClaims{
+		"iss": t.issuer,
+		"iat": now.Unix(),
+		"exp": now.Add(t.tokenExpiration).Unix(),
+	}
+
+	for k, v := range claims {
+		jwtClaims[k] = v
+	}
+
+	token := jwt.NewWithClaims(t.method, jwtClaims)
+	token.Header["kid"] = t.kid
+	signedToken, err := token.SignedString(t.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+func (t *JwtToken) ParseToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		return t.getKeyFunc(ctx, token)
+	}
+
+	token, err := jwt.Parse(tokenString, keyFunc, jwt.WithIssuer(t.issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+func (t *JwtToken) getKeyFunc(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid kid in header")
+	}
+
+	alg, publicKey := t.method.Alg(), t.publicKey
+	if kid == t.kid {
+		// the signing key may have been revoked since it was cached, possibly by another replica
+		if t.isRevoked(kid) {
+			return nil, errors.New("key revoked")
+		}
+	} else {
+		var err error
+		alg, publicKey, err = t.getPublicKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the algorithm is bound to the key, a token must not pick a different one
+	if token.Method.Alg() != alg {
+		return nil, fmt.Errorf("unexpected signing algorithm: %s", token.Method.Alg())
+	}
+
+	return publicKey, nil
+}
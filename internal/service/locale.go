@@ -0,0 +1,81 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var localeRegexp = regexp.MustCompile(`^[a-zA-Z]{2,8}(-[a-zA-Z0-9]{1,8})*$`)
+
+func NormalizeLocale(locale string) (string, bool) {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if locale == "" {
+		return "", true
+	}
+	if !localeRegexp.MatchString(locale) {
+		return "", false
+	}
+
+	parts := strings.Split(locale, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		} else {
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+func PreferredLocale(acceptLanguage string) string {
+	type weightedLocale struct {
+		locale string
+		q      float64
+	}
+
+	var locales []weightedLocale
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		locale, ok := NormalizeLocale(fields[0])
+		if !ok || locale == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			locales = append(locales, weightedLocale{locale, q})
+		}
+	}
+
+	if len(locales) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(locales, func(i, j int) bool {
+		return locales[i].q > locales[j].q
+	})
+	return locales[0].locale
+}
+
+func localeCandidates(locale string) []string {
+	var candidates []string
+	for locale != "" {
+		candidates = append(candidates, locale)
+		index := strings.LastIndex(locale, "-")
+		if index < 0 {
+			break
+		}
+		locale = locale[:index]
+	}
+	return append(candidates, "")
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+)
+
+const LOGIN_METHOD_PASSWORD = "PASSWORD"
+
+type LoginHistoryService struct {
+	appConfig            *config.AppConfig
+	auditService         *AuditService
+	confirmationService  *ConfirmationService
+	mailService          *MailService
+	loginEventRepository repository.LoginEventRepository
+}
+
+func NewLoginHistoryService(
+	appConfig *config.AppConfig,
+	auditService *AuditService,
+	confirmationService *ConfirmationService,
+	mailService *MailService,
+	loginEventRepository repository.LoginEventRepository,
+) *LoginHistoryService {
+	return &LoginHistoryService{
+		appConfig:            appConfig,
+		auditService:         auditService,
+		confirmationService:  confirmationService,
+		mailService:          mailService,
+		loginEventRepository: loginEventRepository,
+	}
+}
+
+func (ls *LoginHistoryService) CheckSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	id, err := db2.ParseUUID(sessionID)
+	if err != nil {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid session")
+	}
+
+	loginEvent, err := ls.loginEventRepository.GetLoginEventById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) || loginEvent.RevokedAt != nil {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "session revoked")
+	}
+
+	return nil
+}
+
+func (ls *LoginHistoryService) GetLoginHistory(ctx context.Context, userID pgtype.UUID, pageable *common.Pageable) (*common.Page[*openapi.LoginEventDetail], error) {
+	page, err := ls.loginEventRepository.SearchLoginEvents(ctx, &repository.SearchLoginEventsCriteria{
+		UserID: userID,
+	}, pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.LoginEventDetail, len(page.Content))
+	for i, loginEvent := range page.Content {
+		content[i] = ls.mapLoginEventDetail(loginEvent)
+	}
+
+	return &common.Page[*openapi.LoginEventDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (ls *LoginHistoryService) RecordSignIn(ctx context.Context, user *repository.User, method string, success bool) (*repository.LoginEvent, error) {
+	requestInfo := GetRequestInfo(ctx)
+	fingerprint := ls.deviceFingerprint(requestInfo)
+
+	newDevice := false
+	if success {
+		count, err := ls.loginEventRepository.CountSuccessfulLoginEventsByFingerprint(ctx, user.ID, fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		newDevice = count == 0
+	}
+
+	loginEvent, err := ls.loginEventRepository.AddLoginEvent(ctx, &repository.LoginEventData{
+		UserID:      user.ID,
+		IP:          requestInfo.IP,
+		UserAgent:   requestInfo.UserAgent,
+		Method:      method,
+		Success:     success,
+		Fingerprint: fingerprint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if newDevice {
+		if err := ls.sendNewSignInMail(ctx, user, loginEvent); err != nil {
+			return nil, err
+		}
+	}
+
+	return loginEvent, nil
+}
+
+func (ls *LoginHistoryService) RevokeSession(ctx context.Context, data *openapi.RevokeSession) error {
+	_, err := inTx(ctx, ls.auditService, func(ctx context.Context) (interface{}, error) {
+		return nil, ls.revokeSession(ctx, data)
+	})
+	return err
+}
+
+func (ls *LoginHistoryService) revokeSession(ctx context.Context, data *openapi.RevokeSession) error {
+	confirmationData, err := ls.confirmationService.ParseToken(ctx, data.Token)
+	if err != nil {
+		return err
+	}
+
+	if confirmationData[CONFIRMATION_TYPE] != REVOKE_SESSION {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	sessionID, err := db2.ParseUUID(confirmationData[SESSION_ID])
+	if err != nil {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	loginEvent, err := ls.loginEventRepository.GetLoginEventById(ctx, sessionID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) || loginEvent.UserID.String() != confirmationData[ID] {
+		return common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TOKEN), "invalid token")
+	}
+
+	if err := ls.confirmationService.ConsumeToken(ctx, confirmationData); err != nil {
+		return err
+	}
+
+	if loginEvent.RevokedAt != nil {
+		return nil
+	}
+
+	if _, err := ls.loginEventRepository.SetLoginEventRevoked(ctx, loginEvent.ID); err != nil {
+		return err
+	}
+
+	return ls.auditService.Record(ctx, AUDIT_SESSION_REVOKED, AUDIT_TARGET_USER, loginEvent.UserID.String(), nil, map[string]string{
+		"sessionId": loginEvent.ID.String(),
+	})
+}
+
+func (ls *LoginHistoryService) deviceFingerprint(requestInfo *RequestInfo) string {
+	hash := sha256.Sum256([]byte(requestInfo.UserAgent))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func (ls *LoginHistoryService) mapLoginEventDetail(loginEvent *repository.LoginEvent) *openapi.LoginEventDetail {
+	result := &openapi.LoginEventDetail{
+		Id:        loginEvent.ID.String(),
+		UserId:    loginEvent.UserID.String(),
+		CreatedAt: loginEvent.CreatedAt,
+		Ip:        loginEvent.IP,
+		UserAgent: loginEvent.UserAgent,
+		Method:    loginEvent.Method,
+		Success:   loginEvent.Success,
+	}
+	if loginEvent.RevokedAt != nil {
+		result.RevokedAt = *loginEvent.RevokedAt
+	}
+	return result
+}
+
+func (ls *LoginHistoryService) sendNewSignInMail(ctx context.Context, user *repository.User, loginEvent *repository.LoginEvent) error {
+	token, err := ls.confirmationService.GenerateToken(ctx, user.ID, REVOKE_SESSION, map[string]string{
+		SESSION_ID: loginEvent.ID.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ls.mailService.SendMail(ctx, user.Email, user.Locale, openapi.NEW_SIGN_IN, &newSignInMailData{
+		Time:      loginEvent.CreatedAt.UTC().Format(time.RFC1123),
+		IP:        loginEvent.IP,
+		UserAgent: loginEvent.UserAgent,
+		RevokeUrl: ls.mailService.TokenURL(ls.appConfig.RevokeSessionPath, token),
+	})
+}
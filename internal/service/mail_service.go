@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/auth-service/internal/service/client"
+	"github.com/janobono/go-util/common"
+	"github.com/sony/gobreaker"
+)
+
+const (
+	mailQueueLease = 10 * time.Minute
+	mailPurgedData = "{}"
+)
+
+// Template data of the mails, templates are validated against the same types.
+type (
+	confirmationMailData struct {
+		ConfirmationUrl string
+	}
+
+	resetPasswordMailData struct {
+		NewPassword     string
+		ConfirmationUrl string
+	}
+
+	changeEmailNoticeMailData struct {
+		NewEmail  string
+		RevertUrl string
+	}
+
+	invitationMailData struct {
+		InvitationUrl string
+	}
+
+	newSignInMailData struct {
+		Time      string
+		IP        string
+		UserAgent string
+		RevokeUrl string
+	}
+)
+
+type MailService struct {
+	appConfig           *config.AppConfig
+	mailConfig          *config.MailConfig
+	auditService        *AuditService
+	mailClient          client.MailClient
+	mailTemplateService *MailTemplateService
+	confirmationService *ConfirmationService
+	mailRepository      repository.MailRepository
+}
+
+func NewMailService(
+	appConfig *config.AppConfig,
+	mailConfig *config.MailConfig,
+	auditService *AuditService,
+	mailClient client.MailClient,
+	mailTemplateService *MailTemplateService,
+	confirmationService *ConfirmationService,
+	mailRepository repository.MailRepository,
+) *MailService {
+	return &MailService{
+		appConfig:           appConfig,
+		mailConfig:          mailConfig,
+		auditService:        auditService,
+		mailClient:          mailClient,
+		mailTemplateService: mailTemplateService,
+		confirmationService: confirmationService,
+		mailRepository:      mailRepository,
+	}
+}
+
+func (ms *MailService) GetMails(ctx context.Context, status string, pageable *common.Pageable) (*common.Page[*openapi.MailDetail], error) {
+	page, err := ms.mailRepository.SearchMails(ctx, &repository.SearchMailsCriteria{
+		Status: status,
+	}, pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.MailDetail, len(page.Content))
+	for i, mail := range page.Content {
+		content[i] = ms.mapMailDetail(mail)
+	}
+
+	return &common.Page[*openapi.MailDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (ms *MailService) ProcessQueue(ctx context.Context) error {
+	mails, err := ms.mailRepository.ClaimMails(ctx, int32(ms.mailConfig.QueueBatchSize), mailQueueLease)
+	if err != nil {
+		return err
+	}
+
+	// Claimed mails are finished even when ctx is cancelled so that a shutdown does not leave them half sent.
+	sendCtx := context.WithoutCancel(ctx)
+
+	queue := make(chan *repository.Mail)
+	var wg sync.WaitGroup
+	for range max(ms.mailConfig.QueueWorkers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for mail := range queue {
+				if err := ms.send(sendCtx, mail); err != nil {
+					slog.Error("Failed to send mail", "id", mail.ID.String(), "error", err)
+				}
+			}
+		}()
+	}
+
+	for _, mail := range mails {
+		queue <- mail
+	}
+	close(queue)
+	wg.Wait()
+
+	return nil
+}
+
+// PurgeMailData blanks the content of sent and failed mails older than the mail retention, their links carry tokens
+// and, in legacy reset mode, a new password.
+func (ms *MailService) PurgeMailData(ctx context.Context) error {
+	count, err := ms.mailRepository.PurgeMailData(ctx, time.Now().Add(-ms.mailConfig.Retention))
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		slog.Info("Mail data purged", "count", count)
+	}
+	return nil
+}
+
+func (ms *MailService) RequeueMail(ctx context.Context, id pgtype.UUID) (*openapi.MailDetail, error) {
+	mail, err := ms.getMail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, ms.auditService, AUDIT_MAIL_REQUEUED, AUDIT_TARGET_MAIL, id.String(), ms.mapMailDetail(mail), func(ctx context.Context) (*openapi.MailDetail, error) {
+		return ms.requeueMail(ctx, id)
+	})
+}
+
+func (ms *MailService) requeueMail(ctx context.Context, id pgtype.UUID) (*openapi.MailDetail, error) {
+	mail, err := ms.getMail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if mail.Status != repository.MailStatusFailed {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVALID_FIELD), "mail is not failed")
+	}
+
+	if string(mail.Data) == mailPurgedData {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVALID_FIELD), "mail content was purged")
+	}
+
+	mail, err = ms.mailRepository.SetMailResult(ctx, id, &repository.MailResultData{
+		Status:        repository.MailStatusQueued,
+		Attempts:      0,
+		NextAttemptAt: time.Now(),
+		LastError:     mail.LastError,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.mapMailDetail(mail), nil
+}
+
+func (ms *MailService) SendMail(ctx context.Context, recipient, locale string, code openapi.MailTemplateCode, data interface{}) error {
+	subject, body, textBody, err := ms.mailTemplateService.Render(ctx, code, locale, data)
+	if err != nil {
+		return err
+	}
+
+	mailData := client.NewMailData()
+
+	mailData.From = ms.mailConfig.User
+	mailData.Recipients = []string{recipient}
+	mailData.Subject = subject
+	mailData.Body = body
+	mailData.TextBody = textBody
+
+	payload, err := json.Marshal(mailData)
+	if err != nil {
+		return err
+	}
+
+	_, err = ms.mailRepository.AddMail(ctx, &repository.MailData{
+		Recipients: mailData.Recipients,
+		Subject:    mailData.Subject,
+		Data:       payload,
+	})
+	return err
+}
+
+func (ms *MailService) SendChangeEmailMails(ctx context.Context, user *repository.User, email string) error {
+	changeToken, err := ms.confirmationService.GenerateToken(ctx, user.ID, CHANGE_EMAIL, map[string]string{
+		EMAIL: email,
+	})
+	if err != nil {
+		return err
+	}
+
+	revertToken, err := ms.confirmationService.GenerateToken(ctx, user.ID, REVERT_EMAIL, map[string]string{
+		EMAIL: user.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ms.SendMail(ctx, email, user.Locale, openapi.CHANGE_EMAIL, &confirmationMailData{
+		ConfirmationUrl: ms.TokenURL(ms.appConfig.ConfirmationPath, changeToken),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ms.SendMail(ctx, user.Email, user.Locale, openapi.CHANGE_EMAIL_NOTICE, &changeEmailNoticeMailData{
+		NewEmail:  email,
+		RevertUrl: ms.TokenURL(ms.appConfig.ConfirmationPath, revertToken),
+	})
+}
+
+func (ms *MailService) TokenURL(path, token string) string {
+	encodedToken := url.QueryEscape(token)
+	return ms.appConfig.ConfirmationWebUrl + path + encodedToken
+}
+
+func (ms *MailService) backoff(attempts int32) time.Duration {
+	backoff := ms.mailConfig.RetryBackoff
+	for i := int32(1); i < attempts && backoff < ms.mailConfig.RetryBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, ms.mailConfig.RetryBackoffMax)
+}
+
+func (ms *MailService) send(ctx context.Context, mail *repository.Mail) error {
+	var mailData client.MailData
+	sendErr := json.Unmarshal(mail.Data, &mailData)
+	if sendErr == nil {
+		sendErr = ms.mailClient.SendEmail(&mailData)
+	}
+
+	result := &repository.MailResultData{
+		Status:        repository.MailStatusSent,
+		Attempts:      mail.Attempts + 1,
+		NextAttemptAt: mail.NextAttemptAt,
+	}
+	if sendErr != nil {
+		result.LastError = sendErr.Error()
+		switch {
+		case errors.Is(sendErr, gobreaker.ErrOpenState) || errors.Is(sendErr, gobreaker.ErrTooManyRequests):
+			result.Status = repository.MailStatusQueued
+			result.Attempts = mail.Attempts
+			result.NextAttemptAt = time.Now().Add(ms.mailConfig.RetryBackoff)
+		case int(result.Attempts) >= ms.mailConfig.MaxAttempts:
+			result.Status = repository.MailStatusFailed
+		default:
+			result.Status = repository.MailStatusQueued
+			result.NextAttemptAt = time.Now().Add(ms.backoff(result.Attempts))
+		}
+	}
+
+	_, err := ms.mailRepository.SetMailResult(ctx, mail.ID, result)
+	return err
+}
+
+func (ms *MailService) getMail(ctx context.Context, id pgtype.UUID) (*repository.Mail, error) {
+	mail, err := ms.mailRepository.GetMailById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "mail not found")
+	}
+	return mail, nil
+}
+
+func (ms *MailService) mapMailDetail(mail *repository.Mail) *openapi.MailDetail {
+	result := &openapi.MailDetail{
+		Id:            mail.ID.String(),
+		CreatedAt:     mail.CreatedAt,
+		Recipients:    mail.Recipients,
+		Subject:       mail.Subject,
+		Status:        openapi.MailStatus(mail.Status),
+		Attempts:      mail.Attempts,
+		NextAttemptAt: mail.NextAttemptAt,
+		LastError:     mail.LastError,
+	}
+	if mail.SentAt != nil {
+		result.SentAt = *mail.SentAt
+	}
+	return result
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/janobono/auth-service/generated/openapi"
+)
+
+const (
+	mailLayoutName     = "layout"
+	mailBodyName       = "body"
+	mailSubjectName    = "subject"
+	mailPartialsDir    = "partials"
+	mailHtmlExt        = ".html"
+	mailTextExt        = ".txt"
+	mailTemplateScheme = "file://"
+)
+
+var (
+	htmlHeadRegexp  = regexp.MustCompile(`(?is)<head.*?</head>`)
+	htmlLinkRegexp  = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	htmlBreakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|li|table|h[1-6])>`)
+	htmlTagRegexp   = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLineRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+type mailLink struct {
+	Url   string
+	Label string
+}
+
+func link(url, label string) mailLink {
+	return mailLink{Url: url, Label: label}
+}
+
+type MailTemplateFile struct {
+	Code        openapi.MailTemplateCode
+	Subject     string
+	TemplateUrl string
+}
+
+type mailTemplateSource struct {
+	Subject  string
+	HtmlBody string
+	TextBody string
+}
+
+type mailLayout struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+type mailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+type MailTemplates struct {
+	layouts  map[openapi.MailTemplateCode]map[string]*mailLayout
+	defaults map[openapi.MailTemplateCode]map[string]*mailTemplateSource
+}
+
+func NewMailTemplates(files ...MailTemplateFile) (*MailTemplates, error) {
+	mailTemplates := &MailTemplates{
+		layouts:  make(map[openapi.MailTemplateCode]map[string]*mailLayout, len(files)),
+		defaults: make(map[openapi.MailTemplateCode]map[string]*mailTemplateSource, len(files)),
+	}
+
+	for _, file := range files {
+		path := strings.TrimPrefix(file.TemplateUrl, mailTemplateScheme)
+
+		layouts, err := parseMailLayouts(filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("mail template %s: %w", file.TemplateUrl, err)
+		}
+		mailTemplates.layouts[file.Code] = layouts
+
+		defaults, err := readMailTemplateSources(path, file.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("mail template %s: %w", file.TemplateUrl, err)
+		}
+		mailTemplates.defaults[file.Code] = defaults
+	}
+
+	return mailTemplates, nil
+}
+
+func (mt *MailTemplates) Supports(code openapi.MailTemplateCode) bool {
+	_, ok := mt.layouts[code]
+	return ok
+}
+
+func (mt *MailTemplates) parse(code openapi.MailTemplateCode, locale string, source *mailTemplateSource) (*mailTemplate, error) {
+	layouts, ok := mt.layouts[code]
+	if !ok {
+		return nil, fmt.Errorf("mail template %s not supported", code)
+	}
+
+	var layout *mailLayout
+	for _, candidate := range localeCandidates(locale) {
+		if layout, ok = layouts[candidate]; ok {
+			break
+		}
+	}
+
+	subjectTemplate, err := texttemplate.New(mailSubjectName).
+		Funcs(texttemplate.FuncMap{"link": link}).
+		Parse(source.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+
+	htmlTemplate, err := layout.html.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := htmlTemplate.Parse(source.HtmlBody); err != nil {
+		return nil, fmt.Errorf("html body: %w", err)
+	}
+
+	if strings.TrimSpace(source.TextBody) == "" {
+		return &mailTemplate{subject: subjectTemplate, html: htmlTemplate}, nil
+	}
+
+	textTemplate, err := layout.text.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := textTemplate.Parse(source.TextBody); err != nil {
+		return nil, fmt.Errorf("text body: %w", err)
+	}
+
+	return &mailTemplate{subject: subjectTemplate, html: htmlTemplate, text: textTemplate}, nil
+}
+
+func (mt *mailTemplate) render(data interface{}) (string, string, string, error) {
+	var subjectBuffer bytes.Buffer
+	if err := mt.subject.Execute(&subjectBuffer, data); err != nil {
+		return "", "", "", fmt.Errorf("subject: %w", err)
+	}
+	subject := strings.TrimSpace(subjectBuffer.String())
+
+	htmlName := mailBodyName
+	if mt.html.Lookup(mailLayoutName) != nil {
+		htmlName = mailLayoutName
+	}
+
+	var htmlBuffer bytes.Buffer
+	if err := mt.html.ExecuteTemplate(&htmlBuffer, htmlName, data); err != nil {
+		return "", "", "", fmt.Errorf("html body: %w", err)
+	}
+
+	if mt.text == nil {
+		return subject, htmlBuffer.String(), htmlToText(htmlBuffer.String()), nil
+	}
+
+	textName := mailBodyName
+	if mt.text.Lookup(mailLayoutName) != nil {
+		textName = mailLayoutName
+	}
+
+	var textBuffer bytes.Buffer
+	if err := mt.text.ExecuteTemplate(&textBuffer, textName, data); err != nil {
+		return "", "", "", fmt.Errorf("text body: %w", err)
+	}
+	return subject, htmlBuffer.String(), strings.TrimSpace(textBuffer.String()) + "\n", nil
+}
+
+func htmlToText(body string) string {
+	text := htmlHeadRegexp.ReplaceAllString(body, "")
+	text = htmlLinkRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		parts := htmlLinkRegexp.FindStringSubmatch(match)
+		label := strings.TrimSpace(htmlTagRegexp.ReplaceAllString(parts[2], ""))
+		if label == "" || label == parts[1] {
+			return parts[1]
+		}
+		return label + ": " + parts[1]
+	})
+	text = htmlBreakRegexp.ReplaceAllString(text, "\n")
+	text = htmlTagRegexp.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = blankLineRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
+
+func mailLocaleDirs(baseDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := map[string]string{"": baseDir}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == mailPartialsDir {
+			continue
+		}
+
+		locale, ok := NormalizeLocale(entry.Name())
+		if !ok || locale == "" {
+			continue
+		}
+		dirs[locale] = filepath.Join(baseDir, entry.Name())
+	}
+	return dirs, nil
+}
+
+func parseMailLayouts(baseDir string) (map[string]*mailLayout, error) {
+	dirs, err := mailLocaleDirs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := make(map[string]*mailLayout, len(dirs))
+	for locale, dir := range dirs {
+		layout, err := parseMailLayout(baseDir, dir)
+		if err != nil {
+			return nil, fmt.Errorf("locale %s: %w", locale, err)
+		}
+		layouts[locale] = layout
+	}
+	return layouts, nil
+}
+
+func parseMailLayout(baseDir, dir string) (*mailLayout, error) {
+	htmlTemplate := htmltemplate.New(mailBodyName).Funcs(htmltemplate.FuncMap{"link": link})
+	htmlFiles, err := mailLayoutFiles(baseDir, dir, mailHtmlExt)
+	if err != nil {
+		return nil, err
+	}
+	if len(htmlFiles) > 0 {
+		if htmlTemplate, err = htmlTemplate.ParseFiles(htmlFiles...); err != nil {
+			return nil, err
+		}
+	}
+
+	textTemplate := texttemplate.New(mailBodyName).Funcs(texttemplate.FuncMap{"link": link})
+	textFiles, err := mailLayoutFiles(baseDir, dir, mailTextExt)
+	if err != nil {
+		return nil, err
+	}
+	if len(textFiles) > 0 {
+		if textTemplate, err = textTemplate.ParseFiles(textFiles...); err != nil {
+			return nil, err
+		}
+	}
+
+	return &mailLayout{html: htmlTemplate, text: textTemplate}, nil
+}
+
+func mailLayoutFiles(baseDir, dir, ext string) ([]string, error) {
+	dirs := []string{baseDir}
+	if dir != baseDir {
+		dirs = append(dirs, dir)
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		layout := filepath.Join(dir, mailLayoutName+ext)
+		if _, err := os.Stat(layout); err == nil {
+			files = append(files, layout)
+		}
+
+		partials, err := filepath.Glob(filepath.Join(dir, mailPartialsDir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, partials...)
+	}
+	return files, nil
+}
+
+func readMailTemplateSources(path, subject string) (map[string]*mailTemplateSource, error) {
+	dirs, err := mailLocaleDirs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]*mailTemplateSource, len(dirs))
+	for locale, dir := range dirs {
+		localePath := filepath.Join(dir, filepath.Base(path))
+		if _, err := os.Stat(localePath); locale != "" && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		source, err := readMailTemplateSource(localePath, subject)
+		if err != nil {
+			return nil, fmt.Errorf("locale %s: %w", locale, err)
+		}
+		sources[locale] = source
+	}
+	return sources, nil
+}
+
+func readMailTemplateSource(path, subject string) (*mailTemplateSource, error) {
+	htmlBody, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source := &mailTemplateSource{Subject: subject, HtmlBody: string(htmlBody)}
+
+	textPath := strings.TrimSuffix(path, filepath.Ext(path)) + mailTextExt
+	textBody, err := os.ReadFile(textPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	source.TextBody = string(textBody)
+
+	for _, body := range []string{source.TextBody, source.HtmlBody} {
+		definedSubject, err := mailSubjectSource(body)
+		if err != nil {
+			return nil, err
+		}
+		if definedSubject != "" {
+			source.Subject = definedSubject
+			break
+		}
+	}
+
+	return source, nil
+}
+
+func mailSubjectSource(body string) (string, error) {
+	parsed, err := texttemplate.New(mailBodyName).
+		Funcs(texttemplate.FuncMap{"link": link}).
+		Parse(body)
+	if err != nil {
+		return "", err
+	}
+
+	subjectTemplate := parsed.Lookup(mailSubjectName)
+	if subjectTemplate == nil || subjectTemplate.Tree == nil {
+		return "", nil
+	}
+	return strings.TrimSpace(subjectTemplate.Tree.Root.String()), nil
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+)
+
+const mailTemplateSampleToken = "sample-token"
+
+type MailTemplateService struct {
+	appConfig              *config.AppConfig
+	auditService           *AuditService
+	mailTemplates          *MailTemplates
+	mailTemplateRepository repository.MailTemplateRepository
+
+	mutex  sync.Mutex
+	parsed map[pgtype.UUID]*parsedMailTemplate
+}
+
+// parsedMailTemplate is a parsed template of one version, an update creates a new version and replaces it.
+type parsedMailTemplate struct {
+	version  int32
+	template *mailTemplate
+}
+
+func NewMailTemplateService(
+	appConfig *config.AppConfig,
+	auditService *AuditService,
+	mailTemplates *MailTemplates,
+	mailTemplateRepository repository.MailTemplateRepository,
+) *MailTemplateService {
+	return &MailTemplateService{
+		appConfig:              appConfig,
+		auditService:           auditService,
+		mailTemplates:          mailTemplates,
+		mailTemplateRepository: mailTemplateRepository,
+		parsed:                 make(map[pgtype.UUID]*parsedMailTemplate),
+	}
+}
+
+func (mts *MailTemplateService) AddMailTemplate(ctx context.Context, data *openapi.MailTemplateData) (*openapi.MailTemplateDetail, error) {
+	return audited(ctx, mts.auditService, AUDIT_MAIL_TEMPLATE_ADDED, AUDIT_TARGET_MAIL_TEMPLATE, "", nil, func(ctx context.Context) (*openapi.MailTemplateDetail, error) {
+		return mts.addMailTemplate(ctx, data)
+	})
+}
+
+func (mts *MailTemplateService) addMailTemplate(ctx context.Context, data *openapi.MailTemplateData) (*openapi.MailTemplateDetail, error) {
+	if !mts.mailTemplates.Supports(data.Code) {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'code' is invalid")
+	}
+
+	locale, ok := NormalizeLocale(data.Locale)
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'locale' is invalid")
+	}
+
+	count, err := mts.mailTemplateRepository.CountByCodeAndLocale(ctx, string(data.Code), locale)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.MAIL_TEMPLATE_ALREADY_EXISTS), "mail template already exists")
+	}
+
+	source := &mailTemplateSource{Subject: data.Subject, HtmlBody: data.HtmlBody, TextBody: data.TextBody}
+	if _, err := mts.preview(data.Code, locale, source); err != nil {
+		return nil, err
+	}
+
+	mailTemplate, err := mts.mailTemplateRepository.AddMailTemplate(ctx, &repository.MailTemplateData{
+		Code:     string(data.Code),
+		Locale:   locale,
+		Subject:  data.Subject,
+		HtmlBody: data.HtmlBody,
+		TextBody: data.TextBody,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mts.mapMailTemplateDetail(mailTemplate), nil
+}
+
+func (mts *MailTemplateService) DeleteMailTemplate(ctx context.Context, id pgtype.UUID) error {
+	mailTemplate, err := mts.getMailTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if mailTemplate.Locale == "" {
+		return common.NewServiceError(http.StatusConflict, string(openapi.INVALID_FIELD), "default mail template cannot be deleted")
+	}
+
+	_, err = audited(ctx, mts.auditService, AUDIT_MAIL_TEMPLATE_DELETED, AUDIT_TARGET_MAIL_TEMPLATE, id.String(), mts.mapMailTemplateDetail(mailTemplate), func(ctx context.Context) (interface{}, error) {
+		return nil, mts.mailTemplateRepository.DeleteMailTemplateById(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	mts.mutex.Lock()
+	delete(mts.parsed, id)
+	mts.mutex.Unlock()
+	return nil
+}
+
+func (mts *MailTemplateService) GetMailTemplate(ctx context.Context, id pgtype.UUID) (*openapi.MailTemplateDetail, error) {
+	mailTemplate, err := mts.getMailTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return mts.mapMailTemplateDetail(mailTemplate), nil
+}
+
+func (mts *MailTemplateService) GetMailTemplates(ctx context.Context, code, locale string, pageable *common.Pageable) (*common.Page[*openapi.MailTemplateDetail], error) {
+	page, err := mts.mailTemplateRepository.SearchMailTemplates(ctx, &repository.SearchMailTemplatesCriteria{
+		Code:   code,
+		Locale: locale,
+	}, pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.MailTemplateDetail, len(page.Content))
+	for i, mailTemplate := range page.Content {
+		content[i] = mts.mapMailTemplateDetail(mailTemplate)
+	}
+
+	return &common.Page[*openapi.MailTemplateDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (mts *MailTemplateService) GetMailTemplateVersions(ctx context.Context, id pgtype.UUID) ([]*openapi.MailTemplateVersionDetail, error) {
+	if _, err := mts.getMailTemplate(ctx, id); err != nil {
+		return nil, err
+	}
+
+	versions, err := mts.mailTemplateRepository.GetMailTemplateVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*openapi.MailTemplateVersionDetail, len(versions))
+	for i, version := range versions {
+		result[i] = &openapi.MailTemplateVersionDetail{
+			Version:   version.Version,
+			Subject:   version.Subject,
+			HtmlBody:  version.HtmlBody,
+			TextBody:  version.TextBody,
+			CreatedAt: version.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+func (mts *MailTemplateService) PreviewMailTemplate(ctx context.Context, id pgtype.UUID) (*openapi.MailTemplatePreview, error) {
+	mailTemplate, err := mts.getMailTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return mts.preview(openapi.MailTemplateCode(mailTemplate.Code), mailTemplate.Locale, &mailTemplateSource{
+		Subject:  mailTemplate.Subject,
+		HtmlBody: mailTemplate.HtmlBody,
+		TextBody: mailTemplate.TextBody,
+	})
+}
+
+func (mts *MailTemplateService) Render(ctx context.Context, code openapi.MailTemplateCode, locale string, data interface{}) (string, string, string, error) {
+	mailTemplates, err := mts.mailTemplateRepository.GetMailTemplatesByCode(ctx, string(code))
+	if err != nil {
+		return "", "", "", err
+	}
+
+	byLocale := make(map[string]*repository.MailTemplate, len(mailTemplates))
+	for _, mailTemplate := range mailTemplates {
+		byLocale[mailTemplate.Locale] = mailTemplate
+	}
+
+	for _, candidate := range localeCandidates(locale) {
+		mailTemplate, ok := byLocale[candidate]
+		if !ok {
+			continue
+		}
+
+		parsed, err := mts.parse(code, mailTemplate)
+		if err != nil {
+			return "", "", "", err
+		}
+		return parsed.render(data)
+	}
+
+	return "", "", "", fmt.Errorf("mail template %s not found", code)
+}
+
+// parse returns the cached template of the stored version, templates changed on another replica have a new version
+// and are parsed again.
+func (mts *MailTemplateService) parse(code openapi.MailTemplateCode, stored *repository.MailTemplate) (*mailTemplate, error) {
+	mts.mutex.Lock()
+	cached, ok := mts.parsed[stored.ID]
+	mts.mutex.Unlock()
+	if ok && cached.version == stored.Version {
+		return cached.template, nil
+	}
+
+	parsed, err := mts.mailTemplates.parse(code, stored.Locale, &mailTemplateSource{
+		Subject:  stored.Subject,
+		HtmlBody: stored.HtmlBody,
+		TextBody: stored.TextBody,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mts.mutex.Lock()
+	mts.parsed[stored.ID] = &parsedMailTemplate{version: stored.Version, template: parsed}
+	mts.mutex.Unlock()
+	return parsed, nil
+}
+
+func (mts *MailTemplateService) SeedMailTemplates(ctx context.Context) error {
+	for code, sources := range mts.mailTemplates.defaults {
+		for locale, source := range sources {
+			count, err := mts.mailTemplateRepository.CountByCodeAndLocale(ctx, string(code), locale)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			if _, err := mts.mailTemplateRepository.AddMailTemplate(ctx, &repository.MailTemplateData{
+				Code:     string(code),
+				Locale:   locale,
+				Subject:  source.Subject,
+				HtmlBody: source.HtmlBody,
+				TextBody: source.TextBody,
+			}); err != nil {
+				return err
+			}
+			slog.Info("Mail template seeded", "code", code, "locale", locale)
+		}
+	}
+	return nil
+}
+
+func (mts *MailTemplateService) SetMailTemplate(ctx context.Context, id pgtype.UUID, data *openapi.MailTemplateContent) (*openapi.MailTemplateDetail, error) {
+	before, err := mts.GetMailTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, mts.auditService, AUDIT_MAIL_TEMPLATE_UPDATED, AUDIT_TARGET_MAIL_TEMPLATE, id.String(), before, func(ctx context.Context) (*openapi.MailTemplateDetail, error) {
+		return mts.setMailTemplate(ctx, id, data)
+	})
+}
+
+func (mts *MailTemplateService) setMailTemplate(ctx context.Context, id pgtype.UUID, data *openapi.MailTemplateContent) (*openapi.MailTemplateDetail, error) {
+	mailTemplate, err := mts.getMailTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &mailTemplateSource{Subject: data.Subject, HtmlBody: data.HtmlBody, TextBody: data.TextBody}
+	if _, err := mts.preview(openapi.MailTemplateCode(mailTemplate.Code), mailTemplate.Locale, source); err != nil {
+		return nil, err
+	}
+
+	mailTemplate, err = mts.mailTemplateRepository.SetMailTemplate(ctx, id, &repository.MailTemplateContentData{
+		Subject:  data.Subject,
+		HtmlBody: data.HtmlBody,
+		TextBody: data.TextBody,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mts.mapMailTemplateDetail(mailTemplate), nil
+}
+
+func (mts *MailTemplateService) getMailTemplate(ctx context.Context, id pgtype.UUID) (*repository.MailTemplate, error) {
+	mailTemplate, err := mts.mailTemplateRepository.GetMailTemplateById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "mail template not found")
+	}
+	return mailTemplate, nil
+}
+
+func (mts *MailTemplateService) preview(code openapi.MailTemplateCode, locale string, source *mailTemplateSource) (*openapi.MailTemplatePreview, error) {
+	parsed, err := mts.mailTemplates.parse(code, locale, source)
+	if err != nil {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TEMPLATE), err.Error())
+	}
+
+	subject, htmlBody, textBody, err := parsed.render(mts.sampleData(code))
+	if err != nil {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_TEMPLATE), err.Error())
+	}
+
+	return &openapi.MailTemplatePreview{
+		Subject:  subject,
+		HtmlBody: htmlBody,
+		TextBody: textBody,
+	}, nil
+}
+
+// sampleData uses the data types of the real mails, so a template referencing an unknown field fails the preview.
+func (mts *MailTemplateService) sampleData(code openapi.MailTemplateCode) interface{} {
+	switch code {
+	case openapi.SIGN_UP, openapi.CHANGE_EMAIL:
+		return &confirmationMailData{
+			ConfirmationUrl: mts.sampleUrl(mts.appConfig.ConfirmationPath),
+		}
+	case openapi.RESET_PASSWORD:
+		resetPasswordMail := &resetPasswordMailData{
+			ConfirmationUrl: mts.sampleUrl(mts.appConfig.ResetPasswordPath),
+		}
+		if mts.appConfig.ResetPasswordLegacyMode {
+			resetPasswordMail.NewPassword = "sample-password"
+			resetPasswordMail.ConfirmationUrl = mts.sampleUrl(mts.appConfig.ConfirmationPath)
+		}
+		return resetPasswordMail
+	case openapi.CHANGE_EMAIL_NOTICE:
+		return &changeEmailNoticeMailData{
+			NewEmail:  "new.email@example.com",
+			RevertUrl: mts.sampleUrl(mts.appConfig.ConfirmationPath),
+		}
+	case openapi.INVITATION:
+		return &invitationMailData{
+			InvitationUrl: mts.sampleUrl(mts.appConfig.InvitationPath),
+		}
+	case openapi.NEW_SIGN_IN:
+		return &newSignInMailData{
+			Time:      time.Now().UTC().Format(time.RFC1123),
+			IP:        "192.0.2.1",
+			UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0",
+			RevokeUrl: mts.sampleUrl(mts.appConfig.RevokeSessionPath),
+		}
+	default:
+		return struct{}{}
+	}
+}
+
+func (mts *MailTemplateService) sampleUrl(path string) string {
+	return mts.appConfig.ConfirmationWebUrl + path + mailTemplateSampleToken
+}
+
+func (mts *MailTemplateService) mapMailTemplateDetail(mailTemplate *repository.MailTemplate) *openapi.MailTemplateDetail {
+	return &openapi.MailTemplateDetail{
+		Id:        mailTemplate.ID.String(),
+		Code:      openapi.MailTemplateCode(mailTemplate.Code),
+		Locale:    mailTemplate.Locale,
+		Version:   mailTemplate.Version,
+		Subject:   mailTemplate.Subject,
+		HtmlBody:  mailTemplate.HtmlBody,
+		TextBody:  mailTemplate.TextBody,
+		CreatedAt: mailTemplate.CreatedAt,
+		UpdatedAt: mailTemplate.UpdatedAt,
+	}
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/service/client"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMailTemplateFiles = map[string]string{
+	"layout.html": `{{define "layout"}}<html lang="{{block "lang" .}}en{{end}}"><head><title>{{template "title" .}}</title></head>` +
+		`<body><h1>{{template "title" .}}</h1>{{template "content" .}}<p>{{template "footer" .}}</p></body></html>{{end}}` +
+		`{{define "footer"}}Default footer{{end}}`,
+	"layout.txt":           `{{define "layout"}}{{template "title" .}}` + "\n\n" + `{{template "content" .}}` + "\n\n--\n" + `{{template "footer" .}}{{end}}{{define "footer"}}Default footer{{end}}`,
+	"partials/button.html": `{{define "button"}}<p><a href="{{.Url}}">{{.Label}}</a></p>{{end}}`,
+	"partials/button.txt":  `{{define "button"}}{{.Label}}: {{.Url}}{{end}}`,
+	"sign_up.html": `{{define "subject"}}Html subject{{end}}{{define "title"}}Sign Up{{end}}` +
+		`{{define "content"}}<p>Welcome &amp; thanks</p>{{template "button" link .ConfirmationUrl "Confirm"}}{{end}}`,
+	"sign_up.txt": `{{define "subject"}}Confirm {{.ConfirmationUrl}}{{end}}{{define "title"}}Sign Up{{end}}` +
+		`{{define "content"}}Welcome & thanks` + "\n\n" + `{{template "button" link .ConfirmationUrl "Confirm"}}{{end}}`,
+	"invitation.html": `{{define "title"}}Invitation{{end}}{{define "content"}}{{template "button" link .InvitationUrl "Accept"}}{{end}}`,
+	"sk/layout.html": `{{define "layout"}}<html lang="sk"><body>{{template "content" .}}<p>{{template "footer" .}}</p></body></html>{{end}}` +
+		`{{define "footer"}}Neodpovedajte{{end}}`,
+	"sk/sign_up.html": `{{define "subject"}}Potvrdenie{{end}}{{define "title"}}Registrácia{{end}}` +
+		`{{define "content"}}<p>Vitajte</p>{{template "button" link .ConfirmationUrl "Potvrdiť"}}{{end}}`,
+}
+
+func newTestMailTemplates(t *testing.T) *MailTemplates {
+	dir := t.TempDir()
+	for name, content := range testMailTemplateFiles {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write template: %v", err)
+		}
+	}
+
+	mailTemplates, err := NewMailTemplates(
+		MailTemplateFile{Code: openapi.SIGN_UP, Subject: "Sign Up", TemplateUrl: "file://" + filepath.Join(dir, "sign_up.html")},
+		MailTemplateFile{Code: openapi.INVITATION, Subject: "Invitation", TemplateUrl: "file://" + filepath.Join(dir, "invitation.html")},
+	)
+	if err != nil {
+		t.Fatalf("failed to create mail templates: %v", err)
+	}
+	return mailTemplates
+}
+
+func renderTestMailTemplate(t *testing.T, mailTemplates *MailTemplates, code openapi.MailTemplateCode, sourceLocale, locale string, data interface{}) (string, string, string) {
+	parsed, err := mailTemplates.parse(code, locale, mailTemplates.defaults[code][sourceLocale])
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	subject, htmlBody, textBody, err := parsed.render(data)
+	if err != nil {
+		t.Fatalf("failed to render template: %v", err)
+	}
+	return subject, htmlBody, textBody
+}
+
+func TestMailTemplates_Defaults(t *testing.T) {
+	mailTemplates := newTestMailTemplates(t)
+
+	tests := []struct {
+		name    string
+		code    openapi.MailTemplateCode
+		locale  string
+		subject string
+		text    bool
+	}{
+		{"subject defined in the text body wins", openapi.SIGN_UP, "", "Confirm {{.ConfirmationUrl}}", true},
+		{"subject defined in the html body", openapi.SIGN_UP, "sk", "Potvrdenie", false},
+		{"subject of the configuration", openapi.INVITATION, "", "Invitation", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, ok := mailTemplates.defaults[tt.code][tt.locale]
+			assert.True(t, ok)
+			assert.Equal(t, tt.subject, source.Subject)
+			assert.Equal(t, tt.text, source.TextBody != "")
+		})
+	}
+
+	// A locale without its own template file has no default of its own
+	_, ok := mailTemplates.defaults[openapi.INVITATION]["sk"]
+	assert.False(t, ok)
+}
+
+func TestMailSubjectSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		subject string
+		err     bool
+	}{
+		{"plain text", `{{define "subject"}} Welcome {{end}}`, "Welcome", false},
+		{"actions are kept", `{{define "subject"}}Hello {{.Name}} {{if .Vip}}VIP{{end}}{{end}}`, "Hello {{.Name}} {{if .Vip}}VIP{{end}}", false},
+		{"functions are kept", `{{define "subject"}}{{(link .Url "x").Label}}{{end}}`, `{{(link .Url "x").Label}}`, false},
+		{"no subject", `{{define "content"}}Body{{end}}`, "", false},
+		{"invalid body", `{{define "subject"}}{{.Name}{{end}}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, err := mailSubjectSource(tt.body)
+			assert.Equal(t, tt.err, err != nil)
+			assert.Equal(t, tt.subject, subject)
+		})
+	}
+}
+
+func TestMailTemplates_Render(t *testing.T) {
+	mailTemplates := newTestMailTemplates(t)
+	data := &confirmationMailData{ConfirmationUrl: "https://auth.org/confirm?token=a&b"}
+
+	subject, htmlBody, textBody := renderTestMailTemplate(t, mailTemplates, openapi.SIGN_UP, "", "", data)
+
+	// The subject extracted from the body renders with the mail data
+	assert.Equal(t, "Confirm https://auth.org/confirm?token=a&b", subject)
+
+	// The body fills the blocks of the layout, the partial and the default footer come from the base directory
+	assert.Contains(t, htmlBody, `<html lang="en">`)
+	assert.Contains(t, htmlBody, "<title>Sign Up</title>")
+	assert.Contains(t, htmlBody, `<a href="https://auth.org/confirm?token=a&amp;b">Confirm</a>`)
+	assert.Contains(t, htmlBody, "<p>Default footer</p>")
+
+	// The text body uses the text layout and partials
+	assert.Equal(t, "Sign Up\n\nWelcome & thanks\n\nConfirm: https://auth.org/confirm?token=a&b\n\n--\nDefault footer\n", textBody)
+
+	// Unknown fields of the mail data are rejected
+	parsed, err := mailTemplates.parse(openapi.SIGN_UP, "", &mailTemplateSource{
+		Subject:  "Subject",
+		HtmlBody: `{{define "title"}}{{.Foo}}{{end}}{{define "content"}}{{end}}`,
+	})
+	assert.NoError(t, err)
+	_, _, _, err = parsed.render(data)
+	assert.Error(t, err)
+}
+
+func TestMailTemplates_LocaleFallback(t *testing.T) {
+	mailTemplates := newTestMailTemplates(t)
+	data := &confirmationMailData{ConfirmationUrl: "https://auth.org/confirm?token=abc"}
+
+	tests := []struct {
+		name    string
+		locale  string
+		layout  string
+		footer  string
+		partial string
+	}{
+		{"exact locale", "sk", `<html lang="sk">`, "Neodpovedajte", `<a href="https://auth.org/confirm?token=abc">Potvrdiť</a>`},
+		{"region falls back to the language", "sk-SK", `<html lang="sk">`, "Neodpovedajte", `<a href="https://auth.org/confirm?token=abc">Potvrdiť</a>`},
+		{"unknown locale falls back to the default", "de", `<html lang="en">`, "Default footer", `<a href="https://auth.org/confirm?token=abc">Potvrdiť</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, htmlBody, _ := renderTestMailTemplate(t, mailTemplates, openapi.SIGN_UP, "sk", tt.locale, data)
+			assert.Equal(t, "Potvrdenie", subject)
+			assert.Contains(t, htmlBody, tt.layout)
+			assert.Contains(t, htmlBody, tt.footer)
+			assert.Contains(t, htmlBody, tt.partial)
+		})
+	}
+}
+
+func TestMailTemplates_TextFallback(t *testing.T) {
+	mailTemplates := newTestMailTemplates(t)
+	data := &confirmationMailData{ConfirmationUrl: "https://auth.org/confirm?token=abc"}
+
+	// Without a text body the text part is generated from the html
+	_, _, textBody := renderTestMailTemplate(t, mailTemplates, openapi.SIGN_UP, "sk", "sk", data)
+	assert.Equal(t, "Vitajte\nPotvrdiť: https://auth.org/confirm?token=abc\nNeodpovedajte\n", textBody)
+}
+
+func TestHtmlToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		text string
+	}{
+		{"head is dropped", "<html><head><title>Title</title><style>p {}</style></head><body><p>Body</p></body></html>", "Body\n"},
+		{"link with label", `<p><a href="https://auth.org" style="color: red">Open <b>it</b></a></p>`, "Open it: https://auth.org\n"},
+		{"link labelled with its url", `<a href="https://auth.org">https://auth.org</a>`, "https://auth.org\n"},
+		{"link without label", `<a href="https://auth.org"><img src="logo.png"></a>`, "https://auth.org\n"},
+		{"breaks and blocks", "<p>One</p><p>Two<br>Three<br/>Four</p><div>Five</div>", "One\nTwo\nThree\nFour\nFive\n"},
+		{"entities are unescaped", "<p>Tom &amp; Jerry &lt;3 &#39;quoted&#39;</p>", "Tom & Jerry <3 'quoted'\n"},
+		{"lines are trimmed", "<p>   padded   </p>", "padded\n"},
+		{"blank lines are collapsed", "<p>One</p>\n\n\n\n<p>Two</p>", "One\n\nTwo\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.text, htmlToText(tt.html))
+		})
+	}
+}
+
+func TestMailTemplates_Multipart(t *testing.T) {
+	mailTemplates := newTestMailTemplates(t)
+	data := &confirmationMailData{ConfirmationUrl: "https://auth.org/confirm?token=abc"}
+
+	tests := []struct {
+		name         string
+		sourceLocale string
+		text         string
+	}{
+		{"text body", "", "Sign Up\n\nWelcome & thanks\n\nConfirm: https://auth.org/confirm?token=abc\n\n--\nDefault footer\n"},
+		{"generated text body", "sk", "Vitajte\nPotvrdiť: https://auth.org/confirm?token=abc\nNeodpovedajte\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, htmlBody, textBody := renderTestMailTemplate(t, mailTemplates, openapi.SIGN_UP, tt.sourceLocale, tt.sourceLocale, data)
+
+			mailDir := t.TempDir()
+			mailClient, err := client.NewMailClient(&config.MailConfig{Transport: client.MAIL_TRANSPORT_FILE, FileDir: mailDir})
+			assert.NoError(t, err)
+
+			mailData := client.NewMailData()
+			mailData.From = "auth@auth.org"
+			mailData.Recipients = []string{"user@auth.org"}
+			mailData.Subject = subject
+			mailData.Body = htmlBody
+			mailData.TextBody = textBody
+			assert.NoError(t, mailClient.SendEmail(mailData))
+
+			message := readTestMail(t, mailDir)
+			decodedSubject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+			assert.NoError(t, err)
+			assert.Equal(t, subject, decodedSubject)
+
+			mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+			assert.NoError(t, err)
+			assert.Equal(t, "multipart/alternative", mediaType)
+
+			// The plain text part comes first, mail clients prefer the last alternative they support
+			reader := multipart.NewReader(message.Body, params["boundary"])
+			parts := map[string]string{}
+			var order []string
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+
+				content, err := io.ReadAll(part)
+				assert.NoError(t, err)
+
+				partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+				assert.NoError(t, err)
+				// Quoted-printable parts carry CRLF line breaks
+				parts[partType] = strings.ReplaceAll(string(content), "\r\n", "\n")
+				order = append(order, partType)
+			}
+
+			assert.Equal(t, []string{"text/plain", "text/html"}, order)
+			assert.Equal(t, tt.text, parts["text/plain"])
+			assert.Equal(t, htmlBody, parts["text/html"])
+		})
+	}
+}
+
+func readTestMail(t *testing.T, mailDir string) *mail.Message {
+	files, err := filepath.Glob(filepath.Join(mailDir, "new", "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one mail, got %d: %v", len(files), err)
+	}
+
+	file, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("failed to open mail: %v", err)
+	}
+	t.Cleanup(func() { _ = file.Close() })
+
+	message, err := mail.ReadMessage(file)
+	if err != nil {
+		t.Fatalf("failed to read mail: %v", err)
+	}
+	return message
+}
+
+func TestLocaleCandidates(t *testing.T) {
+	tests := []struct {
+		locale     string
+		candidates []string
+	}{
+		{"", []string{""}},
+		{"sk", []string{"sk", ""}},
+		{"sk-SK", []string{"sk-SK", "sk", ""}},
+		{"zh-hant-TW", []string{"zh-hant-TW", "zh-hant", "zh", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			assert.Equal(t, tt.candidates, localeCandidates(tt.locale))
+		})
+	}
+}
+
+func TestMailTemplates_Supports(t *testing.T) {
+	mailTemplates := newTestMailTemplates(t)
+
+	assert.True(t, mailTemplates.Supports(openapi.SIGN_UP))
+	assert.False(t, mailTemplates.Supports(openapi.RESET_PASSWORD))
+
+	_, err := mailTemplates.parse(openapi.RESET_PASSWORD, "", &mailTemplateSource{Subject: "Subject"})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "not supported"))
+}
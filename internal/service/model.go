@@ -1,15 +1,50 @@
 package service
 
+import (
+	"time"
+
+	"github.com/janobono/auth-service/generated/openapi"
+)
+
 type SearchAttributeCriteria struct {
 	SearchField string
 }
 
+type SearchAuditEventCriteria struct {
+	ActorID  string
+	TargetID string
+	Action   string
+	From     *time.Time
+	To       *time.Time
+}
+
 type SearchAuthorityCriteria struct {
 	SearchField string
 }
 
+type SearchInvitationCriteria struct {
+	SearchField string
+	Status      string
+}
+
 type SearchUserCriteria struct {
 	SearchField   string
 	Email         string
 	AttributeKeys []string
 }
+
+type UserChangeEvent struct {
+	Seq       int64
+	ID        string
+	Type      string
+	CreatedAt time.Time
+	User      *openapi.UserDetail
+}
+
+type UserClaims struct {
+	Email       string
+	Confirmed   bool
+	CreatedAt   time.Time
+	Attributes  map[string]string
+	Authorities []string
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/repository"
+)
+
+const (
+	userEventsBatchSize    = 100
+	userEventsPollInterval = time.Second
+)
+
+type OutboxService struct {
+	outboxEventRepository repository.OutboxEventRepository
+	userRepository        repository.UserRepository
+}
+
+func NewOutboxService(outboxEventRepository repository.OutboxEventRepository, userRepository repository.UserRepository) *OutboxService {
+	return &OutboxService{outboxEventRepository, userRepository}
+}
+
+func (ob *OutboxService) PublishUserEvent(ctx context.Context, eventType openapi.WebhookEventType, user *repository.User) error {
+	userDetail, err := mapUserDetail(ctx, ob.userRepository, user)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(userDetail)
+	if err != nil {
+		return err
+	}
+
+	_, err = ob.outboxEventRepository.AddOutboxEvent(ctx, &repository.OutboxEventData{
+		EventType:   string(eventType),
+		AggregateID: user.ID,
+		Payload:     payload,
+	})
+	return err
+}
+
+func (ob *OutboxService) GetLastUserEventSeq(ctx context.Context) (int64, error) {
+	return ob.outboxEventRepository.GetLastOutboxEventSeq(ctx)
+}
+
+func (ob *OutboxService) WatchUserEvents(ctx context.Context, fromSeq int64, fn func(*UserChangeEvent) error) error {
+	ticker := time.NewTicker(userEventsPollInterval)
+	defer ticker.Stop()
+
+	seq := fromSeq
+	for {
+		for {
+			outboxEvents, err := ob.outboxEventRepository.GetOutboxEventsAfterSeq(ctx, seq, userEventsBatchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			for _, outboxEvent := range outboxEvents {
+				userChangeEvent, err := ob.mapUserChangeEvent(outboxEvent)
+				if err != nil {
+					return err
+				}
+
+				if err := fn(userChangeEvent); err != nil {
+					return err
+				}
+				seq = outboxEvent.Seq
+			}
+
+			if len(outboxEvents) < userEventsBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ob *OutboxService) mapUserChangeEvent(outboxEvent *repository.OutboxEvent) (*UserChangeEvent, error) {
+	var userDetail openapi.UserDetail
+	if err := json.Unmarshal(outboxEvent.Payload, &userDetail); err != nil {
+		return nil, err
+	}
+
+	return &UserChangeEvent{
+		Seq:       outboxEvent.Seq,
+		ID:        outboxEvent.ID.String(),
+		Type:      outboxEvent.EventType,
+		CreatedAt: outboxEvent.CreatedAt,
+		User:      &userDetail,
+	}, nil
+}
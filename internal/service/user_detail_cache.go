@@ -0,0 +1,99 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/janobono/auth-service/generated/openapi"
+)
+
+// userDetailCache is a bounded LRU cache of user details, entries expire after ttl. Every removal bumps the
+// generation, so a detail loaded while the user changed is not stored.
+type userDetailCache struct {
+	size int
+	ttl  time.Duration
+
+	mutex      sync.Mutex
+	generation uint64
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type userDetailCacheEntry struct {
+	id         string
+	userDetail *openapi.UserDetail
+	expiresAt  time.Time
+}
+
+func newUserDetailCache(size int, ttl time.Duration) *userDetailCache {
+	return &userDetailCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *userDetailCache) get(id string) (*openapi.UserDetail, uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[id]
+	if !ok {
+		return nil, c.generation, false
+	}
+
+	entry := element.Value.(*userDetailCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, id)
+		return nil, c.generation, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.userDetail, c.generation, true
+}
+
+func (c *userDetailCache) put(id string, userDetail *openapi.UserDetail, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	entry := &userDetailCacheEntry{id: id, userDetail: userDetail, expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[id]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*userDetailCacheEntry).id)
+	}
+}
+
+func (c *userDetailCache) remove(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	if element, ok := c.entries[id]; ok {
+		c.order.Remove(element)
+		delete(c.entries, id)
+	}
+}
+
+func (c *userDetailCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*list.Element, c.size)
+	c.order.Init()
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+)
+
+const (
+	TOKEN_VALIDATION_DATABASE = "database"
+	TOKEN_VALIDATION_CLAIMS   = "claims"
+)
+
+type UserDetailService struct {
+	securityConfig      *config.SecurityConfig
+	jwtService          *JwtService
+	confirmationService *ConfirmationService
+	outboxService       *OutboxService
+	userRepository      repository.UserRepository
+	cache               *userDetailCache
+}
+
+func NewUserDetailService(
+	securityConfig *config.SecurityConfig,
+	jwtService *JwtService,
+	confirmationService *ConfirmationService,
+	outboxService *OutboxService,
+	userRepository repository.UserRepository,
+) *UserDetailService {
+	var cache *userDetailCache
+	if tokenValidationMode(securityConfig) == TOKEN_VALIDATION_DATABASE && securityConfig.UserCacheSize > 0 && securityConfig.UserCacheTtl > 0 {
+		cache = newUserDetailCache(securityConfig.UserCacheSize, securityConfig.UserCacheTtl)
+	}
+
+	return &UserDetailService{
+		securityConfig:      securityConfig,
+		jwtService:          jwtService,
+		confirmationService: confirmationService,
+		outboxService:       outboxService,
+		userRepository:      userRepository,
+		cache:               cache,
+	}
+}
+
+func (uds *UserDetailService) CacheEnabled() bool {
+	return uds.cache != nil
+}
+
+func (uds *UserDetailService) DecodeUserDetail(ctx context.Context, token string) (*openapi.UserDetail, error) {
+	jwtToken, err := uds.jwtService.GetAccessJwtToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var userDetail *openapi.UserDetail
+	if tokenValidationMode(uds.securityConfig) == TOKEN_VALIDATION_CLAIMS {
+		id, userClaims, err := uds.jwtService.ParseUserClaims(ctx, jwtToken, token)
+		if err != nil {
+			return nil, err
+		}
+
+		// older tokens lack the confirmation state, their user is loaded instead
+		if userClaims.CreatedAt.IsZero() {
+			userDetail, err = uds.getUserDetail(ctx, id)
+		} else {
+			userDetail = mapClaimsUserDetail(id, userClaims)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		id, _, err := uds.jwtService.ParseAuthToken(ctx, jwtToken, token)
+		if err != nil {
+			return nil, err
+		}
+
+		userDetail, err = uds.getUserDetail(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := uds.confirmationService.CheckConfirmed(userDetail.Confirmed, userDetail.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return userDetail, nil
+}
+
+// WatchUserChanges removes changed users from the cache until ctx is done. Changes made while nobody watched are
+// unknown, so the cache is cleared first.
+func (uds *UserDetailService) WatchUserChanges(ctx context.Context) error {
+	if uds.cache == nil {
+		return nil
+	}
+
+	seq, err := uds.outboxService.GetLastUserEventSeq(ctx)
+	if err != nil {
+		return err
+	}
+
+	uds.cache.clear()
+
+	return uds.outboxService.WatchUserEvents(ctx, seq, func(userChangeEvent *UserChangeEvent) error {
+		uds.cache.remove(userChangeEvent.User.Id)
+		return nil
+	})
+}
+
+func (uds *UserDetailService) getUserDetail(ctx context.Context, id pgtype.UUID) (*openapi.UserDetail, error) {
+	if uds.cache == nil {
+		return uds.loadUserDetail(ctx, id)
+	}
+
+	userDetail, generation, ok := uds.cache.get(id.String())
+	if ok {
+		return userDetail, nil
+	}
+
+	userDetail, err := uds.loadUserDetail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uds.cache.put(id.String(), userDetail, generation)
+	return userDetail, nil
+}
+
+func (uds *UserDetailService) loadUserDetail(ctx context.Context, id pgtype.UUID) (*openapi.UserDetail, error) {
+	user, err := uds.userRepository.GetUserById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "User not found")
+	}
+	return mapUserDetail(ctx, uds.userRepository, user)
+}
+
+func mapClaimsUserDetail(id pgtype.UUID, userClaims *UserClaims) *openapi.UserDetail {
+	attributes := make([]openapi.AttributeValueDetail, 0, len(userClaims.Attributes))
+	for _, key := range slices.Sorted(maps.Keys(userClaims.Attributes)) {
+		attributes = append(attributes, openapi.AttributeValueDetail{
+			Key:   key,
+			Value: userClaims.Attributes[key],
+		})
+	}
+
+	authorities := make([]openapi.AuthorityDetail, len(userClaims.Authorities))
+	for i, authority := range userClaims.Authorities {
+		authorities[i] = openapi.AuthorityDetail{Authority: authority}
+	}
+
+	// tokens are issued to enabled users only
+	return &openapi.UserDetail{
+		Id:          id.String(),
+		Email:       userClaims.Email,
+		Confirmed:   userClaims.Confirmed,
+		Enabled:     true,
+		CreatedAt:   userClaims.CreatedAt,
+		Attributes:  attributes,
+		Authorities: authorities,
+	}
+}
+
+func tokenValidationMode(securityConfig *config.SecurityConfig) string {
+	if strings.ToLower(securityConfig.TokenValidationMode) == TOKEN_VALIDATION_CLAIMS {
+		return TOKEN_VALIDATION_CLAIMS
+	}
+	return TOKEN_VALIDATION_DATABASE
+}
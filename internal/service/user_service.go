@@ -17,6 +17,10 @@ import (
 type UserService struct {
 	passwordEncoder     *security.PasswordEncoder
 	randomString        *security.RandomString
+	auditService        *AuditService
+	mailService         *MailService
+	invitationService   *InvitationService
+	outboxService       *OutboxService
 	attributeRepository repository.AttributeRepository
 	authorityRepository repository.AuthorityRepository
 	userRepository      repository.UserRepository
@@ -25,6 +29,10 @@ type UserService struct {
 func NewUserService(
 	passwordEncoder *security.PasswordEncoder,
 	randomString *security.RandomString,
+	auditService *AuditService,
+	mailService *MailService,
+	invitationService *InvitationService,
+	outboxService *OutboxService,
 	attributeRepository repository.AttributeRepository,
 	authorityRepository repository.AuthorityRepository,
 	userRepository repository.UserRepository,
@@ -32,6 +40,10 @@ func NewUserService(
 	return &UserService{
 		passwordEncoder,
 		randomString,
+		auditService,
+		mailService,
+		invitationService,
+		outboxService,
 		attributeRepository,
 		authorityRepository,
 		userRepository,
@@ -39,6 +51,12 @@ func NewUserService(
 }
 
 func (u *UserService) AddUser(ctx context.Context, data *openapi.UserData) (*openapi.UserDetail, error) {
+	return audited(ctx, u.auditService, AUDIT_USER_ADDED, AUDIT_TARGET_USER, "", nil, func(ctx context.Context) (*openapi.UserDetail, error) {
+		return u.addUser(ctx, data)
+	})
+}
+
+func (u *UserService) addUser(ctx context.Context, data *openapi.UserData) (*openapi.UserDetail, error) {
 	email := common.ToScDf(data.Email)
 
 	count, err := u.userRepository.CountByEmail(ctx, email)
@@ -60,25 +78,62 @@ func (u *UserService) AddUser(ctx context.Context, data *openapi.UserData) (*ope
 		return nil, err
 	}
 
+	locale, ok := NormalizeLocale(data.Locale)
+	if !ok {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'locale' is invalid")
+	}
+
 	user, err := u.userRepository.AddUser(ctx, &repository.UserData{
 		Email:     email,
 		Password:  password,
 		Confirmed: data.Confirmed,
 		Enabled:   data.Enabled,
+		Locale:    locale,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if data.Invite {
+		if _, err := u.invitationService.Invite(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.outboxService.PublishUserEvent(ctx, openapi.USER_CREATED, user); err != nil {
+		return nil, err
+	}
+
 	return u.mapUserDetail(ctx, user)
 }
 
 func (u *UserService) DeleteUser(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID) error {
+	before, err := u.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = audited(ctx, u.auditService, AUDIT_USER_DELETED, AUDIT_TARGET_USER, id.String(), before, func(ctx context.Context) (interface{}, error) {
+		return nil, u.deleteUser(ctx, userDetail, id)
+	})
+	return err
+}
+
+func (u *UserService) deleteUser(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID) error {
 	err := u.checkUser(ctx, userDetail, id)
 	if err != nil {
 		return err
 	}
 
+	user, err := u.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.outboxService.PublishUserEvent(ctx, openapi.USER_DELETED, user); err != nil {
+		return err
+	}
+
 	return u.userRepository.DeleteUserById(ctx, id)
 }
 
@@ -124,6 +179,17 @@ func (u *UserService) GetUsers(ctx context.Context, criteria *SearchUserCriteria
 }
 
 func (u *UserService) SetAttributes(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.UserAttributesData) (*openapi.UserDetail, error) {
+	before, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, u.auditService, AUDIT_USER_ATTRIBUTES_SET, AUDIT_TARGET_USER, id.String(), before, func(ctx context.Context) (*openapi.UserDetail, error) {
+		return u.setAttributes(ctx, userDetail, id, data)
+	})
+}
+
+func (u *UserService) setAttributes(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.UserAttributesData) (*openapi.UserDetail, error) {
 	err := u.checkUser(ctx, userDetail, id)
 	if err != nil {
 		return nil, err
@@ -167,10 +233,30 @@ func (u *UserService) SetAttributes(ctx context.Context, userDetail *openapi.Use
 		return nil, err
 	}
 
-	return u.GetUser(ctx, id)
+	user, err := u.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.outboxService.PublishUserEvent(ctx, openapi.USER_ATTRIBUTES_CHANGED, user); err != nil {
+		return nil, err
+	}
+
+	return u.mapUserDetail(ctx, user)
 }
 
 func (u *UserService) SetAuthorities(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.UserAuthoritiesData) (*openapi.UserDetail, error) {
+	before, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, u.auditService, AUDIT_USER_AUTHORITIES_SET, AUDIT_TARGET_USER, id.String(), before, func(ctx context.Context) (*openapi.UserDetail, error) {
+		return u.setAuthorities(ctx, userDetail, id, data)
+	})
+}
+
+func (u *UserService) setAuthorities(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.UserAuthoritiesData) (*openapi.UserDetail, error) {
 	err := u.checkUser(ctx, userDetail, id)
 	if err != nil {
 		return nil, err
@@ -194,24 +280,70 @@ func (u *UserService) SetAuthorities(ctx context.Context, userDetail *openapi.Us
 		return nil, err
 	}
 
-	return u.GetUser(ctx, id)
+	user, err := u.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.outboxService.PublishUserEvent(ctx, openapi.USER_AUTHORITIES_CHANGED, user); err != nil {
+		return nil, err
+	}
+
+	return u.mapUserDetail(ctx, user)
 }
 
 func (u *UserService) SetConfirmed(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.BooleanValue) (*openapi.UserDetail, error) {
+	before, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, u.auditService, AUDIT_USER_CONFIRMED_SET, AUDIT_TARGET_USER, id.String(), before, func(ctx context.Context) (*openapi.UserDetail, error) {
+		return u.setConfirmed(ctx, userDetail, id, data)
+	})
+}
+
+func (u *UserService) setConfirmed(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.BooleanValue) (*openapi.UserDetail, error) {
 	err := u.checkUser(ctx, userDetail, id)
 	if err != nil {
 		return nil, err
 	}
 
+	before, err := u.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := u.userRepository.SetUserConfirmed(ctx, id, data.Value)
 	if err != nil {
 		return nil, err
 	}
 
+	if user.Confirmed != before.Confirmed {
+		eventType := openapi.USER_UNCONFIRMED
+		if user.Confirmed {
+			eventType = openapi.USER_CONFIRMED
+		}
+		if err := u.outboxService.PublishUserEvent(ctx, eventType, user); err != nil {
+			return nil, err
+		}
+	}
+
 	return u.mapUserDetail(ctx, user)
 }
 
 func (u *UserService) SetEmail(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.UserEmailData) (*openapi.UserDetail, error) {
+	before, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, u.auditService, AUDIT_USER_EMAIL_SET, AUDIT_TARGET_USER, id.String(), before, func(ctx context.Context) (*openapi.UserDetail, error) {
+		return u.setEmail(ctx, userDetail, id, data)
+	})
+}
+
+func (u *UserService) setEmail(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.UserEmailData) (*openapi.UserDetail, error) {
 	err := u.checkUser(ctx, userDetail, id)
 	if err != nil {
 		return nil, err
@@ -228,25 +360,67 @@ func (u *UserService) SetEmail(ctx context.Context, userDetail *openapi.UserDeta
 		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVALID_FIELD), "'email' already exists")
 	}
 
-	user, err := u.userRepository.SetUserEmail(ctx, id, email)
+	user, err := u.userRepository.GetUserById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if email == user.Email {
+		user, err = u.userRepository.SetUserPendingEmail(ctx, id, "")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = u.userRepository.SetUserPendingEmail(ctx, id, email)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := u.mailService.SendChangeEmailMails(ctx, user, email); err != nil {
+			return nil, err
+		}
+	}
+
 	return u.mapUserDetail(ctx, user)
 }
 
 func (u *UserService) SetEnabled(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.BooleanValue) (*openapi.UserDetail, error) {
+	before, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, u.auditService, AUDIT_USER_ENABLED_SET, AUDIT_TARGET_USER, id.String(), before, func(ctx context.Context) (*openapi.UserDetail, error) {
+		return u.setEnabled(ctx, userDetail, id, data)
+	})
+}
+
+func (u *UserService) setEnabled(ctx context.Context, userDetail *openapi.UserDetail, id pgtype.UUID, data *openapi.BooleanValue) (*openapi.UserDetail, error) {
 	err := u.checkUser(ctx, userDetail, id)
 	if err != nil {
 		return nil, err
 	}
 
+	before, err := u.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := u.userRepository.SetUserEnabled(ctx, id, data.Value)
 	if err != nil {
 		return nil, err
 	}
 
+	if user.Enabled != before.Enabled {
+		eventType := openapi.USER_DISABLED
+		if user.Enabled {
+			eventType = openapi.USER_ENABLED
+		}
+		if err := u.outboxService.PublishUserEvent(ctx, eventType, user); err != nil {
+			return nil, err
+		}
+	}
+
 	return u.mapUserDetail(ctx, user)
 }
 
@@ -267,12 +441,16 @@ func (u *UserService) checkUser(ctx context.Context, userDetail *openapi.UserDet
 }
 
 func (u *UserService) mapUserDetail(ctx context.Context, user *repository.User) (*openapi.UserDetail, error) {
-	userAttributes, err := u.userRepository.GetUserAttributes(ctx, user.ID)
+	return mapUserDetail(ctx, u.userRepository, user)
+}
+
+func mapUserDetail(ctx context.Context, userRepository repository.UserRepository, user *repository.User) (*openapi.UserDetail, error) {
+	userAttributes, err := userRepository.GetUserAttributes(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	userAuthorities, err := u.userRepository.GetUserAuthorities(ctx, user.ID)
+	userAuthorities, err := userRepository.GetUserAuthorities(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -296,11 +474,14 @@ func (u *UserService) mapUserDetail(ctx context.Context, user *repository.User)
 	}
 
 	return &openapi.UserDetail{
-		Id:          user.ID.String(),
-		Email:       user.Email,
-		Confirmed:   user.Confirmed,
-		Enabled:     user.Enabled,
-		Attributes:  attributes,
-		Authorities: authorities,
+		Id:           user.ID.String(),
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail,
+		Locale:       user.Locale,
+		CreatedAt:    user.CreatedAt,
+		Confirmed:    user.Confirmed,
+		Enabled:      user.Enabled,
+		Attributes:   attributes,
+		Authorities:  authorities,
 	}, nil
 }
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/auth-service/internal/service/client"
+	"github.com/janobono/go-util/common"
+)
+
+const (
+	WEBHOOK_HEADER_ID        = "X-Webhook-Id"
+	WEBHOOK_HEADER_DELIVERY  = "X-Webhook-Delivery"
+	WEBHOOK_HEADER_EVENT     = "X-Webhook-Event"
+	WEBHOOK_HEADER_TIMESTAMP = "X-Webhook-Timestamp"
+	WEBHOOK_HEADER_SIGNATURE = "X-Webhook-Signature"
+)
+
+type webhookPayload struct {
+	ID        string          `json:"id"`
+	Seq       int64           `json:"seq"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type WebhookService struct {
+	webhookConfig             *config.WebhookConfig
+	auditService              *AuditService
+	webhookClient             client.WebhookClient
+	outboxEventRepository     repository.OutboxEventRepository
+	webhookDeliveryRepository repository.WebhookDeliveryRepository
+	webhookRepository         repository.WebhookRepository
+}
+
+func NewWebhookService(
+	webhookConfig *config.WebhookConfig,
+	auditService *AuditService,
+	webhookClient client.WebhookClient,
+	outboxEventRepository repository.OutboxEventRepository,
+	webhookDeliveryRepository repository.WebhookDeliveryRepository,
+	webhookRepository repository.WebhookRepository,
+) *WebhookService {
+	return &WebhookService{
+		webhookConfig:             webhookConfig,
+		auditService:              auditService,
+		webhookClient:             webhookClient,
+		outboxEventRepository:     outboxEventRepository,
+		webhookDeliveryRepository: webhookDeliveryRepository,
+		webhookRepository:         webhookRepository,
+	}
+}
+
+func (ws *WebhookService) AddWebhook(ctx context.Context, data *openapi.WebhookData) (*openapi.WebhookDetail, error) {
+	return audited(ctx, ws.auditService, AUDIT_WEBHOOK_ADDED, AUDIT_TARGET_WEBHOOK, "", nil, func(ctx context.Context) (*openapi.WebhookDetail, error) {
+		return ws.addWebhook(ctx, data)
+	})
+}
+
+func (ws *WebhookService) addWebhook(ctx context.Context, data *openapi.WebhookData) (*openapi.WebhookDetail, error) {
+	webhookData, err := ws.toWebhookData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := ws.webhookRepository.AddWebhook(ctx, webhookData)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.mapWebhookDetail(webhook), nil
+}
+
+func (ws *WebhookService) DeleteWebhook(ctx context.Context, id pgtype.UUID) error {
+	before, err := ws.GetWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = audited(ctx, ws.auditService, AUDIT_WEBHOOK_DELETED, AUDIT_TARGET_WEBHOOK, id.String(), before, func(ctx context.Context) (interface{}, error) {
+		return nil, ws.webhookRepository.DeleteWebhookById(ctx, id)
+	})
+	return err
+}
+
+func (ws *WebhookService) Dispatch(ctx context.Context) error {
+	if err := ws.scheduleDeliveries(ctx); err != nil {
+		return err
+	}
+
+	deliveries, err := ws.webhookDeliveryRepository.ClaimWebhookDeliveries(ctx, int32(ws.webhookConfig.BatchSize), ws.webhookConfig.Timeout+time.Minute)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *repository.WebhookDelivery) {
+			defer wg.Done()
+			if err := ws.deliver(ctx, delivery); err != nil {
+				slog.Error("Failed to deliver webhook", "id", delivery.ID.String(), "error", err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (ws *WebhookService) GetWebhook(ctx context.Context, id pgtype.UUID) (*openapi.WebhookDetail, error) {
+	webhook, err := ws.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ws.mapWebhookDetail(webhook), nil
+}
+
+func (ws *WebhookService) GetWebhookDeliveries(ctx context.Context, id pgtype.UUID, status string, pageable *common.Pageable) (*common.Page[*openapi.WebhookDeliveryDetail], error) {
+	if _, err := ws.getWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	page, err := ws.webhookDeliveryRepository.SearchWebhookDeliveries(ctx, &repository.SearchWebhookDeliveriesCriteria{
+		WebhookID: id,
+		Status:    status,
+	}, pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.WebhookDeliveryDetail, len(page.Content))
+	for i, delivery := range page.Content {
+		content[i] = ws.mapWebhookDeliveryDetail(delivery)
+	}
+
+	return &common.Page[*openapi.WebhookDeliveryDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (ws *WebhookService) GetWebhookDeliveryAttempts(ctx context.Context, id pgtype.UUID) ([]*openapi.WebhookDeliveryAttemptDetail, error) {
+	if _, err := ws.getWebhookDelivery(ctx, id); err != nil {
+		return nil, err
+	}
+
+	attempts, err := ws.webhookDeliveryRepository.GetWebhookDeliveryAttempts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*openapi.WebhookDeliveryAttemptDetail, len(attempts))
+	for i, attempt := range attempts {
+		result[i] = &openapi.WebhookDeliveryAttemptDetail{
+			Id:         attempt.ID.String(),
+			CreatedAt:  attempt.CreatedAt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.Duration.Milliseconds(),
+		}
+	}
+	return result, nil
+}
+
+func (ws *WebhookService) GetWebhooks(ctx context.Context, pageable *common.Pageable) (*common.Page[*openapi.WebhookDetail], error) {
+	page, err := ws.webhookRepository.GetWebhooks(ctx, pageable)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]*openapi.WebhookDetail, len(page.Content))
+	for i, webhook := range page.Content {
+		content[i] = ws.mapWebhookDetail(webhook)
+	}
+
+	return &common.Page[*openapi.WebhookDetail]{
+		Pageable:      pageable,
+		TotalElements: page.TotalElements,
+		TotalPages:    page.TotalPages,
+		First:         page.First,
+		Last:          page.Last,
+		Content:       content,
+		Empty:         page.Empty,
+	}, nil
+}
+
+func (ws *WebhookService) RetryWebhookDelivery(ctx context.Context, id pgtype.UUID) (*openapi.WebhookDeliveryDetail, error) {
+	delivery, err := ws.getWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, ws.auditService, AUDIT_WEBHOOK_DELIVERY_RETRIED, AUDIT_TARGET_WEBHOOK, delivery.WebhookID.String(), ws.mapWebhookDeliveryDetail(delivery), func(ctx context.Context) (*openapi.WebhookDeliveryDetail, error) {
+		return ws.retryWebhookDelivery(ctx, id)
+	})
+}
+
+func (ws *WebhookService) retryWebhookDelivery(ctx context.Context, id pgtype.UUID) (*openapi.WebhookDeliveryDetail, error) {
+	delivery, err := ws.getWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery.Status != repository.WebhookDeliveryStatusDeadLetter {
+		return nil, common.NewServiceError(http.StatusConflict, string(openapi.INVALID_FIELD), "delivery is not dead-lettered")
+	}
+
+	delivery, err = ws.webhookDeliveryRepository.SetWebhookDeliveryResult(ctx, id, &repository.WebhookDeliveryResultData{
+		Status:        repository.WebhookDeliveryStatusScheduled,
+		Attempts:      0,
+		NextAttemptAt: time.Now(),
+		LastError:     delivery.LastError,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.mapWebhookDeliveryDetail(delivery), nil
+}
+
+func (ws *WebhookService) SetWebhook(ctx context.Context, id pgtype.UUID, data *openapi.WebhookData) (*openapi.WebhookDetail, error) {
+	before, err := ws.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return audited(ctx, ws.auditService, AUDIT_WEBHOOK_UPDATED, AUDIT_TARGET_WEBHOOK, id.String(), before, func(ctx context.Context) (*openapi.WebhookDetail, error) {
+		return ws.setWebhook(ctx, id, data)
+	})
+}
+
+func (ws *WebhookService) setWebhook(ctx context.Context, id pgtype.UUID, data *openapi.WebhookData) (*openapi.WebhookDetail, error) {
+	if _, err := ws.getWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	webhookData, err := ws.toWebhookData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := ws.webhookRepository.SetWebhook(ctx, id, webhookData)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.mapWebhookDetail(webhook), nil
+}
+
+func (ws *WebhookService) scheduleDeliveries(ctx context.Context) error {
+	_, err := inTx(ctx, ws.auditService, func(ctx context.Context) (interface{}, error) {
+		outboxEvents, err := ws.outboxEventRepository.GetNotDispatchedOutboxEvents(ctx, int32(ws.webhookConfig.BatchSize))
+		if err != nil || len(outboxEvents) == 0 {
+			return nil, err
+		}
+
+		webhooks, err := ws.webhookRepository.GetEnabledWebhooks(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, outboxEvent := range outboxEvents {
+			for _, webhook := range webhooks {
+				if !webhook.Accepts(outboxEvent.EventType) {
+					continue
+				}
+				if err := ws.webhookDeliveryRepository.AddWebhookDelivery(ctx, webhook.ID, outboxEvent.ID); err != nil {
+					return nil, err
+				}
+			}
+
+			if err := ws.outboxEventRepository.SetOutboxEventDispatched(ctx, outboxEvent.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (ws *WebhookService) deliver(ctx context.Context, delivery *repository.WebhookDelivery) error {
+	webhook, err := ws.webhookRepository.GetWebhookById(ctx, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	if !webhook.Enabled {
+		_, err = ws.webhookDeliveryRepository.SetWebhookDeliveryResult(ctx, delivery.ID, &repository.WebhookDeliveryResultData{
+			Status:        repository.WebhookDeliveryStatusDeadLetter,
+			Attempts:      delivery.Attempts,
+			NextAttemptAt: delivery.NextAttemptAt,
+			LastError:     "webhook disabled",
+		})
+		return err
+	}
+
+	outboxEvent, err := ws.outboxEventRepository.GetOutboxEventById(ctx, delivery.OutboxEventID)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&webhookPayload{
+		ID:        outboxEvent.ID.String(),
+		Seq:       outboxEvent.Seq,
+		Type:      outboxEvent.EventType,
+		CreatedAt: outboxEvent.CreatedAt,
+		Data:      outboxEvent.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	start := time.Now()
+	statusCode, sendErr := ws.webhookClient.Send(ctx, &client.WebhookRequest{
+		URL: webhook.URL,
+		Headers: map[string]string{
+			WEBHOOK_HEADER_ID:        outboxEvent.ID.String(),
+			WEBHOOK_HEADER_DELIVERY:  delivery.ID.String(),
+			WEBHOOK_HEADER_EVENT:     outboxEvent.EventType,
+			WEBHOOK_HEADER_TIMESTAMP: timestamp,
+			WEBHOOK_HEADER_SIGNATURE: "sha256=" + WebhookSignature(webhook.Secret, timestamp, body),
+		},
+		Body: body,
+	})
+	duration := time.Since(start)
+
+	result := &repository.WebhookDeliveryResultData{
+		Status:        repository.WebhookDeliveryStatusDelivered,
+		Attempts:      delivery.Attempts + 1,
+		NextAttemptAt: delivery.NextAttemptAt,
+	}
+	lastError := ""
+	if sendErr != nil {
+		lastError = sendErr.Error()
+		result.LastError = lastError
+		if int(result.Attempts) >= ws.webhookConfig.MaxAttempts {
+			result.Status = repository.WebhookDeliveryStatusDeadLetter
+		} else {
+			result.Status = repository.WebhookDeliveryStatusScheduled
+			result.NextAttemptAt = time.Now().Add(ws.backoff(result.Attempts))
+		}
+	}
+
+	_, err = inTx(ctx, ws.auditService, func(ctx context.Context) (interface{}, error) {
+		if _, err := ws.webhookDeliveryRepository.AddWebhookDeliveryAttempt(ctx, &repository.WebhookDeliveryAttemptData{
+			DeliveryID: delivery.ID,
+			StatusCode: int32(statusCode),
+			Error:      lastError,
+			Duration:   duration,
+		}); err != nil {
+			return nil, err
+		}
+		return ws.webhookDeliveryRepository.SetWebhookDeliveryResult(ctx, delivery.ID, result)
+	})
+	return err
+}
+
+func (ws *WebhookService) backoff(attempts int32) time.Duration {
+	backoff := ws.webhookConfig.RetryBackoff
+	for i := int32(1); i < attempts && backoff < ws.webhookConfig.RetryBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, ws.webhookConfig.RetryBackoffMax)
+}
+
+func (ws *WebhookService) getWebhook(ctx context.Context, id pgtype.UUID) (*repository.Webhook, error) {
+	webhook, err := ws.webhookRepository.GetWebhookById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "webhook not found")
+	}
+	return webhook, nil
+}
+
+func (ws *WebhookService) getWebhookDelivery(ctx context.Context, id pgtype.UUID) (*repository.WebhookDelivery, error) {
+	delivery, err := ws.webhookDeliveryRepository.GetWebhookDeliveryById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, common.NewServiceError(http.StatusNotFound, string(openapi.NOT_FOUND), "webhook delivery not found")
+	}
+	return delivery, nil
+}
+
+func (ws *WebhookService) mapWebhookDeliveryDetail(delivery *repository.WebhookDelivery) *openapi.WebhookDeliveryDetail {
+	result := &openapi.WebhookDeliveryDetail{
+		Id:            delivery.ID.String(),
+		WebhookId:     delivery.WebhookID.String(),
+		EventId:       delivery.OutboxEventID.String(),
+		Status:        openapi.WebhookDeliveryStatus(delivery.Status),
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+		CreatedAt:     delivery.CreatedAt,
+	}
+	if delivery.DeliveredAt != nil {
+		result.DeliveredAt = *delivery.DeliveredAt
+	}
+	return result
+}
+
+func (ws *WebhookService) mapWebhookDetail(webhook *repository.Webhook) *openapi.WebhookDetail {
+	eventTypes := make([]openapi.WebhookEventType, len(webhook.EventTypes))
+	for i, eventType := range webhook.EventTypes {
+		eventTypes[i] = openapi.WebhookEventType(eventType)
+	}
+
+	return &openapi.WebhookDetail{
+		Id:         webhook.ID.String(),
+		Url:        webhook.URL,
+		EventTypes: eventTypes,
+		Enabled:    webhook.Enabled,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+func (ws *WebhookService) toWebhookData(data *openapi.WebhookData) (*repository.WebhookData, error) {
+	webhookUrl, err := url.Parse(data.Url)
+	if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+		return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'url' must be an absolute http(s) url")
+	}
+
+	eventTypes := make([]string, len(data.EventTypes))
+	for i, eventType := range data.EventTypes {
+		switch eventType {
+		case openapi.USER_CREATED, openapi.USER_CONFIRMED, openapi.USER_UNCONFIRMED, openapi.USER_EMAIL_CHANGED,
+			openapi.USER_AUTHORITIES_CHANGED, openapi.USER_ATTRIBUTES_CHANGED, openapi.USER_ENABLED, openapi.USER_DISABLED,
+			openapi.USER_DELETED:
+			eventTypes[i] = string(eventType)
+		default:
+			return nil, common.NewServiceError(http.StatusBadRequest, string(openapi.INVALID_FIELD), "'eventTypes' contains unsupported value")
+		}
+	}
+
+	return &repository.WebhookData{
+		URL:        data.Url,
+		Secret:     data.Secret,
+		EventTypes: eventTypes,
+		Enabled:    data.Enabled,
+	}, nil
+}
+
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,18 @@
+-- Table: confirmation_token
+create table if not exists confirmation_token
+(
+    id          uuid         not null,
+    user_id     uuid         not null,
+    purpose     varchar(255) not null,
+    created_at  timestamptz  not null,
+    expires_at  timestamptz  not null,
+    consumed_at timestamptz
+);
+
+alter table confirmation_token
+    add constraint pk_confirmation_token primary key (id);
+
+alter table confirmation_token
+    add constraint fk_confirmation_token_user foreign key (user_id) references "user" (id) on delete cascade;
+
+create index if not exists idx_confirmation_token_user_purpose on confirmation_token (user_id, purpose);
@@ -0,0 +1,2 @@
+alter table "user"
+    add column if not exists pending_email varchar(255);
@@ -0,0 +1,20 @@
+-- Table: invitation
+create table if not exists invitation
+(
+    id          uuid         not null,
+    user_id     uuid         not null,
+    email       varchar(255) not null,
+    created_at  timestamptz  not null,
+    expires_at  timestamptz  not null,
+    accepted_at timestamptz,
+    revoked_at  timestamptz
+);
+
+alter table invitation
+    add constraint pk_invitation primary key (id);
+
+alter table invitation
+    add constraint uq_invitation_user_id unique (user_id);
+
+alter table invitation
+    add constraint fk_invitation_user foreign key (user_id) references "user" (id) on delete cascade;
@@ -0,0 +1,3 @@
+drop trigger if exists trg_audit_event_append_only on audit_event;
+drop function if exists audit_event_append_only();
+drop table if exists audit_event;
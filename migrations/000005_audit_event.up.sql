@@ -0,0 +1,36 @@
+-- Table: audit_event
+create table if not exists audit_event
+(
+    id          uuid         not null,
+    created_at  timestamptz  not null,
+    actor_id    varchar(255),
+    actor_email varchar(255),
+    action      varchar(255) not null,
+    target_type varchar(255) not null,
+    target_id   varchar(255),
+    ip          varchar(255),
+    user_agent  text,
+    before      jsonb,
+    after       jsonb
+);
+
+alter table audit_event
+    add constraint pk_audit_event primary key (id);
+
+create index if not exists idx_audit_event_created_at on audit_event (created_at);
+create index if not exists idx_audit_event_actor_id on audit_event (actor_id);
+create index if not exists idx_audit_event_target_id on audit_event (target_id);
+create index if not exists idx_audit_event_action on audit_event (action);
+
+create or replace function audit_event_append_only() returns trigger as
+$$
+begin
+    raise exception 'audit_event is append-only';
+end;
+$$ language plpgsql;
+
+create trigger trg_audit_event_append_only
+    before update or delete
+    on audit_event
+    for each row
+execute function audit_event_append_only();
@@ -0,0 +1,11 @@
+drop trigger if exists trg_audit_checkpoint_append_only on audit_checkpoint;
+drop table if exists audit_checkpoint;
+
+drop index if exists idx_audit_event_seq;
+
+alter table audit_event
+    drop column if exists hash;
+alter table audit_event
+    drop column if exists prev_hash;
+alter table audit_event
+    drop column if exists seq;
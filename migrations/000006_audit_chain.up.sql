@@ -0,0 +1,40 @@
+alter table audit_event
+    add column if not exists seq bigserial;
+alter table audit_event
+    add column if not exists prev_hash varchar(64);
+alter table audit_event
+    add column if not exists hash varchar(64);
+
+create unique index if not exists idx_audit_event_seq on audit_event (seq);
+
+-- Table: audit_checkpoint
+create table if not exists audit_checkpoint
+(
+    id         uuid        not null,
+    seq        bigint      not null,
+    hash       varchar(64) not null,
+    kid        uuid        not null,
+    signature  text        not null,
+    created_at timestamptz not null
+);
+
+alter table audit_checkpoint
+    add constraint pk_audit_checkpoint primary key (id);
+
+alter table audit_checkpoint
+    add constraint fk_audit_checkpoint_jwk foreign key (kid) references jwk (id);
+
+create index if not exists idx_audit_checkpoint_seq on audit_checkpoint (seq);
+
+create or replace function audit_event_append_only() returns trigger as
+$$
+begin
+    raise exception '% is append-only', tg_table_name;
+end;
+$$ language plpgsql;
+
+create trigger trg_audit_checkpoint_append_only
+    before update or delete
+    on audit_checkpoint
+    for each row
+execute function audit_event_append_only();
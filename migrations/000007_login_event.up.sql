@@ -0,0 +1,22 @@
+-- Table: login_event
+create table if not exists login_event
+(
+    id          uuid         not null,
+    user_id     uuid         not null,
+    created_at  timestamptz  not null,
+    ip          varchar(255),
+    user_agent  text,
+    method      varchar(255) not null,
+    success     boolean      not null,
+    fingerprint varchar(255) not null,
+    revoked_at  timestamptz
+);
+
+alter table login_event
+    add constraint pk_login_event primary key (id);
+
+alter table login_event
+    add constraint fk_login_event_user foreign key (user_id) references "user" (id) on delete cascade;
+
+create index if not exists idx_login_event_user_id_created_at on login_event (user_id, created_at);
+create index if not exists idx_login_event_user_id_fingerprint on login_event (user_id, fingerprint);
@@ -0,0 +1,4 @@
+drop table if exists webhook_delivery_attempt;
+drop table if exists webhook_delivery;
+drop table if exists webhook;
+drop table if exists outbox_event;
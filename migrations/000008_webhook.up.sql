@@ -0,0 +1,79 @@
+-- Table: outbox_event
+create table if not exists outbox_event
+(
+    id            uuid         not null,
+    seq           bigserial    not null,
+    created_at    timestamptz  not null,
+    event_type    varchar(255) not null,
+    aggregate_id  uuid         not null,
+    payload       jsonb        not null,
+    dispatched_at timestamptz
+);
+
+alter table outbox_event
+    add constraint pk_outbox_event primary key (id);
+
+create unique index if not exists idx_outbox_event_seq on outbox_event (seq);
+create index if not exists idx_outbox_event_not_dispatched on outbox_event (seq) where dispatched_at is null;
+
+-- Table: webhook
+create table if not exists webhook
+(
+    id          uuid           not null,
+    url         varchar(2048)  not null,
+    secret      varchar(255)   not null,
+    event_types varchar(255)[] not null,
+    enabled     boolean        not null,
+    created_at  timestamptz    not null
+);
+
+alter table webhook
+    add constraint pk_webhook primary key (id);
+
+-- Table: webhook_delivery
+create table if not exists webhook_delivery
+(
+    id              uuid         not null,
+    webhook_id      uuid         not null,
+    outbox_event_id uuid         not null,
+    status          varchar(255) not null,
+    attempts        integer      not null,
+    next_attempt_at timestamptz  not null,
+    last_error      text,
+    created_at      timestamptz  not null,
+    delivered_at    timestamptz
+);
+
+alter table webhook_delivery
+    add constraint pk_webhook_delivery primary key (id);
+
+alter table webhook_delivery
+    add constraint uq_webhook_delivery_webhook_id_outbox_event_id unique (webhook_id, outbox_event_id);
+
+alter table webhook_delivery
+    add constraint fk_webhook_delivery_webhook foreign key (webhook_id) references webhook (id) on delete cascade;
+
+alter table webhook_delivery
+    add constraint fk_webhook_delivery_outbox_event foreign key (outbox_event_id) references outbox_event (id) on delete cascade;
+
+create index if not exists idx_webhook_delivery_status_next_attempt_at on webhook_delivery (status, next_attempt_at);
+create index if not exists idx_webhook_delivery_webhook_id_created_at on webhook_delivery (webhook_id, created_at);
+
+-- Table: webhook_delivery_attempt
+create table if not exists webhook_delivery_attempt
+(
+    id          uuid        not null,
+    delivery_id uuid        not null,
+    created_at  timestamptz not null,
+    status_code integer,
+    error       text,
+    duration_ms bigint      not null
+);
+
+alter table webhook_delivery_attempt
+    add constraint pk_webhook_delivery_attempt primary key (id);
+
+alter table webhook_delivery_attempt
+    add constraint fk_webhook_delivery_attempt_webhook_delivery foreign key (delivery_id) references webhook_delivery (id) on delete cascade;
+
+create index if not exists idx_webhook_delivery_attempt_delivery_id_created_at on webhook_delivery_attempt (delivery_id, created_at);
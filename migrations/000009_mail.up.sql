@@ -0,0 +1,20 @@
+-- Table: mail
+create table if not exists mail
+(
+    id              uuid           not null,
+    created_at      timestamptz    not null,
+    recipients      varchar(255)[] not null,
+    subject         text           not null,
+    data            jsonb          not null,
+    status          varchar(255)   not null,
+    attempts        integer        not null,
+    next_attempt_at timestamptz    not null,
+    last_error      text,
+    sent_at         timestamptz
+);
+
+alter table mail
+    add constraint pk_mail primary key (id);
+
+create index if not exists idx_mail_status_next_attempt_at on mail (status, next_attempt_at);
+create index if not exists idx_mail_created_at on mail (created_at);
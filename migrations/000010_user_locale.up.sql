@@ -0,0 +1,2 @@
+alter table "user"
+    add column if not exists locale varchar(35);
@@ -0,0 +1,2 @@
+drop table if exists mail_template_version;
+drop table if exists mail_template;
@@ -0,0 +1,36 @@
+-- Table: mail_template
+create table if not exists mail_template
+(
+    id         uuid        not null,
+    code       varchar(50) not null,
+    locale     varchar(35) not null,
+    version    integer     not null,
+    subject    text        not null,
+    html_body  text        not null,
+    text_body  text,
+    created_at timestamptz not null,
+    updated_at timestamptz not null
+);
+
+alter table mail_template
+    add constraint pk_mail_template primary key (id);
+
+alter table mail_template
+    add constraint uq_mail_template_code_locale unique (code, locale);
+
+-- Table: mail_template_version
+create table if not exists mail_template_version
+(
+    mail_template_id uuid        not null,
+    version          integer     not null,
+    subject          text        not null,
+    html_body        text        not null,
+    text_body        text,
+    created_at       timestamptz not null
+);
+
+alter table mail_template_version
+    add constraint pk_mail_template_version primary key (mail_template_id, version);
+
+alter table mail_template_version
+    add constraint fk_mail_template_version_mail_template foreign key (mail_template_id) references mail_template (id) on delete cascade;
@@ -0,0 +1,4 @@
+alter table jwk
+    drop column if exists kek_version;
+alter table jwk
+    drop column if exists private_key_dek;
@@ -0,0 +1,4 @@
+alter table jwk
+    add column if not exists private_key_dek bytea;
+alter table jwk
+    add column if not exists kek_version varchar(255);
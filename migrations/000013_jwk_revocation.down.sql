@@ -0,0 +1,2 @@
+alter table jwk
+    drop column if exists revoked_at;
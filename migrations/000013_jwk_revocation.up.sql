@@ -0,0 +1,2 @@
+alter table jwk
+    add column if not exists revoked_at timestamptz;
@@ -0,0 +1,12 @@
+alter table jwk
+    add column if not exists active boolean not null default false;
+
+update jwk
+set active = revoked_at is null and activates_at <= now() and expires_at > now();
+
+alter table jwk
+    alter column active drop default;
+alter table jwk
+    drop column if exists published_until;
+alter table jwk
+    drop column if exists activates_at;
@@ -0,0 +1,16 @@
+alter table jwk
+    add column if not exists activates_at timestamptz;
+alter table jwk
+    add column if not exists published_until timestamptz;
+
+update jwk
+set activates_at    = created_at,
+    expires_at      = case when active then expires_at else least(expires_at, now()) end,
+    published_until = case when active then expires_at else least(expires_at, now()) end;
+
+alter table jwk
+    alter column activates_at set not null;
+alter table jwk
+    alter column published_until set not null;
+alter table jwk
+    drop column if exists active;
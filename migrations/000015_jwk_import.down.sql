@@ -0,0 +1,4 @@
+alter table jwk
+    drop constraint if exists uq_jwk_kid;
+alter table jwk
+    drop column if exists kid;
@@ -0,0 +1,10 @@
+alter table jwk
+    add column if not exists kid varchar(255);
+
+update jwk
+set kid = id::text;
+
+alter table jwk
+    alter column kid set not null;
+alter table jwk
+    add constraint uq_jwk_kid unique (kid);
@@ -0,0 +1,2 @@
+alter table jwk
+    add column if not exists imported boolean not null default false;
\ No newline at end of file
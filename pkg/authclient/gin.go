@@ -0,0 +1,44 @@
+package authclient
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/go-util/security"
+)
+
+type httpHandlers struct {
+	verifier *Verifier
+}
+
+var _ security.HttpHandlers[*Principal] = (*httpHandlers)(nil)
+
+// NewHttpTokenMiddleware verifies the bearer token of every non-public endpoint and checks the authorities configured
+// for it, the principal is available through GetHttpPrincipal.
+func NewHttpTokenMiddleware(verifier *Verifier, config security.HttpSecurityConfig) gin.HandlerFunc {
+	return security.NewHttpTokenMiddleware[*Principal](config, &httpHandlers{verifier}).HandlerFunc()
+}
+
+func GetHttpPrincipal(c *gin.Context) (*Principal, bool) {
+	return security.GetHttpUserDetail[*Principal](c)
+}
+
+func (h *httpHandlers) MissingAuthorizationHeader(c *gin.Context) {
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+func (h *httpHandlers) Unauthorized(c *gin.Context) {
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+func (h *httpHandlers) PermissionDenied(c *gin.Context) {
+	c.AbortWithStatus(http.StatusForbidden)
+}
+
+func (h *httpHandlers) DecodeUserDetail(c *gin.Context, token string) (*Principal, error) {
+	return h.verifier.Verify(c.Request.Context(), token)
+}
+
+func (h *httpHandlers) GetUserAuthorities(c *gin.Context, principal *Principal) ([]string, error) {
+	return principal.Authorities, nil
+}
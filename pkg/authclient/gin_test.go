@@ -0,0 +1,70 @@
+package authclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/go-util/security"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpTokenMiddleware(t *testing.T) {
+	key := newTestKey(t, "access-1")
+	source := &testKeySource{}
+	source.publish(key.jwk(KEY_USE_ACCESS))
+	verifier := newTestVerifier(t, source, time.Hour)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(NewHttpTokenMiddleware(verifier, security.HttpSecurityConfig{
+		PublicEndpoints: map[string]struct{}{"GET:/public": {}},
+		Authorities:     map[string][]string{"GET:/admin": {"admin"}},
+	}))
+	handler := func(c *gin.Context) {
+		if principal, ok := GetHttpPrincipal(c); ok {
+			c.String(http.StatusOK, principal.ID)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	router.GET("/public", handler)
+	router.GET("/user", handler)
+	router.GET("/admin", handler)
+
+	expired := testClaims("admin")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+		principal     string
+	}{
+		{"public endpoint", "/public", "", http.StatusOK, ""},
+		{"missing token", "/user", "", http.StatusUnauthorized, ""},
+		{"not a bearer token", "/user", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"invalid token", "/user", "Bearer not-a-token", http.StatusUnauthorized, ""},
+		{"expired token", "/user", "Bearer " + key.sign(t, expired), http.StatusUnauthorized, ""},
+		{"authenticated", "/user", "Bearer " + key.sign(t, testClaims("customer")), http.StatusOK, "user-1"},
+		{"missing authority", "/admin", "Bearer " + key.sign(t, testClaims("customer")), http.StatusForbidden, ""},
+		{"granted authority", "/admin", "Bearer " + key.sign(t, testClaims("admin")), http.StatusOK, "user-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			assert.Equal(t, tt.status, response.Code)
+			assert.Equal(t, tt.principal, response.Body.String())
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package authclient
+
+import (
+	"context"
+	"strings"
+
+	"github.com/janobono/go-util/security"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type principalContextKey struct{}
+
+type userDetailDecoder struct {
+	verifier *Verifier
+}
+
+var _ security.UserDetailDecoder[*Principal] = (*userDetailDecoder)(nil)
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedServerStream) Context() context.Context {
+	return w.ctx
+}
+
+// NewUnaryTokenInterceptor verifies the bearer token of the secured methods and checks their authorities, the
+// principal is available through GetGrpcPrincipal.
+func NewUnaryTokenInterceptor(verifier *Verifier, methods []security.GrpcSecuredMethod) grpc.UnaryServerInterceptor {
+	return security.NewGrpcTokenInterceptor[*Principal](&userDetailDecoder{verifier}).InterceptToken(methods)
+}
+
+func NewStreamTokenInterceptor(verifier *Verifier, methods []security.GrpcSecuredMethod) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		securedMethod := security.FindGrpcSecuredMethod(methods, info.FullMethod)
+		if securedMethod == nil {
+			return handler(srv, stream)
+		}
+
+		ctx := stream.Context()
+		md, _ := metadata.FromIncomingContext(ctx)
+		authHeader := md.Get("authorization")
+		if len(authHeader) == 0 || !strings.HasPrefix(authHeader[0], "Bearer ") {
+			return status.Errorf(codes.Unauthenticated, "missing or invalid Bearer token")
+		}
+
+		principal, err := verifier.Verify(ctx, strings.TrimPrefix(authHeader[0], "Bearer "))
+		if err != nil {
+			return status.Errorf(codes.Unauthenticated, "%s", err.Error())
+		}
+
+		if len(securedMethod.Authorities) > 0 && !principal.HasAnyAuthority(securedMethod.Authorities...) {
+			return status.Errorf(codes.PermissionDenied, "insufficient permissions")
+		}
+
+		return handler(srv, &wrappedServerStream{stream, context.WithValue(ctx, principalContextKey{}, principal)})
+	}
+}
+
+func GetGrpcPrincipal(ctx context.Context) (*Principal, bool) {
+	if principal, ok := ctx.Value(principalContextKey{}).(*Principal); ok {
+		return principal, true
+	}
+	return security.GetGrpcUserDetail[*Principal](ctx)
+}
+
+func (ud *userDetailDecoder) DecodeGrpcUserDetail(ctx context.Context, token string) (*Principal, error) {
+	return ud.verifier.Verify(ctx, token)
+}
+
+func (ud *userDetailDecoder) GetGrpcUserAuthorities(ctx context.Context, principal *Principal) ([]string, error) {
+	return principal.Authorities, nil
+}
@@ -0,0 +1,90 @@
+package authclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/janobono/go-util/security"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestGrpcTokenInterceptors(t *testing.T) {
+	key := newTestKey(t, "access-1")
+	source := &testKeySource{}
+	source.publish(key.jwk(KEY_USE_ACCESS))
+	verifier := newTestVerifier(t, source, time.Hour)
+
+	methods := []security.GrpcSecuredMethod{
+		{Method: "/test.Service/User"},
+		{Method: "/test.Service/Admin", Authorities: []string{"admin"}},
+	}
+	unaryInterceptor := NewUnaryTokenInterceptor(verifier, methods)
+	streamInterceptor := NewStreamTokenInterceptor(verifier, methods)
+
+	expired := testClaims("admin")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		code          codes.Code
+		principal     string
+	}{
+		{"public method", "/test.Service/Public", "", codes.OK, ""},
+		{"missing token", "/test.Service/User", "", codes.Unauthenticated, ""},
+		{"not a bearer token", "/test.Service/User", "Basic dXNlcjpwYXNz", codes.Unauthenticated, ""},
+		{"invalid token", "/test.Service/User", "Bearer not-a-token", codes.Unauthenticated, ""},
+		{"expired token", "/test.Service/User", "Bearer " + key.sign(t, expired), codes.Unauthenticated, ""},
+		{"authenticated", "/test.Service/User", "Bearer " + key.sign(t, testClaims("customer")), codes.OK, "user-1"},
+		{"missing authority", "/test.Service/Admin", "Bearer " + key.sign(t, testClaims("customer")), codes.PermissionDenied, ""},
+		{"granted authority", "/test.Service/Admin", "Bearer " + key.sign(t, testClaims("admin")), codes.OK, "user-1"},
+	}
+
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+		}
+
+		t.Run(tt.name+" unary", func(t *testing.T) {
+			var principal string
+			_, err := unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				if p, ok := GetGrpcPrincipal(ctx); ok {
+					principal = p.ID
+				}
+				return nil, nil
+			})
+
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.principal, principal)
+		})
+
+		t.Run(tt.name+" stream", func(t *testing.T) {
+			var principal string
+			err := streamInterceptor(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: tt.method}, func(srv interface{}, stream grpc.ServerStream) error {
+				if p, ok := GetGrpcPrincipal(stream.Context()); ok {
+					principal = p.ID
+				}
+				return nil
+			})
+
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.principal, principal)
+		})
+	}
+}
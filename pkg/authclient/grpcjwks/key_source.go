@@ -0,0 +1,51 @@
+// Package grpcjwks fetches the key set of the auth service for authclient over gRPC. It depends on the generated gRPC
+// stubs of the service, the authclient package itself does not.
+package grpcjwks
+
+import (
+	"context"
+
+	"github.com/janobono/auth-service/generated/proto"
+	"github.com/janobono/auth-service/pkg/authclient"
+	"google.golang.org/grpc"
+)
+
+type keySource struct {
+	client proto.JwksClient
+}
+
+// NewKeySource fetches keys from the Jwks gRPC service of the auth service.
+func NewKeySource(conn grpc.ClientConnInterface) authclient.KeySource {
+	return &keySource{proto.NewJwksClient(conn)}
+}
+
+func (ks *keySource) FetchJwks(ctx context.Context, etag string) (*authclient.Jwks, error) {
+	response, err := ks.client.GetJwks(ctx, &proto.JwksRequest{IfNoneMatch: etag})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &authclient.Jwks{
+		ETag:        response.Etag,
+		NotModified: response.NotModified,
+	}
+	if response.ExpiresAt != nil {
+		result.ExpiresAt = response.ExpiresAt.AsTime()
+	}
+
+	result.Keys = make([]authclient.Jwk, len(response.Keys))
+	for i, key := range response.Keys {
+		result.Keys[i] = authclient.Jwk{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		}
+	}
+	return result, nil
+}
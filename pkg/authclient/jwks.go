@@ -0,0 +1,185 @@
+package authclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Jwks is a fetched key set. NotModified is set instead of Keys when the set still has the requested ETag, ExpiresAt
+// is zero when the source did not tell how long the set can be cached.
+type Jwks struct {
+	Keys        []Jwk
+	ETag        string
+	ExpiresAt   time.Time
+	NotModified bool
+}
+
+// KeySource fetches the key set published by the auth service.
+type KeySource interface {
+	FetchJwks(ctx context.Context, etag string) (*Jwks, error)
+}
+
+type httpKeySource struct {
+	url    string
+	client *http.Client
+}
+
+// NewHttpKeySource fetches keys from the /.well-known/jwks.json URL of the auth service, http.DefaultClient is used
+// when client is nil.
+func NewHttpKeySource(url string, client *http.Client) KeySource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpKeySource{url, client}
+}
+
+func (hs *httpKeySource) FetchJwks(ctx context.Context, etag string) (*Jwks, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, hs.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
+	response, err := hs.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	result := &Jwks{
+		ETag:      response.Header.Get("ETag"),
+		ExpiresAt: cacheExpiration(response.Header.Get("Cache-Control"), time.Now()),
+	}
+
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		result.NotModified = true
+		return result, nil
+	case http.StatusOK:
+		var body struct {
+			Keys []Jwk `json:"keys"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		result.Keys = body.Keys
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unexpected jwks response status: %d", response.StatusCode)
+	}
+}
+
+func cacheExpiration(cacheControl string, now time.Time) time.Time {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+				return now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// PublicKey returns the public key of a JWK as published by the auth service.
+func (k *Jwk) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		values, err := decodeJwkValues(k.N, k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		exponent := new(big.Int).SetBytes(values[1])
+		if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(values[0]), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		values, err := decodeJwkValues(k.X, k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		if len(values[0]) != size || len(values[1]) != size {
+			return nil, errors.New("invalid key size")
+		}
+		// ecdh rejects points that are not on the curve
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, values[0]...), values[1]...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(values[0]), Y: new(big.Int).SetBytes(values[1])}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		values, err := decodeJwkValues(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(values[0]) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid key size")
+		}
+		return ed25519.PublicKey(values[0]), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+func decodeJwkValues(values ...string) ([][]byte, error) {
+	result := make([][]byte, len(values))
+	for i, value := range values {
+		if value == "" {
+			return nil, errors.New("missing key parameter")
+		}
+
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = decoded
+	}
+	return result, nil
+}
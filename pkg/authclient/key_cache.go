@@ -0,0 +1,148 @@
+package authclient
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keyCache keeps the published keys of one use. The set is refreshed when the source says it expires, at the latest
+// after maxRefresh, and early when a token names an unknown key, at most once per minRefresh.
+type keyCache struct {
+	source     KeySource
+	use        string
+	minRefresh time.Duration
+	maxRefresh time.Duration
+
+	fetchMutex sync.Mutex
+	current    atomic.Pointer[keySet]
+}
+
+type keySet struct {
+	keys      map[string]*cachedKey
+	etag      string
+	fetchedAt time.Time
+	refreshAt time.Time
+}
+
+type cachedKey struct {
+	alg       string
+	publicKey crypto.PublicKey
+}
+
+func newKeyCache(source KeySource, use string, minRefresh, maxRefresh time.Duration) *keyCache {
+	return &keyCache{
+		source:     source,
+		use:        use,
+		minRefresh: minRefresh,
+		maxRefresh: maxRefresh,
+	}
+}
+
+func (c *keyCache) getKey(ctx context.Context, kid string) (*cachedKey, error) {
+	set := c.current.Load()
+	if set == nil || !time.Now().Before(set.refreshAt) {
+		var err error
+		set, err = c.refresh(ctx, set)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := set.keys[kid]; ok {
+		return key, nil
+	}
+
+	// a key published after the last fetch
+	if time.Since(set.fetchedAt) >= c.minRefresh {
+		var err error
+		set, err = c.refresh(ctx, set)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := set.keys[kid]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key: %s", kid)
+}
+
+// refresh fetches the key set unless another caller replaced seen meanwhile. A failed fetch keeps the previous keys
+// and is retried after minRefresh.
+func (c *keyCache) refresh(ctx context.Context, seen *keySet) (*keySet, error) {
+	c.fetchMutex.Lock()
+	defer c.fetchMutex.Unlock()
+
+	current := c.current.Load()
+	if current != seen {
+		return current, nil
+	}
+
+	etag := ""
+	if current != nil {
+		etag = current.etag
+	}
+
+	jwks, err := c.source.FetchJwks(ctx, etag)
+	now := time.Now()
+	if err != nil {
+		if current == nil {
+			return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+		}
+
+		slog.Warn("Failed to refresh jwks", "error", err)
+		next := *current
+		next.fetchedAt = now
+		next.refreshAt = now.Add(c.minRefresh)
+		c.current.Store(&next)
+		return &next, nil
+	}
+
+	next := &keySet{
+		etag:      jwks.ETag,
+		fetchedAt: now,
+		refreshAt: c.refreshAt(jwks.ExpiresAt, now),
+	}
+	if jwks.NotModified && current != nil {
+		next.keys = current.keys
+	} else {
+		next.keys = c.mapKeys(jwks.Keys)
+	}
+
+	c.current.Store(next)
+	return next, nil
+}
+
+func (c *keyCache) refreshAt(expiresAt, now time.Time) time.Time {
+	result := now.Add(c.maxRefresh)
+	if !expiresAt.IsZero() && expiresAt.Before(result) {
+		result = expiresAt
+	}
+	if earliest := now.Add(c.minRefresh); result.Before(earliest) {
+		result = earliest
+	}
+	return result
+}
+
+func (c *keyCache) mapKeys(jwks []Jwk) map[string]*cachedKey {
+	result := make(map[string]*cachedKey, len(jwks))
+	for _, jwk := range jwks {
+		if c.use != "" && jwk.Use != c.use {
+			continue
+		}
+
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			slog.Warn("Skipping invalid jwk", "kid", jwk.Kid, "error", err)
+			continue
+		}
+
+		result[jwk.Kid] = &cachedKey{alg: jwk.Alg, publicKey: publicKey}
+	}
+	return result
+}
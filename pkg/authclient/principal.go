@@ -0,0 +1,26 @@
+package authclient
+
+import (
+	"slices"
+	"time"
+)
+
+// Principal is the user an access token was issued to, as far as the token claims tell.
+type Principal struct {
+	ID          string
+	SessionID   string
+	Email       string
+	Confirmed   bool
+	Attributes  map[string]string
+	Authorities []string
+	ExpiresAt   time.Time
+}
+
+func (p *Principal) HasAnyAuthority(authorities ...string) bool {
+	for _, authority := range authorities {
+		if slices.Contains(p.Authorities, authority) {
+			return true
+		}
+	}
+	return false
+}
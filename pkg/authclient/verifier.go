@@ -0,0 +1,197 @@
+// Package authclient verifies access tokens issued by the auth service in other Go services, the signing keys are
+// fetched from the published key set and cached.
+package authclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	CLAIM_AUTHORITIES      = "authorities"
+	CLAIM_EMAIL            = "email"
+	CLAIM_CONFIRMED        = "confirmed"
+	CLAIM_ATTRIBUTE_PREFIX = "attribute."
+
+	KEY_USE_ACCESS = "access"
+)
+
+// registeredClaims are set by the token itself and cannot be mapped
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "sid", "created_at"}
+
+var defaultAlgorithms = []string{"RS256", "PS256", "ES256", "ES384", "EdDSA"}
+
+// Config mirrors the token settings of the auth service. Claims has the format of SECURITY_TOKEN_CLAIMS and
+// LegacyAudience the meaning of SECURITY_TOKEN_AUDIENCE_LEGACY_MODE, Audience is not checked in legacy mode.
+type Config struct {
+	Issuer             string
+	Audience           string
+	Algorithms         []string
+	KeyUse             string
+	Claims             map[string]string
+	LegacyAudience     bool
+	Leeway             time.Duration
+	MinRefreshInterval time.Duration
+	MaxRefreshInterval time.Duration
+}
+
+type Verifier struct {
+	config   Config
+	parser   *jwt.Parser
+	keyCache *keyCache
+}
+
+func NewVerifier(config Config, source KeySource) (*Verifier, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("issuer is required")
+	}
+	if source == nil {
+		return nil, errors.New("key source is required")
+	}
+
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = defaultAlgorithms
+	}
+	if config.KeyUse == "" {
+		config.KeyUse = KEY_USE_ACCESS
+	}
+	if config.MinRefreshInterval <= 0 {
+		config.MinRefreshInterval = 30 * time.Second
+	}
+	if config.MaxRefreshInterval < config.MinRefreshInterval {
+		config.MaxRefreshInterval = max(time.Hour, config.MinRefreshInterval)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods(config.Algorithms),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Audience != "" && !config.LegacyAudience {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	return &Verifier{
+		config:   config,
+		parser:   jwt.NewParser(options...),
+		keyCache: newKeyCache(source, config.KeyUse, config.MinRefreshInterval, config.MaxRefreshInterval),
+	}, nil
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		return v.getKey(ctx, token)
+	}
+
+	jwtToken, err := v.parser.Parse(token, keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %w", err)
+	}
+
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	if !ok || !jwtToken.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return v.mapPrincipal(claims)
+}
+
+func (v *Verifier) getKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid kid in header")
+	}
+
+	key, err := v.keyCache.getKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// the algorithm is bound to the key, a token must not pick a different one
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("unexpected signing algorithm: %s", token.Method.Alg())
+	}
+
+	return key.publicKey, nil
+}
+
+func (v *Verifier) mapPrincipal(claims jwt.MapClaims) (*Principal, error) {
+	id, ok := claims["sub"].(string)
+	if !ok || id == "" {
+		return nil, errors.New("invalid access token")
+	}
+
+	principal := &Principal{
+		ID:          id,
+		Attributes:  make(map[string]string),
+		Authorities: v.authorities(claims),
+	}
+	principal.SessionID, _ = claims["sid"].(string)
+	principal.Confirmed, _ = claims[v.confirmedClaim()].(bool)
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		principal.ExpiresAt = expiresAt.Time
+	}
+
+	for source, name := range v.config.Claims {
+		if slices.Contains(registeredClaims, name) {
+			continue
+		}
+
+		switch {
+		case source == CLAIM_EMAIL:
+			principal.Email, _ = claims[name].(string)
+		case strings.HasPrefix(source, CLAIM_ATTRIBUTE_PREFIX):
+			if value, ok := claims[name].(string); ok {
+				principal.Attributes[strings.TrimPrefix(source, CLAIM_ATTRIBUTE_PREFIX)] = value
+			}
+		}
+	}
+
+	return principal, nil
+}
+
+func (v *Verifier) authorities(claims jwt.MapClaims) []string {
+	authorities, ok := stringsClaim(claims, v.authoritiesClaim())
+	// tokens issued before the switch to the authorities claim carry them in aud only
+	if !ok && v.config.LegacyAudience {
+		authorities, _ = stringsClaim(claims, "aud")
+	}
+	return authorities
+}
+
+func (v *Verifier) authoritiesClaim() string {
+	if name, ok := v.config.Claims[CLAIM_AUTHORITIES]; ok && !slices.Contains(registeredClaims, name) {
+		return name
+	}
+	return CLAIM_AUTHORITIES
+}
+
+// confirmedClaim is always set by the auth service, under its own name unless mapped
+func (v *Verifier) confirmedClaim() string {
+	if name, ok := v.config.Claims[CLAIM_CONFIRMED]; ok && !slices.Contains(registeredClaims, name) {
+		return name
+	}
+	return CLAIM_CONFIRMED
+}
+
+func stringsClaim(claims jwt.MapClaims, name string) ([]string, bool) {
+	values, ok := claims[name].([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result, true
+}
@@ -0,0 +1,272 @@
+package authclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testKeySource struct {
+	mutex   sync.Mutex
+	keys    []Jwk
+	fetches int
+}
+
+func (s *testKeySource) FetchJwks(ctx context.Context, etag string) (*Jwks, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.fetches++
+	return &Jwks{Keys: append([]Jwk(nil), s.keys...)}, nil
+}
+
+func (s *testKeySource) publish(keys ...Jwk) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys = append(s.keys, keys...)
+}
+
+func (s *testKeySource) fetchCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.fetches
+}
+
+type testKey struct {
+	kid        string
+	privateKey *ecdsa.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) *testKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return &testKey{kid: kid, privateKey: privateKey}
+}
+
+func (k *testKey) jwk(use string) Jwk {
+	return Jwk{
+		Kty: "EC",
+		Kid: k.kid,
+		Use: use,
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(k.privateKey.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(k.privateKey.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func (k *testKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = k.kid
+
+	signed, err := token.SignedString(k.privateKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func testClaims(authorities ...string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":         "simple",
+		"aud":         []string{"simple"},
+		"sub":         "user-1",
+		"sid":         "session-1",
+		"iat":         now.Unix(),
+		"exp":         now.Add(time.Hour).Unix(),
+		"authorities": authorities,
+		"email":       "user@auth.org",
+		"verified":    true,
+		"tenant":      "acme",
+	}
+}
+
+func newTestVerifier(t *testing.T, source KeySource, minRefreshInterval time.Duration) *Verifier {
+	verifier, err := NewVerifier(Config{
+		Issuer:             "simple",
+		Audience:           "simple",
+		Claims:             map[string]string{"email": "email", "confirmed": "verified", "attribute.tenant": "tenant"},
+		MinRefreshInterval: minRefreshInterval,
+	}, source)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	return verifier
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	accessKey := newTestKey(t, "access-1")
+	refreshKey := newTestKey(t, "refresh-1")
+	unpublishedKey := newTestKey(t, "unpublished-1")
+
+	source := &testKeySource{}
+	source.publish(accessKey.jwk(KEY_USE_ACCESS), refreshKey.jwk("refresh"))
+	verifier := newTestVerifier(t, source, time.Hour)
+
+	claimsWith := func(name string, value interface{}) jwt.MapClaims {
+		claims := testClaims("admin")
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid token", accessKey.sign(t, testClaims("admin")), true},
+		{"key of another use", refreshKey.sign(t, testClaims("admin")), false},
+		{"unknown key", unpublishedKey.sign(t, testClaims("admin")), false},
+		{"expired token", accessKey.sign(t, claimsWith("exp", time.Now().Add(-time.Minute).Unix())), false},
+		{"missing expiry", accessKey.sign(t, claimsWith("exp", nil)), false},
+		{"wrong issuer", accessKey.sign(t, claimsWith("iss", "other")), false},
+		{"wrong audience", accessKey.sign(t, claimsWith("aud", []string{"other"})), false},
+		{"missing subject", accessKey.sign(t, claimsWith("sub", "")), false},
+		{"malformed token", "not-a-token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), tt.token)
+			if !tt.valid {
+				assert.Error(t, err)
+				assert.Nil(t, principal)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", principal.ID)
+			assert.Equal(t, "session-1", principal.SessionID)
+			assert.Equal(t, "user@auth.org", principal.Email)
+			assert.True(t, principal.Confirmed)
+			assert.Equal(t, []string{"admin"}, principal.Authorities)
+			assert.Equal(t, map[string]string{"tenant": "acme"}, principal.Attributes)
+		})
+	}
+}
+
+func TestVerifier_ConfirmedClaim(t *testing.T) {
+	key := newTestKey(t, "access-1")
+	source := &testKeySource{}
+	source.publish(key.jwk(KEY_USE_ACCESS))
+
+	defaultVerifier, err := NewVerifier(Config{Issuer: "simple", Audience: "simple"}, source)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	mappedVerifier := newTestVerifier(t, source, time.Hour)
+
+	claimsWith := func(name string, value bool) jwt.MapClaims {
+		claims := testClaims("admin")
+		delete(claims, "verified")
+		claims[name] = value
+		return claims
+	}
+
+	tests := []struct {
+		name      string
+		verifier  *Verifier
+		claims    jwt.MapClaims
+		confirmed bool
+	}{
+		{"default claim confirmed", defaultVerifier, claimsWith(CLAIM_CONFIRMED, true), true},
+		{"default claim not confirmed", defaultVerifier, claimsWith(CLAIM_CONFIRMED, false), false},
+		{"mapped claim confirmed", mappedVerifier, claimsWith("verified", true), true},
+		{"mapped claim overrides the default", mappedVerifier, claimsWith(CLAIM_CONFIRMED, true), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := tt.verifier.Verify(context.Background(), key.sign(t, tt.claims))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.confirmed, principal.Confirmed)
+		})
+	}
+}
+
+func TestVerifier_AlgorithmBoundToKey(t *testing.T) {
+	key := newTestKey(t, "access-1")
+	jwk := key.jwk(KEY_USE_ACCESS)
+	jwk.Alg = "ES384"
+
+	source := &testKeySource{}
+	source.publish(jwk)
+	verifier := newTestVerifier(t, source, time.Hour)
+
+	_, err := verifier.Verify(context.Background(), key.sign(t, testClaims("admin")))
+	assert.Error(t, err)
+}
+
+func TestVerifier_UnknownKidRefresh(t *testing.T) {
+	firstKey := newTestKey(t, "access-1")
+	secondKey := newTestKey(t, "access-2")
+	thirdKey := newTestKey(t, "access-3")
+
+	tests := []struct {
+		name               string
+		minRefreshInterval time.Duration
+		valid              bool
+		fetches            int
+	}{
+		{"refresh on unknown kid", time.Nanosecond, true, 2},
+		{"refresh limited by min interval", time.Hour, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &testKeySource{}
+			source.publish(firstKey.jwk(KEY_USE_ACCESS))
+			verifier := newTestVerifier(t, source, tt.minRefreshInterval)
+
+			_, err := verifier.Verify(context.Background(), firstKey.sign(t, testClaims("admin")))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, source.fetchCount())
+
+			// The next key is published after the key set was cached
+			source.publish(secondKey.jwk(KEY_USE_ACCESS))
+			_, err = verifier.Verify(context.Background(), secondKey.sign(t, testClaims("admin")))
+			assert.Equal(t, tt.valid, err == nil)
+			assert.Equal(t, tt.fetches, source.fetchCount())
+		})
+	}
+
+	// A kid that stays unknown is rejected after the refresh
+	source := &testKeySource{}
+	source.publish(firstKey.jwk(KEY_USE_ACCESS))
+	verifier := newTestVerifier(t, source, time.Nanosecond)
+	_, err := verifier.Verify(context.Background(), thirdKey.sign(t, testClaims("admin")))
+	assert.Error(t, err)
+	assert.Equal(t, 2, source.fetchCount())
+}
+
+type failingKeySource struct{}
+
+func (s *failingKeySource) FetchJwks(ctx context.Context, etag string) (*Jwks, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestVerifier_KeySourceUnavailable(t *testing.T) {
+	key := newTestKey(t, "access-1")
+	verifier := newTestVerifier(t, &failingKeySource{}, time.Hour)
+
+	_, err := verifier.Verify(context.Background(), key.sign(t, testClaims("admin")))
+	assert.Error(t, err)
+}
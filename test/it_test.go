@@ -3,7 +3,9 @@ package test
 import (
 	"context"
 	"fmt"
+	"mime"
 	"net"
+	"net/mail"
 	"syscall"
 	"testing"
 	"time"
@@ -12,6 +14,9 @@ import (
 	"github.com/janobono/auth-service/internal/config"
 	"github.com/janobono/auth-service/internal/db"
 	"github.com/janobono/auth-service/internal/server"
+	client2 "github.com/janobono/auth-service/internal/service/client"
+	"github.com/janobono/auth-service/pkg/authclient"
+	"github.com/janobono/auth-service/pkg/authclient/grpcjwks"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
@@ -21,8 +26,8 @@ type testInitializer struct {
 	initializer server.Initializer
 }
 
-func (ti *testInitializer) Repositories(dataSource *db.DataSource) *server.Repositories {
-	return ti.initializer.Repositories(dataSource)
+func (ti *testInitializer) Repositories(serverConfig *config.ServerConfig, dataSource *db.DataSource) *server.Repositories {
+	return ti.initializer.Repositories(serverConfig, dataSource)
 }
 
 func (ti *testInitializer) Utils(serverConfig *config.ServerConfig) *server.Utils {
@@ -30,9 +35,15 @@ func (ti *testInitializer) Utils(serverConfig *config.ServerConfig) *server.Util
 }
 
 func (ti *testInitializer) Clients(serverConfig *config.ServerConfig) *server.Clients {
+	mailClient, err := client2.NewMailClient(serverConfig.MailConfig)
+	if err != nil {
+		panic(err)
+	}
+
 	return &server.Clients{
 		CaptchaClient: &testCaptchaClient{},
-		MailClient:    &testMailClient{},
+		MailClient:    mailClient,
+		WebhookClient: &testWebhookClient{},
 	}
 }
 
@@ -45,6 +56,7 @@ func TestIntegrationSomething(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to get free ports: %v", err)
 	}
+	mailDir := t.TempDir()
 
 	serverConfig := &config.ServerConfig{
 		Prod:        false,
@@ -53,29 +65,68 @@ func TestIntegrationSomething(t *testing.T) {
 		ContextPath: "/api",
 		DbConfig:    DbConfig,
 		MailConfig: &config.MailConfig{
-			Host:                         "",
-			Port:                         0,
-			User:                         "",
-			Password:                     "",
-			AuthEnabled:                  false,
-			TlsEnabled:                   false,
-			SignUpMailSubject:            "Sign Up Confirmation",
-			SignUpMailTemplateUrl:        "file://../templates/sign_up.html",
-			ResetPasswordMailSubject:     "Reset Password Confirmation",
-			ResetPasswordMailTemplateUrl: "file://../templates/reset_password.html",
+			Host:                             "",
+			Port:                             0,
+			User:                             "",
+			Password:                         "",
+			AuthEnabled:                      false,
+			TlsEnabled:                       false,
+			Transport:                        client2.MAIL_TRANSPORT_FILE,
+			FileDir:                          mailDir,
+			SignUpMailSubject:                "Sign Up Confirmation",
+			SignUpMailTemplateUrl:            "file://../templates/sign_up.html",
+			ResetPasswordMailSubject:         "Reset Password Confirmation",
+			ResetPasswordMailTemplateUrl:     "file://../templates/reset_password.html",
+			ChangeEmailMailSubject:           "Email Change Confirmation",
+			ChangeEmailMailTemplateUrl:       "file://../templates/change_email.html",
+			ChangeEmailNoticeMailSubject:     "Email Change Requested",
+			ChangeEmailNoticeMailTemplateUrl: "file://../templates/change_email_notice.html",
+			InvitationMailSubject:            "Invitation",
+			InvitationMailTemplateUrl:        "file://../templates/invitation.html",
+			NewSignInMailSubject:             "New sign-in",
+			NewSignInMailTemplateUrl:         "file://../templates/new_sign_in.html",
+			QueueInterval:                    time.Duration(1) * time.Second,
+			QueueBatchSize:                   50,
+			QueueWorkers:                     4,
+			MaxAttempts:                      10,
+			RetryBackoff:                     time.Duration(30) * time.Second,
+			RetryBackoffMax:                  time.Duration(3600) * time.Second,
+			Retention:                        time.Duration(24) * time.Hour,
 		},
 		SecurityConfig: &config.SecurityConfig{
-			ReadAuthorities:          []string{"customer", "manager"},
-			WriteAuthorities:         []string{"admin"},
-			DefaultUsername:          "simple@auth.org",
-			DefaultPassword:          "$2a$10$gRKMsjTON2A4b5PDIgjej.EZPvzVaKRj52Mug/9bfQBzAYmVF0Cae",
-			TokenIssuer:              "simple",
-			AccessTokenExpiresIn:     time.Duration(30) * time.Minute,
-			AccessTokenJwkExpiresIn:  time.Duration(720) * time.Minute,
-			RefreshTokenExpiresIn:    time.Duration(10080) * time.Minute,
-			RefreshTokenJwkExpiresIn: time.Duration(20160) * time.Minute,
-			ContentTokenExpiresIn:    time.Duration(10080) * time.Minute,
-			ContentTokenJwkExpiresIn: time.Duration(20160) * time.Minute,
+			ReadAuthorities:             []string{"customer", "manager"},
+			WriteAuthorities:            []string{"admin"},
+			DefaultUsername:             "simple@auth.org",
+			DefaultPassword:             "$2a$10$gRKMsjTON2A4b5PDIgjej.EZPvzVaKRj52Mug/9bfQBzAYmVF0Cae",
+			TokenIssuer:                 "simple",
+			TokenAudience:               []string{"simple"},
+			TokenClaims:                 map[string]string{"authorities": "authorities", "email": "email", "confirmed": "email_verified"},
+			TokenValidationMode:         "database",
+			UserCacheSize:               1000,
+			UserCacheTtl:                time.Duration(60) * time.Second,
+			AccessTokenExpiresIn:        time.Duration(30) * time.Minute,
+			AccessTokenJwkExpiresIn:     time.Duration(720) * time.Minute,
+			AccessTokenJwkAlgorithm:     "ES256",
+			RefreshTokenExpiresIn:       time.Duration(10080) * time.Minute,
+			RefreshTokenJwkExpiresIn:    time.Duration(20160) * time.Minute,
+			RefreshTokenJwkAlgorithm:    "EdDSA",
+			ContentTokenJwkExpiresIn:    time.Duration(20160) * time.Minute,
+			ContentTokenJwkAlgorithm:    "PS256",
+			ConfirmUserTokenExpiresIn:   time.Duration(10080) * time.Minute,
+			ResetPasswordTokenExpiresIn: time.Duration(60) * time.Minute,
+			ChangeEmailTokenExpiresIn:   time.Duration(1440) * time.Minute,
+			RevertEmailTokenExpiresIn:   time.Duration(20160) * time.Minute,
+			InvitationTokenExpiresIn:    time.Duration(10080) * time.Minute,
+			RevokeSessionTokenExpiresIn: time.Duration(10080) * time.Minute,
+			AuditJwkExpiresIn:           time.Duration(525600) * time.Minute,
+			AuditCheckpointInterval:     time.Duration(60) * time.Minute,
+			JwkRsaKeySize:               2048,
+			JwkPublishLeadTime:          time.Duration(60) * time.Minute,
+			JwkRefreshInterval:          time.Duration(60) * time.Second,
+			JwkRevocationPollInterval:   time.Duration(1) * time.Second,
+			JwkGenerationEnabled:        true,
+			JwkKekVersion:               "1",
+			JwkKeks:                     "1=MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
 		},
 		CorsConfig: &config.CorsConfig{
 			AllowedOrigins:   []string{"*"}, // Or restrict to specific domains
@@ -89,12 +140,30 @@ func TestIntegrationSomething(t *testing.T) {
 			CaptchaServiceUrl:             "",
 			ConfirmationWebUrl:            "http://localhost:3000",
 			ConfirmationPath:              "/confirm?token=",
+			ResetPasswordPath:             "/reset-password?token=",
+			ResetPasswordLegacyMode:       false,
+			InvitationPath:                "/accept-invitation?token=",
+			RevokeSessionPath:             "/revoke-session?token=",
 			SignUpConfirmationMailEnabled: true,
+			ConfirmationPolicy:            "grace",
+			ConfirmationGraceDays:         7,
 			PasswordCharacters:            "abcdefghijklmnopqrstuvwxyz0123456789",
 			PasswordLength:                8,
+			PasswordMinLength:             8,
+			PasswordRequireUppercase:      false,
+			PasswordRequireDigit:          false,
+			PasswordRequireSpecial:        false,
 			MandatoryUserAttributes:       make(map[string]string),
 			MandatoryUserAuthorities:      []string{},
 		},
+		WebhookConfig: &config.WebhookConfig{
+			DispatchInterval: time.Duration(5) * time.Second,
+			BatchSize:        100,
+			Timeout:          time.Duration(10) * time.Second,
+			MaxAttempts:      10,
+			RetryBackoff:     time.Duration(30) * time.Second,
+			RetryBackoffMax:  time.Duration(3600) * time.Second,
+		},
 	}
 
 	s := server.NewServer(serverConfig, &testInitializer{server.NewInitializer()})
@@ -120,6 +189,20 @@ func TestIntegrationSomething(t *testing.T) {
 	}
 	t.Logf("sign in result: %v", result)
 
+	var mails []*mail.Message
+	for i := 0; i < 50 && len(mails) == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if mails, err = readMails(mailDir, "simple@auth.org"); err != nil {
+			t.Fatalf("failed to read mails: %v", err)
+		}
+	}
+	if len(mails) != 1 {
+		t.Fatalf("expected one new sign-in mail, got %d", len(mails))
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(mails[0].Header.Get("Subject")); subject != "New sign-in" {
+		t.Fatalf("unexpected mail subject: %s", subject)
+	}
+
 	userClient := proto.NewUserClient(conn)
 	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(
 		"authorization", "Bearer "+result.AccessToken,
@@ -130,6 +213,33 @@ func TestIntegrationSomething(t *testing.T) {
 	}
 	t.Logf("search result: %v", usersPage)
 
+	keySources := map[string]authclient.KeySource{
+		"http": authclient.NewHttpKeySource("http://localhost"+serverConfig.HTTPAddress+serverConfig.ContextPath+"/.well-known/jwks.json", nil),
+		"grpc": grpcjwks.NewKeySource(conn),
+	}
+	for name, keySource := range keySources {
+		verifier, err := authclient.NewVerifier(authclient.Config{
+			Issuer:   serverConfig.SecurityConfig.TokenIssuer,
+			Audience: serverConfig.SecurityConfig.TokenAudience[0],
+			Claims:   serverConfig.SecurityConfig.TokenClaims,
+		}, keySource)
+		if err != nil {
+			t.Fatalf("failed to create %s verifier: %v", name, err)
+		}
+
+		principal, err := verifier.Verify(context.Background(), result.AccessToken)
+		if err != nil {
+			t.Fatalf("failed to verify access token with %s keys: %v", name, err)
+		}
+		if principal.Email != "simple@auth.org" || len(principal.Authorities) == 0 {
+			t.Fatalf("unexpected %s principal: %+v", name, principal)
+		}
+
+		if _, err := verifier.Verify(context.Background(), result.RefreshToken); err == nil {
+			t.Fatalf("refresh token accepted with %s keys", name)
+		}
+	}
+
 	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
 	time.Sleep(1 * time.Second)
 }
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/auth-service/internal/service"
+	db2 "github.com/janobono/go-util/db"
+)
+
+type testReplica struct {
+	dataSource    *db.DataSource
+	jwkRepository repository.JwkRepository
+	jwtService    *service.JwtService
+}
+
+func TestIntegrationJwkRotationReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	jwkCipher, err := repository.NewJwkCipher("1", map[string][]byte{"1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("failed to create jwk cipher: %v", err)
+	}
+
+	securityConfig := &config.SecurityConfig{
+		TokenIssuer:             "simple",
+		TokenAudience:           []string{"simple"},
+		TokenClaims:             map[string]string{"authorities": "authorities", "email": "email", "confirmed": "email_verified"},
+		AccessTokenExpiresIn:    time.Duration(30) * time.Minute,
+		AccessTokenJwkExpiresIn: time.Duration(720) * time.Minute,
+		AccessTokenJwkAlgorithm: "ES256",
+		JwkRsaKeySize:           2048,
+		JwkPublishLeadTime:      time.Duration(60) * time.Minute,
+		JwkRefreshInterval:      time.Duration(1) * time.Second,
+		JwkGenerationEnabled:    true,
+	}
+
+	replicas := make([]*testReplica, 3)
+	for i := range replicas {
+		dataSource := db.NewDataSource(DbConfig)
+		defer dataSource.Close()
+
+		jwkRepository := repository.NewJwkRepository(dataSource, jwkCipher)
+		replicas[i] = &testReplica{
+			dataSource:    dataSource,
+			jwkRepository: jwkRepository,
+			jwtService:    service.NewJwtService(securityConfig, repository.NewTransactor(dataSource), jwkRepository),
+		}
+	}
+
+	// All replicas agree on one signing key
+	kid := assertSameSigningKey(t, replicas)
+
+	// Tokens signed by one replica are accepted by the others
+	jwtToken, err := replicas[0].jwtService.GetAccessJwtToken(ctx)
+	if err != nil {
+		t.Fatalf("failed to get access token key: %v", err)
+	}
+	userId := db2.NewUUID()
+	token, err := replicas[0].jwtService.GenerateAuthToken(jwtToken, userId, &service.UserClaims{Authorities: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	for i, replica := range replicas[1:] {
+		otherJwtToken, err := replica.jwtService.GetAccessJwtToken(ctx)
+		if err != nil {
+			t.Fatalf("replica %d failed to get access token key: %v", i+1, err)
+		}
+		id, authorities, err := replica.jwtService.ParseAuthToken(ctx, otherJwtToken, token)
+		if err != nil || id != userId || !slices.Equal(authorities, []string{"admin"}) {
+			t.Fatalf("replica %d failed to parse token: %v", i+1, err)
+		}
+	}
+
+	// A manual rotation signs with the new key right away, replicas switch within the refresh interval and still accept
+	// tokens of the retired key
+	rotated, err := replicas[0].jwtService.RotateJwk(ctx, "access")
+	if err != nil {
+		t.Fatalf("failed to rotate jwk: %v", err)
+	}
+	if rotated.Kid == kid {
+		t.Fatalf("rotation kept signing key %s", kid)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if current := assertSameSigningKey(t, replicas); current != rotated.Kid {
+		t.Fatalf("expected signing key %s, got %s", rotated.Kid, current)
+	}
+	for i, replica := range replicas {
+		otherJwtToken, err := replica.jwtService.GetAccessJwtToken(ctx)
+		if err != nil {
+			t.Fatalf("replica %d failed to get access token key: %v", i, err)
+		}
+		if _, _, err := replica.jwtService.ParseAuthToken(ctx, otherJwtToken, token); err != nil {
+			t.Fatalf("replica %d rejects a token of the retired key: %v", i, err)
+		}
+	}
+
+	// A revocation on one replica rejects tokens of the revoked key on the others once they poll the revoked keys,
+	// even while they still cache it for signing
+	for i, replica := range replicas {
+		if err := replica.jwtService.RefreshRevokedJwks(ctx); err != nil {
+			t.Fatalf("replica %d failed to load revoked jwks: %v", i, err)
+		}
+	}
+	jwtToken, err = replicas[1].jwtService.GetAccessJwtToken(ctx)
+	if err != nil {
+		t.Fatalf("failed to get access token key: %v", err)
+	}
+	token, err = replicas[1].jwtService.GenerateAuthToken(jwtToken, userId, &service.UserClaims{Authorities: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	if _, err := replicas[0].jwkRepository.SetJwkRevoked(ctx, rotated.ID); err != nil {
+		t.Fatalf("failed to revoke jwk: %v", err)
+	}
+	if err := replicas[1].jwtService.RefreshRevokedJwks(ctx); err != nil {
+		t.Fatalf("failed to refresh revoked jwks: %v", err)
+	}
+	if _, _, err := replicas[1].jwtService.ParseAuthToken(ctx, jwtToken, token); err == nil {
+		t.Fatalf("token of the revoked key %s accepted", rotated.Kid)
+	}
+	for i, replica := range replicas {
+		if err := replica.jwtService.RefreshRevokedJwks(ctx); err != nil {
+			t.Fatalf("replica %d failed to refresh revoked jwks: %v", i, err)
+		}
+	}
+	if current := assertSameSigningKey(t, replicas); current == rotated.Kid {
+		t.Fatalf("revoked key %s still signs tokens", rotated.Kid)
+	}
+}
+
+func assertSameSigningKey(t *testing.T, replicas []*testReplica) string {
+	t.Helper()
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		kids  = map[string]int{}
+	)
+
+	for _, replica := range replicas {
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				jwtToken, err := replica.jwtService.GetAccessJwtToken(context.Background())
+				if err != nil {
+					t.Errorf("failed to get access token key: %v", err)
+					return
+				}
+
+				mutex.Lock()
+				kids[jwtToken.KeyID()]++
+				mutex.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+
+	if len(kids) != 1 {
+		t.Fatalf("expected all replicas to sign with one key, got %v", kids)
+	}
+	for kid := range kids {
+		return kid
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/janobono/auth-service/generated/openapi"
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/auth-service/internal/server/impl"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+func TestIntegrationJwksController(t *testing.T) {
+	ctx := context.Background()
+
+	jwkCipher, err := repository.NewJwkCipher("1", map[string][]byte{"1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("failed to create jwk cipher: %v", err)
+	}
+
+	securityConfig := &config.SecurityConfig{
+		TokenIssuer:              "simple",
+		TokenAudience:            []string{"simple"},
+		AccessTokenExpiresIn:     time.Duration(30) * time.Minute,
+		AccessTokenJwkExpiresIn:  time.Duration(720) * time.Minute,
+		AccessTokenJwkAlgorithm:  "ES256",
+		RefreshTokenExpiresIn:    time.Duration(10080) * time.Minute,
+		RefreshTokenJwkExpiresIn: time.Duration(20160) * time.Minute,
+		RefreshTokenJwkAlgorithm: "EdDSA",
+		JwkRsaKeySize:            2048,
+		JwkPublishLeadTime:       time.Duration(60) * time.Minute,
+		JwkRefreshInterval:       time.Duration(60) * time.Second,
+		JwkGenerationEnabled:     true,
+	}
+
+	dataSource := db.NewDataSource(DbConfig)
+	defer dataSource.Close()
+
+	transactor := repository.NewTransactor(dataSource)
+	jwkRepository := repository.NewJwkRepository(dataSource, jwkCipher)
+	jwtService := service.NewJwtService(securityConfig, transactor, jwkRepository)
+	auditService := service.NewAuditService(
+		jwtService,
+		transactor,
+		repository.NewAuditCheckpointRepository(dataSource),
+		repository.NewAuditEventRepository(dataSource),
+	)
+	jwkService := service.NewJwkService(securityConfig, auditService, jwtService, jwkRepository)
+
+	// Keys of every use exist, only the access keys are published
+	if _, err := jwtService.GetAccessJwtToken(ctx); err != nil {
+		t.Fatalf("failed to get access token key: %v", err)
+	}
+	if _, err := jwtService.GetRefreshJwtToken(ctx); err != nil {
+		t.Fatalf("failed to get refresh token key: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", impl.NewJwksController(jwkService).GetJwks)
+
+	first := serveJwks(router, "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", first.Code)
+	}
+
+	var jwks openapi.Jwks
+	if err := json.Unmarshal(first.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("failed to decode jwks: %v", err)
+	}
+	if len(jwks.Keys) == 0 {
+		t.Fatal("no keys published")
+	}
+	for _, key := range jwks.Keys {
+		if key.Use != string(openapi.ACCESS) {
+			t.Fatalf("key %s of use %s published", key.Kid, key.Use)
+		}
+	}
+
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	snapshot, err := jwkService.GetJwks(ctx)
+	if err != nil {
+		t.Fatalf("failed to get jwks: %v", err)
+	}
+	if snapshot.ETag != etag {
+		t.Fatalf("unexpected ETag: %s", etag)
+	}
+
+	var maxAge int64
+	if _, err := fmt.Sscanf(first.Header().Get("Cache-Control"), "public, max-age=%d", &maxAge); err != nil {
+		t.Fatalf("unexpected Cache-Control: %s", first.Header().Get("Cache-Control"))
+	}
+	if expected := snapshot.MaxAge(time.Now()); maxAge <= 0 || maxAge < expected-1 || maxAge > expected+1 {
+		t.Fatalf("unexpected max-age: %d, expected %d", maxAge, expected)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"current etag", etag, http.StatusNotModified},
+		{"weak current etag", "W/" + etag, http.StatusNotModified},
+		{"one of several etags", `"stale", ` + etag, http.StatusNotModified},
+		{"any etag", "*", http.StatusNotModified},
+		{"stale etag", `"stale"`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := serveJwks(router, tt.ifNoneMatch)
+			if response.Code != tt.status {
+				t.Fatalf("unexpected status: %d, expected %d", response.Code, tt.status)
+			}
+			if response.Header().Get("ETag") != etag {
+				t.Fatalf("unexpected ETag: %s", response.Header().Get("ETag"))
+			}
+			if response.Header().Get("Cache-Control") == "" {
+				t.Fatal("missing Cache-Control")
+			}
+			if tt.status == http.StatusNotModified && response.Body.Len() != 0 {
+				t.Fatalf("unexpected body: %s", response.Body.String())
+			}
+		})
+	}
+}
+
+func serveJwks(router http.Handler, ifNoneMatch string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	if ifNoneMatch != "" {
+		request.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditCheckpointRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewAuditCheckpointRepository(DataSource)
+
+	jwk, err := repository.NewJwkRepository(DataSource, JwkCipher).AddJwk(ctx, repository.JwkData{
+		Use:         "audit",
+		Alg:         repository.JwkAlgRS256,
+		RsaKeySize:  2048,
+		ActivatesAt: time.Now(),
+		Expiration:  time.Hour,
+	})
+	assert.NoError(t, err)
+
+	first, err := repo.AddAuditCheckpoint(ctx, &repository.AuditCheckpointData{
+		Seq:       1,
+		Hash:      "hash1",
+		Kid:       jwk.ID,
+		Signature: "signature1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), first.Seq)
+
+	second, err := repo.AddAuditCheckpoint(ctx, &repository.AuditCheckpointData{
+		Seq:       2,
+		Hash:      "hash2",
+		Kid:       jwk.ID,
+		Signature: "signature2",
+	})
+	assert.NoError(t, err)
+
+	last, err := repo.GetLastAuditCheckpoint(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, last.ID)
+
+	auditCheckpoints, err := repo.GetAuditCheckpoints(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(auditCheckpoints))
+	assert.Equal(t, "hash1", auditCheckpoints[0].Hash)
+
+	// Append-only
+	_, err = DataSource.Pool.Exec(ctx, "delete from audit_checkpoint where id = $1", first.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEventRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewAuditEventRepository(DataSource)
+	transactor := repository.NewTransactor(DataSource)
+
+	targetID := db2.NewUUID().String()
+	from := time.Now().Add(-time.Minute)
+
+	auditEvent, err := repo.AddAuditEvent(ctx, &repository.AuditEventData{
+		ActorID:    "actor",
+		ActorEmail: "actor@auth.org",
+		Action:     "USER_ENABLED_SET",
+		TargetType: "USER",
+		TargetID:   targetID,
+		IP:         "127.0.0.1",
+		UserAgent:  "test",
+		Before:     []byte(`{"enabled":false}`),
+		After:      []byte(`{"enabled":true}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, targetID, auditEvent.TargetID)
+	assert.JSONEq(t, `{"enabled":true}`, string(auditEvent.After))
+
+	// Rolled back together with the surrounding transaction
+	rollbackErr := errors.New("rollback")
+	err = transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if _, err := repo.AddAuditEvent(ctx, &repository.AuditEventData{
+			Action:     "USER_DELETED",
+			TargetType: "USER",
+			TargetID:   targetID,
+		}); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	assert.ErrorIs(t, err, rollbackErr)
+
+	page, err := repo.SearchAuditEvents(ctx,
+		&repository.SearchAuditEventsCriteria{TargetID: targetID, From: &from},
+		&common.Pageable{
+			Page: 0,
+			Size: 10,
+			Sort: "created_at DESC",
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.TotalElements)
+	assert.Equal(t, "USER_ENABLED_SET", page.Content[0].Action)
+
+	var exported []*repository.AuditEvent
+	err = repo.ExportAuditEvents(ctx,
+		&repository.SearchAuditEventsCriteria{ActorID: "actor", Action: "USER_ENABLED_SET", TargetID: targetID},
+		func(auditEvent *repository.AuditEvent) error {
+			exported = append(exported, auditEvent)
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(exported))
+
+	// Hash chain
+	next, err := repo.AddAuditEvent(ctx, &repository.AuditEventData{
+		Action:     "USER_DELETED",
+		TargetType: "USER",
+		TargetID:   targetID,
+		Before:     []byte(`{"id":"` + targetID + `","enabled":true}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, auditEvent.Hash, next.PrevHash)
+	assert.Greater(t, next.Seq, auditEvent.Seq)
+
+	last, err := repo.GetLastAuditEvent(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, next.ID, last.ID)
+
+	var chain []*repository.AuditEvent
+	err = repo.ExportAuditChain(ctx, func(auditEvent *repository.AuditEvent) error {
+		chain = append(chain, auditEvent)
+		return nil
+	})
+	assert.NoError(t, err)
+	for i, chained := range chain {
+		hash, err := repository.AuditEventHash(chained)
+		assert.NoError(t, err)
+		assert.Equal(t, chained.Hash, hash)
+		if i > 0 {
+			assert.Equal(t, chain[i-1].Hash, chained.PrevHash)
+		}
+	}
+
+	// Append-only
+	_, err = DataSource.Pool.Exec(ctx, "delete from audit_event where id = $1", auditEvent.ID)
+	assert.Error(t, err)
+}
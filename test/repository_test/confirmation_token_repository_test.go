@@ -0,0 +1,113 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmationTokenRepository_AddAndConsume(t *testing.T) {
+	ctx := context.Background()
+	userRepository := repository.NewUserRepository(DataSource)
+	tokenRepository := repository.NewConfirmationTokenRepository(DataSource)
+
+	u, err := userRepository.AddUser(ctx, &repository.UserData{
+		Email:     uniqueEmail("confirmation_token"),
+		Password:  "pw",
+		Enabled:   true,
+		Confirmed: false,
+	})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = userRepository.DeleteUserById(ctx, u.ID)
+	})
+
+	// Add token
+	first, err := tokenRepository.AddConfirmationToken(ctx, repository.ConfirmationTokenData{
+		UserID:     u.ID,
+		Purpose:    "RESET_PASSWORD",
+		Expiration: time.Hour,
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, first)
+	assert.Equal(t, u.ID, first.UserID)
+	assert.Equal(t, "RESET_PASSWORD", first.Purpose)
+	assert.Nil(t, first.ConsumedAt)
+
+	// Issuing a new token invalidates the outstanding one
+	second, err := tokenRepository.AddConfirmationToken(ctx, repository.ConfirmationTokenData{
+		UserID:     u.ID,
+		Purpose:    "RESET_PASSWORD",
+		Expiration: time.Hour,
+	})
+	assert.NoError(t, err)
+
+	_, err = tokenRepository.ConsumeConfirmationToken(ctx, repository.ConsumeConfirmationTokenData{
+		ID:      first.ID,
+		UserID:  u.ID,
+		Purpose: "RESET_PASSWORD",
+	})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// Wrong purpose
+	_, err = tokenRepository.ConsumeConfirmationToken(ctx, repository.ConsumeConfirmationTokenData{
+		ID:      second.ID,
+		UserID:  u.ID,
+		Purpose: "CONFIRM_USER",
+	})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// Consume
+	consumed, err := tokenRepository.ConsumeConfirmationToken(ctx, repository.ConsumeConfirmationTokenData{
+		ID:      second.ID,
+		UserID:  u.ID,
+		Purpose: "RESET_PASSWORD",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, consumed.ConsumedAt)
+
+	// Replay
+	_, err = tokenRepository.ConsumeConfirmationToken(ctx, repository.ConsumeConfirmationTokenData{
+		ID:      second.ID,
+		UserID:  u.ID,
+		Purpose: "RESET_PASSWORD",
+	})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestConfirmationTokenRepository_Expired(t *testing.T) {
+	ctx := context.Background()
+	userRepository := repository.NewUserRepository(DataSource)
+	tokenRepository := repository.NewConfirmationTokenRepository(DataSource)
+
+	u, err := userRepository.AddUser(ctx, &repository.UserData{
+		Email:     uniqueEmail("confirmation_token_expired"),
+		Password:  "pw",
+		Enabled:   true,
+		Confirmed: false,
+	})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = userRepository.DeleteUserById(ctx, u.ID)
+	})
+
+	token, err := tokenRepository.AddConfirmationToken(ctx, repository.ConfirmationTokenData{
+		UserID:     u.ID,
+		Purpose:    "CONFIRM_USER",
+		Expiration: -time.Minute,
+	})
+	assert.NoError(t, err)
+
+	_, err = tokenRepository.ConsumeConfirmationToken(ctx, repository.ConsumeConfirmationTokenData{
+		ID:      token.ID,
+		UserID:  u.ID,
+		Purpose: "CONFIRM_USER",
+	})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/janobono/auth-service/internal/config"
 	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/auth-service/internal/repository"
 
 	"github.com/docker/go-connections/nat"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -19,6 +20,9 @@ import (
 
 var (
 	DataSource *db.DataSource
+	JwkCipher  *repository.JwkCipher
+	KekV1      = []byte("0123456789abcdef0123456789abcdef")
+	KekV2      = []byte("fedcba9876543210fedcba9876543210")
 )
 
 func TestMain(m *testing.M) {
@@ -31,6 +35,11 @@ func TestMain(m *testing.M) {
 
 	DataSource = db.NewDataSource(cfg)
 
+	JwkCipher, err = repository.NewJwkCipher("1", map[string][]byte{"1": KekV1, "2": KekV2})
+	if err != nil {
+		log.Fatalf("could not create jwk cipher: %v", err)
+	}
+
 	code := m.Run()
 
 	_ = postgres.Terminate(ctx)
@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitationRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	userRepository := repository.NewUserRepository(DataSource)
+	invitationRepository := repository.NewInvitationRepository(DataSource)
+
+	email := uniqueEmail("invitation")
+	u, err := userRepository.AddUser(ctx, &repository.UserData{
+		Email:     email,
+		Password:  "pw",
+		Enabled:   true,
+		Confirmed: false,
+	})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = userRepository.DeleteUserById(ctx, u.ID)
+	})
+
+	// Add
+	invitation, err := invitationRepository.AddInvitation(ctx, &repository.InvitationData{
+		UserID:     u.ID,
+		Email:      email,
+		Expiration: time.Hour,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, invitation.UserID)
+	assert.Equal(t, email, invitation.Email)
+	assert.Equal(t, repository.InvitationStatusPending, invitation.Status())
+
+	// Get
+	byId, err := invitationRepository.GetInvitationById(ctx, invitation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, invitation.ID, byId.ID)
+
+	byUserId, err := invitationRepository.GetInvitationByUserId(ctx, u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, invitation.ID, byUserId.ID)
+
+	// Search
+	page, err := invitationRepository.SearchInvitations(ctx, &repository.SearchInvitationsCriteria{
+		SearchField: email,
+		Status:      repository.InvitationStatusPending,
+	}, &common.Pageable{Page: 0, Size: 10, Sort: "created_at DESC"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.TotalElements)
+
+	// Expire
+	expired, err := invitationRepository.SetInvitationExpiresAt(ctx, invitation.ID, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, repository.InvitationStatusExpired, expired.Status())
+
+	page, err = invitationRepository.SearchInvitations(ctx, &repository.SearchInvitationsCriteria{
+		SearchField: email,
+		Status:      repository.InvitationStatusExpired,
+	}, &common.Pageable{Page: 0, Size: 10, Sort: "created_at DESC"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.TotalElements)
+
+	// Revoke
+	revoked, err := invitationRepository.SetInvitationRevoked(ctx, invitation.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	assert.Equal(t, repository.InvitationStatusRevoked, revoked.Status())
+}
+
+func TestInvitationRepository_Accept(t *testing.T) {
+	ctx := context.Background()
+	userRepository := repository.NewUserRepository(DataSource)
+	invitationRepository := repository.NewInvitationRepository(DataSource)
+
+	email := uniqueEmail("invitation_accept")
+	u, err := userRepository.AddUser(ctx, &repository.UserData{
+		Email:     email,
+		Password:  "pw",
+		Enabled:   true,
+		Confirmed: false,
+	})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = userRepository.DeleteUserById(ctx, u.ID)
+	})
+
+	invitation, err := invitationRepository.AddInvitation(ctx, &repository.InvitationData{
+		UserID:     u.ID,
+		Email:      email,
+		Expiration: time.Hour,
+	})
+	assert.NoError(t, err)
+
+	accepted, err := invitationRepository.SetInvitationAccepted(ctx, invitation.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, accepted.AcceptedAt)
+	assert.Equal(t, repository.InvitationStatusAccepted, accepted.Status())
+
+	page, err := invitationRepository.SearchInvitations(ctx, &repository.SearchInvitationsCriteria{
+		SearchField: email,
+		Status:      repository.InvitationStatusPending,
+	}, &common.Pageable{Page: 0, Size: 10, Sort: "created_at DESC"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), page.TotalElements)
+}
@@ -1,7 +1,14 @@
 package repository_test
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"testing"
 	"time"
 
@@ -10,21 +17,27 @@ import (
 )
 
 func TestJwkRepository_CRUD(t *testing.T) {
-	repo := repository.NewJwkRepository(DataSource)
+	repo := repository.NewJwkRepository(DataSource, JwkCipher)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Add new JWK
+	now := time.Now()
 	addData := repository.JwkData{
-		Use:        "sig",
-		Expiration: 2 * time.Hour,
+		Use:             "sig",
+		Alg:             repository.JwkAlgRS256,
+		RsaKeySize:      2048,
+		ActivatesAt:     now,
+		Expiration:      2 * time.Hour,
+		TokenExpiration: 30 * time.Minute,
 	}
 	created, err := repo.AddJwk(ctx, addData)
 	assert.NoError(t, err)
 	assert.NotNil(t, created)
 	assert.Equal(t, "sig", created.Use)
-	assert.True(t, created.Active)
+	assert.WithinDuration(t, now.Add(2*time.Hour), created.ExpiresAt, time.Second)
+	assert.WithinDuration(t, now.Add(150*time.Minute), created.PublishedUntil, time.Second)
 
 	// Get active JWK
 	active, err := repo.GetActiveJwk(ctx, "sig")
@@ -32,11 +45,14 @@ func TestJwkRepository_CRUD(t *testing.T) {
 	assert.NotNil(t, active)
 	assert.Equal(t, created.ID, active.ID)
 
-	// Get active JWKs
-	activeJwks, err := repo.GetActiveJwks(ctx)
+	// No pending JWK
+	_, err = repo.GetPendingJwk(ctx, "sig")
+	assert.Error(t, err)
+
+	// Get published JWKs
+	publishedJwks, err := repo.GetPublishedJwks(ctx)
 	assert.NoError(t, err)
-	assert.NotNil(t, activeJwks)
-	assert.Equal(t, created.ID, activeJwks[0].ID)
+	assert.True(t, containsJwk(publishedJwks, created))
 
 	// Get JWK by ID
 	fetched, err := repo.GetJwk(ctx, created.ID)
@@ -44,4 +60,290 @@ func TestJwkRepository_CRUD(t *testing.T) {
 	assert.NotNil(t, fetched)
 	assert.Equal(t, created.ID, fetched.ID)
 	assert.Equal(t, "RSA", fetched.Kty)
+	assert.True(t, privateKeyEqual(created.PrivateKey, fetched.PrivateKey))
+	assert.Nil(t, fetched.RevokedAt)
+
+	// Get all JWKs
+	jwks, err := repo.GetJwks(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, jwks)
+
+	// Revoke JWK
+	revoked, err := repo.SetJwkRevoked(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	_, err = repo.GetActiveJwk(ctx, "sig")
+	assert.Error(t, err)
+
+	publishedJwks, err = repo.GetPublishedJwks(ctx)
+	assert.NoError(t, err)
+	assert.False(t, containsJwk(publishedJwks, created))
+}
+
+func TestJwkRepository_Lifecycle(t *testing.T) {
+	repo := repository.NewJwkRepository(DataSource, JwkCipher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	addData := repository.JwkData{
+		Use:             "lifecycle",
+		Alg:             repository.JwkAlgES256,
+		ActivatesAt:     now,
+		Expiration:      2 * time.Hour,
+		TokenExpiration: 30 * time.Minute,
+	}
+	current, err := repo.AddJwk(ctx, addData)
+	assert.NoError(t, err)
+
+	// Next key is published but does not sign yet
+	addData.ActivatesAt = current.ExpiresAt
+	next, err := repo.AddJwk(ctx, addData)
+	assert.NoError(t, err)
+
+	active, err := repo.GetActiveJwk(ctx, "lifecycle")
+	assert.NoError(t, err)
+	assert.Equal(t, current.ID, active.ID)
+
+	pending, err := repo.GetPendingJwk(ctx, "lifecycle")
+	assert.NoError(t, err)
+	assert.Equal(t, next.ID, pending.ID)
+
+	publishedJwks, err := repo.GetPublishedJwks(ctx)
+	assert.NoError(t, err)
+	assert.True(t, containsJwk(publishedJwks, current))
+	assert.True(t, containsJwk(publishedJwks, next))
+
+	// Another next key replaces the pending one and cuts the current key short
+	addData.ActivatesAt = now.Add(time.Hour)
+	replacement, err := repo.AddJwk(ctx, addData)
+	assert.NoError(t, err)
+
+	_, err = repo.GetJwk(ctx, next.ID)
+	assert.Error(t, err)
+
+	retiring, err := repo.GetJwk(ctx, current.ID)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, replacement.ActivatesAt, retiring.ExpiresAt, time.Millisecond)
+	assert.WithinDuration(t, replacement.ActivatesAt.Add(30*time.Minute), retiring.PublishedUntil, time.Millisecond)
+
+	// A key activated right away takes over signing, the previous one stays published for its tokens
+	addData.ActivatesAt = time.Now()
+	immediate, err := repo.AddJwk(ctx, addData)
+	assert.NoError(t, err)
+
+	active, err = repo.GetActiveJwk(ctx, "lifecycle")
+	assert.NoError(t, err)
+	assert.Equal(t, immediate.ID, active.ID)
+
+	publishedJwks, err = repo.GetPublishedJwks(ctx)
+	assert.NoError(t, err)
+	assert.True(t, containsJwk(publishedJwks, current))
+	assert.False(t, containsJwk(publishedJwks, replacement))
+}
+
+func TestJwkRepository_Import(t *testing.T) {
+	repo := repository.NewJwkRepository(DataSource, JwkCipher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	imported, err := repo.ImportJwk(ctx, repository.JwkImportData{
+		Kid:             "offline-1",
+		Use:             "imported",
+		Alg:             repository.JwkAlgES256,
+		PrivateKey:      privateKey,
+		ActivatesAt:     now.Add(time.Hour),
+		ExpiresAt:       now.Add(2 * time.Hour),
+		TokenExpiration: 30 * time.Minute,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "offline-1", imported.Kid)
+	assert.Equal(t, "EC", imported.Kty)
+	assert.WithinDuration(t, now.Add(150*time.Minute), imported.PublishedUntil, time.Millisecond)
+
+	fetched, err := repo.GetJwkByKid(ctx, "offline-1")
+	assert.NoError(t, err)
+	assert.Equal(t, imported.ID, fetched.ID)
+	assert.True(t, privateKeyEqual(privateKey, fetched.PrivateKey))
+
+	pending, err := repo.GetPendingJwk(ctx, "imported")
+	assert.NoError(t, err)
+	assert.Equal(t, imported.ID, pending.ID)
+
+	// The kid is unique
+	_, err = repo.ImportJwk(ctx, repository.JwkImportData{
+		Kid:         "offline-1",
+		Use:         "imported",
+		Alg:         repository.JwkAlgES256,
+		PrivateKey:  privateKey,
+		ActivatesAt: now,
+		ExpiresAt:   now.Add(time.Hour),
+	})
+	assert.Error(t, err)
+
+	// A generated key does not replace an imported pending one
+	_, err = repo.AddJwk(ctx, repository.JwkData{
+		Use:         "imported",
+		Alg:         repository.JwkAlgES256,
+		ActivatesAt: now.Add(30 * time.Minute),
+		Expiration:  time.Hour,
+	})
+	assert.NoError(t, err)
+
+	_, err = repo.GetJwk(ctx, imported.ID)
+	assert.NoError(t, err)
+
+	// A back-dated key retires the current one now and keeps it published for its tokens
+	current, err := repo.AddJwk(ctx, repository.JwkData{
+		Use:             "backdated",
+		Alg:             repository.JwkAlgES256,
+		ActivatesAt:     now,
+		Expiration:      2 * time.Hour,
+		TokenExpiration: 30 * time.Minute,
+	})
+	assert.NoError(t, err)
+
+	backdated, err := repo.ImportJwk(ctx, repository.JwkImportData{
+		Kid:             "offline-2",
+		Use:             "backdated",
+		Alg:             repository.JwkAlgES256,
+		PrivateKey:      privateKey,
+		ActivatesAt:     now.Add(-time.Hour),
+		ExpiresAt:       now.Add(time.Hour),
+		TokenExpiration: 30 * time.Minute,
+	})
+	assert.NoError(t, err)
+
+	active, err := repo.GetActiveJwk(ctx, "backdated")
+	assert.NoError(t, err)
+	assert.Equal(t, backdated.ID, active.ID)
+
+	retiring, err := repo.GetJwk(ctx, current.ID)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), retiring.ExpiresAt, 5*time.Second)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), retiring.PublishedUntil, 5*time.Second)
+
+	// Generated keys use their id as kid
+	generated, err := repo.AddJwk(ctx, repository.JwkData{
+		Use:         "generated",
+		Alg:         repository.JwkAlgES256,
+		ActivatesAt: now,
+		Expiration:  time.Hour,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, generated.ID.String(), generated.Kid)
+}
+
+func containsJwk(jwks []*repository.Jwk, jwk *repository.Jwk) bool {
+	for _, j := range jwks {
+		if j.ID == jwk.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJwkRepository_Algorithms(t *testing.T) {
+	repo := repository.NewJwkRepository(DataSource, JwkCipher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		alg       string
+		kty       string
+		publicKey interface{}
+	}{
+		{repository.JwkAlgRS256, "RSA", &rsa.PublicKey{}},
+		{repository.JwkAlgPS256, "RSA", &rsa.PublicKey{}},
+		{repository.JwkAlgES256, "EC", &ecdsa.PublicKey{}},
+		{repository.JwkAlgES384, "EC", &ecdsa.PublicKey{}},
+		{repository.JwkAlgEdDSA, "OKP", ed25519.PublicKey{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			created, err := repo.AddJwk(ctx, repository.JwkData{
+				Use:         "alg-" + tt.alg,
+				Alg:         tt.alg,
+				RsaKeySize:  3072,
+				ActivatesAt: time.Now(),
+				Expiration:  time.Hour,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kty, created.Kty)
+			assert.Equal(t, tt.alg, created.Alg)
+
+			fetched, err := repo.GetJwk(ctx, created.ID)
+			assert.NoError(t, err)
+			assert.IsType(t, tt.publicKey, fetched.PublicKey)
+			assert.True(t, privateKeyEqual(created.PrivateKey, fetched.PrivateKey))
+
+			if rsaKey, ok := fetched.PublicKey.(*rsa.PublicKey); ok {
+				assert.Equal(t, 3072, rsaKey.N.BitLen())
+			}
+		})
+	}
+
+	_, err := repo.AddJwk(ctx, repository.JwkData{Use: "alg-unknown", Alg: "HS256", Expiration: time.Hour})
+	assert.Error(t, err)
+}
+
+func privateKeyEqual(expected, actual crypto.Signer) bool {
+	key, ok := expected.(interface{ Equal(crypto.PrivateKey) bool })
+	return ok && key.Equal(actual)
+}
+
+func TestJwkRepository_EncryptionAtRest(t *testing.T) {
+	repo := repository.NewJwkRepository(DataSource, JwkCipher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	created, err := repo.AddJwk(ctx, repository.JwkData{
+		Use:         "encrypted",
+		Alg:         repository.JwkAlgES256,
+		ActivatesAt: time.Now(),
+		Expiration:  2 * time.Hour,
+	})
+	assert.NoError(t, err)
+
+	// Private key is not stored as plain PEM
+	stored, err := DataSource.Queries.GetJwk(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(stored.PrivateKey, []byte("PRIVATE KEY")))
+	assert.Equal(t, "1", stored.KekVersion.String)
+
+	// New KEK only cannot unwrap keys of the old version
+	cipherV2Only, err := repository.NewJwkCipher("2", map[string][]byte{"2": KekV2})
+	assert.NoError(t, err)
+	_, err = repository.NewJwkRepository(DataSource, cipherV2Only).GetJwk(ctx, created.ID)
+	assert.Error(t, err)
+
+	// Re-wrap with both KEK versions configured
+	cipherV2, err := repository.NewJwkCipher("2", map[string][]byte{"1": KekV1, "2": KekV2})
+	assert.NoError(t, err)
+	count, err := repository.NewJwkRepository(DataSource, cipherV2).RewrapJwks(ctx)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, count, 1)
+
+	rewrapped, err := repository.NewJwkRepository(DataSource, cipherV2Only).GetJwk(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.True(t, privateKeyEqual(created.PrivateKey, rewrapped.PrivateKey))
+
+	// Nothing left to re-wrap
+	count, err = repository.NewJwkRepository(DataSource, cipherV2).RewrapJwks(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Re-wrap back to the shared KEK version
+	_, err = repo.RewrapJwks(ctx)
+	assert.NoError(t, err)
 }
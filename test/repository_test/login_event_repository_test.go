@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginEventRepository(t *testing.T) {
+	ctx := context.Background()
+	userRepository := repository.NewUserRepository(DataSource)
+	loginEventRepository := repository.NewLoginEventRepository(DataSource)
+
+	u, err := userRepository.AddUser(ctx, &repository.UserData{
+		Email:     uniqueEmail("login-event"),
+		Password:  "pw",
+		Enabled:   true,
+		Confirmed: true,
+	})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = userRepository.DeleteUserById(ctx, u.ID)
+	})
+
+	// Add
+	failed, err := loginEventRepository.AddLoginEvent(ctx, &repository.LoginEventData{
+		UserID:      u.ID,
+		IP:          "127.0.0.1",
+		UserAgent:   "test",
+		Method:      "PASSWORD",
+		Success:     false,
+		Fingerprint: "device",
+	})
+	assert.NoError(t, err)
+	assert.False(t, failed.Success)
+
+	count, err := loginEventRepository.CountSuccessfulLoginEventsByFingerprint(ctx, u.ID, "device")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	succeeded, err := loginEventRepository.AddLoginEvent(ctx, &repository.LoginEventData{
+		UserID:      u.ID,
+		IP:          "127.0.0.1",
+		UserAgent:   "test",
+		Method:      "PASSWORD",
+		Success:     true,
+		Fingerprint: "device",
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, succeeded.RevokedAt)
+
+	count, err = loginEventRepository.CountSuccessfulLoginEventsByFingerprint(ctx, u.ID, "device")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Search
+	page, err := loginEventRepository.SearchLoginEvents(ctx,
+		&repository.SearchLoginEventsCriteria{UserID: u.ID},
+		&common.Pageable{
+			Page: 0,
+			Size: 10,
+			Sort: "created_at DESC",
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.TotalElements)
+
+	// Revoke
+	revoked, err := loginEventRepository.SetLoginEventRevoked(ctx, succeeded.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	fetched, err := loginEventRepository.GetLoginEventById(ctx, succeeded.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, fetched.RevokedAt)
+}
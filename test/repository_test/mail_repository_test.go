@@ -0,0 +1,92 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailRepository(t *testing.T) {
+	ctx := context.Background()
+	mailRepository := repository.NewMailRepository(DataSource)
+
+	mail, err := mailRepository.AddMail(ctx, &repository.MailData{
+		Recipients: []string{"mail@auth.org"},
+		Subject:    "Subject",
+		Data:       []byte(`{"Body":"body"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, repository.MailStatusQueued, mail.Status)
+	assert.Equal(t, []string{"mail@auth.org"}, mail.Recipients)
+
+	claimed, err := mailRepository.ClaimMails(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 1)
+	assert.Equal(t, mail.ID, claimed[0].ID)
+
+	claimed, err = mailRepository.ClaimMails(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	mail, err = mailRepository.SetMailResult(ctx, mail.ID, &repository.MailResultData{
+		Status:        repository.MailStatusFailed,
+		Attempts:      10,
+		NextAttemptAt: time.Now(),
+		LastError:     "connection refused",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, repository.MailStatusFailed, mail.Status)
+	assert.Nil(t, mail.SentAt)
+
+	page, err := mailRepository.SearchMails(ctx, &repository.SearchMailsCriteria{
+		Status: repository.MailStatusFailed,
+	}, &common.Pageable{
+		Page: 0,
+		Size: 10,
+		Sort: "created_at DESC",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.TotalElements)
+	assert.Equal(t, "connection refused", page.Content[0].LastError)
+
+	mail, err = mailRepository.SetMailResult(ctx, mail.ID, &repository.MailResultData{
+		Status:        repository.MailStatusSent,
+		Attempts:      1,
+		NextAttemptAt: time.Now(),
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, mail.SentAt)
+
+	fetched, err := mailRepository.GetMailById(ctx, mail.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.MailStatusSent, fetched.Status)
+
+	// Queued mails keep their content, sent ones are blanked once
+	queued, err := mailRepository.AddMail(ctx, &repository.MailData{
+		Recipients: []string{"queued@auth.org"},
+		Subject:    "Subject",
+		Data:       []byte(`{"Body":"body"}`),
+	})
+	assert.NoError(t, err)
+
+	count, err := mailRepository.PurgeMailData(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	fetched, err = mailRepository.GetMailById(ctx, mail.ID)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(fetched.Data))
+	assert.Equal(t, "Subject", fetched.Subject)
+
+	fetched, err = mailRepository.GetMailById(ctx, queued.ID)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Body":"body"}`, string(fetched.Data))
+
+	count, err = mailRepository.PurgeMailData(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
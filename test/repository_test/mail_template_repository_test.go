@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailTemplateRepository(t *testing.T) {
+	ctx := context.Background()
+	mailTemplateRepository := repository.NewMailTemplateRepository(DataSource)
+
+	mailTemplate, err := mailTemplateRepository.AddMailTemplate(ctx, &repository.MailTemplateData{
+		Code:     "TEST_TEMPLATE",
+		Locale:   "sk",
+		Subject:  "Subject",
+		HtmlBody: `{{define "content"}}<p>v1</p>{{end}}`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), mailTemplate.Version)
+	assert.Empty(t, mailTemplate.TextBody)
+
+	t.Cleanup(func() {
+		_ = mailTemplateRepository.DeleteMailTemplateById(ctx, mailTemplate.ID)
+	})
+
+	count, err := mailTemplateRepository.CountByCodeAndLocale(ctx, "TEST_TEMPLATE", "sk")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	mailTemplate, err = mailTemplateRepository.SetMailTemplate(ctx, mailTemplate.ID, &repository.MailTemplateContentData{
+		Subject:  "Subject v2",
+		HtmlBody: `{{define "content"}}<p>v2</p>{{end}}`,
+		TextBody: `{{define "content"}}v2{{end}}`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), mailTemplate.Version)
+	assert.Equal(t, "Subject v2", mailTemplate.Subject)
+
+	versions, err := mailTemplateRepository.GetMailTemplateVersions(ctx, mailTemplate.ID)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, int32(2), versions[0].Version)
+	assert.Equal(t, "Subject", versions[1].Subject)
+
+	byCode, err := mailTemplateRepository.GetMailTemplatesByCode(ctx, "TEST_TEMPLATE")
+	assert.NoError(t, err)
+	assert.Len(t, byCode, 1)
+	assert.Equal(t, "sk", byCode[0].Locale)
+
+	page, err := mailTemplateRepository.SearchMailTemplates(ctx, &repository.SearchMailTemplatesCriteria{
+		Code:   "TEST_TEMPLATE",
+		Locale: "sk",
+	}, &common.Pageable{
+		Page: 0,
+		Size: 10,
+		Sort: "code ASC",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.TotalElements)
+	assert.Equal(t, `{{define "content"}}v2{{end}}`, page.Content[0].TextBody)
+
+	err = mailTemplateRepository.DeleteMailTemplateById(ctx, mailTemplate.ID)
+	assert.NoError(t, err)
+
+	versions, err = mailTemplateRepository.GetMailTemplateVersions(ctx, mailTemplate.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+}
@@ -26,6 +26,7 @@ func TestUserRepository_CountsAndGetters(t *testing.T) {
 		Password:  "pw",
 		Enabled:   true,
 		Confirmed: false,
+		Locale:    "sk-SK",
 	})
 	assert.NoError(t, err)
 
@@ -56,6 +57,7 @@ func TestUserRepository_CountsAndGetters(t *testing.T) {
 	byID, err := userRepository.GetUserById(ctx, u.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, email, byID.Email)
+	assert.Equal(t, "sk-SK", byID.Locale)
 }
 
 func TestUserRepository_Setters(t *testing.T) {
@@ -72,11 +74,17 @@ func TestUserRepository_Setters(t *testing.T) {
 	assert.NoError(t, err)
 	t.Cleanup(func() { _ = userRepository.DeleteUserById(ctx, u.ID) })
 
-	// email
+	// pending email
 	newEmail := uniqueEmail("setters_new")
+	u, err = userRepository.SetUserPendingEmail(ctx, u.ID, newEmail)
+	assert.NoError(t, err)
+	assert.Equal(t, newEmail, u.PendingEmail)
+
+	// email
 	u, err = userRepository.SetUserEmail(ctx, u.ID, newEmail)
 	assert.NoError(t, err)
 	assert.Equal(t, newEmail, u.Email)
+	assert.Empty(t, u.PendingEmail)
 
 	// password
 	u, err = userRepository.SetUserPassword(ctx, u.ID, "newpw")
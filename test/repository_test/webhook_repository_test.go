@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/go-util/common"
+	db2 "github.com/janobono/go-util/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookRepository_Delivery(t *testing.T) {
+	ctx := context.Background()
+	outboxEventRepository := repository.NewOutboxEventRepository(DataSource)
+	webhookRepository := repository.NewWebhookRepository(DataSource)
+	webhookDeliveryRepository := repository.NewWebhookDeliveryRepository(DataSource)
+
+	// Webhook
+	webhook, err := webhookRepository.AddWebhook(ctx, &repository.WebhookData{
+		URL:        "http://localhost:8080/hook",
+		Secret:     "secret",
+		EventTypes: []string{"USER_CREATED"},
+		Enabled:    true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, webhook.Accepts("USER_CREATED"))
+	assert.False(t, webhook.Accepts("USER_DELETED"))
+
+	t.Cleanup(func() {
+		_ = webhookRepository.DeleteWebhookById(ctx, webhook.ID)
+	})
+
+	webhook, err = webhookRepository.SetWebhook(ctx, webhook.ID, &repository.WebhookData{
+		URL:     "http://localhost:8080/hook",
+		Secret:  "secret",
+		Enabled: true,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, webhook.EventTypes)
+	assert.True(t, webhook.Accepts("USER_DELETED"))
+
+	enabled, err := webhookRepository.GetEnabledWebhooks(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, enabled)
+
+	page, err := webhookRepository.GetWebhooks(ctx, &common.Pageable{
+		Page: 0,
+		Size: 10,
+		Sort: "created_at DESC",
+	})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, page.TotalElements, int64(1))
+
+	// Outbox
+	outboxEvent, err := outboxEventRepository.AddOutboxEvent(ctx, &repository.OutboxEventData{
+		EventType:   "USER_CREATED",
+		AggregateID: db2.NewUUID(),
+		Payload:     []byte(`{"email":"outbox@auth.org"}`),
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, outboxEvent.DispatchedAt)
+
+	outboxEvents, err := outboxEventRepository.GetNotDispatchedOutboxEvents(ctx, 100)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, outboxEvents)
+
+	outboxEvents, err = outboxEventRepository.GetOutboxEventsAfterSeq(ctx, outboxEvent.Seq-1, 100)
+	assert.NoError(t, err)
+	assert.Len(t, outboxEvents, 1)
+	assert.Equal(t, outboxEvent.ID, outboxEvents[0].ID)
+
+	outboxEvents, err = outboxEventRepository.GetOutboxEventsAfterSeq(ctx, outboxEvent.Seq, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, outboxEvents)
+
+	// Delivery
+	assert.NoError(t, webhookDeliveryRepository.AddWebhookDelivery(ctx, webhook.ID, outboxEvent.ID))
+	assert.NoError(t, webhookDeliveryRepository.AddWebhookDelivery(ctx, webhook.ID, outboxEvent.ID))
+	assert.NoError(t, outboxEventRepository.SetOutboxEventDispatched(ctx, outboxEvent.ID))
+
+	fetchedEvent, err := outboxEventRepository.GetOutboxEventById(ctx, outboxEvent.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, fetchedEvent.DispatchedAt)
+
+	claimed, err := webhookDeliveryRepository.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 1)
+	assert.Equal(t, outboxEvent.ID, claimed[0].OutboxEventID)
+
+	again, err := webhookDeliveryRepository.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, again)
+
+	_, err = webhookDeliveryRepository.AddWebhookDeliveryAttempt(ctx, &repository.WebhookDeliveryAttemptData{
+		DeliveryID: claimed[0].ID,
+		StatusCode: 500,
+		Error:      "unexpected status 500",
+		Duration:   25 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	delivery, err := webhookDeliveryRepository.SetWebhookDeliveryResult(ctx, claimed[0].ID, &repository.WebhookDeliveryResultData{
+		Status:        repository.WebhookDeliveryStatusDeadLetter,
+		Attempts:      1,
+		NextAttemptAt: time.Now(),
+		LastError:     "unexpected status 500",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, repository.WebhookDeliveryStatusDeadLetter, delivery.Status)
+	assert.Nil(t, delivery.DeliveredAt)
+
+	attempts, err := webhookDeliveryRepository.GetWebhookDeliveryAttempts(ctx, delivery.ID)
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 1)
+	assert.Equal(t, int32(500), attempts[0].StatusCode)
+
+	deliveries, err := webhookDeliveryRepository.SearchWebhookDeliveries(ctx,
+		&repository.SearchWebhookDeliveriesCriteria{
+			WebhookID: webhook.ID,
+			Status:    repository.WebhookDeliveryStatusDeadLetter,
+		},
+		&common.Pageable{
+			Page: 0,
+			Size: 10,
+			Sort: "created_at DESC",
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deliveries.TotalElements)
+
+	delivery, err = webhookDeliveryRepository.SetWebhookDeliveryResult(ctx, delivery.ID, &repository.WebhookDeliveryResultData{
+		Status:        repository.WebhookDeliveryStatusDelivered,
+		Attempts:      2,
+		NextAttemptAt: time.Now(),
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, delivery.DeliveredAt)
+}
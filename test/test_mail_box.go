@@ -0,0 +1,36 @@
+package test
+
+import (
+	"bytes"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func readMails(dir, recipient string) ([]*mail.Message, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	var result []*mail.Message
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		message, err := mail.ReadMessage(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.Contains(message.Header.Get("To"), recipient) {
+			result = append(result, message)
+		}
+	}
+	return result, nil
+}
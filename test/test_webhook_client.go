@@ -0,0 +1,15 @@
+package test
+
+import (
+	"context"
+	"net/http"
+
+	client2 "github.com/janobono/auth-service/internal/service/client"
+)
+
+type testWebhookClient struct {
+}
+
+func (t testWebhookClient) Send(ctx context.Context, request *client2.WebhookRequest) (int, error) {
+	return http.StatusOK, nil
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/janobono/auth-service/internal/config"
+	"github.com/janobono/auth-service/internal/db"
+	"github.com/janobono/auth-service/internal/repository"
+	"github.com/janobono/auth-service/internal/service"
+)
+
+func BenchmarkUserDetail(b *testing.B) {
+	ctx := context.Background()
+
+	dataSource := db.NewDataSource(DbConfig)
+	defer dataSource.Close()
+
+	jwkCipher, err := repository.NewJwkCipher("1", map[string][]byte{"1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		b.Fatalf("failed to create jwk cipher: %v", err)
+	}
+
+	attributeRepository := repository.NewAttributeRepository(dataSource)
+	authorityRepository := repository.NewAuthorityRepository(dataSource)
+	userRepository := repository.NewUserRepository(dataSource)
+
+	attribute, err := attributeRepository.AddAttribute(ctx, &repository.AttributeData{Key: "bench_name"})
+	if err != nil {
+		b.Fatalf("failed to add attribute: %v", err)
+	}
+	authority, err := authorityRepository.AddAuthority(ctx, &repository.AuthorityData{Authority: "bench"})
+	if err != nil {
+		b.Fatalf("failed to add authority: %v", err)
+	}
+	user, err := userRepository.AddUserWithAttributesAndAuthorities(ctx, &repository.UserData{
+		Email:     "bench@auth.org",
+		Password:  "password",
+		Confirmed: true,
+		Enabled:   true,
+		Locale:    "en",
+	}, []*repository.UserAttribute{{Attribute: attribute, Value: "Bench"}}, []*repository.Authority{authority})
+	if err != nil {
+		b.Fatalf("failed to add user: %v", err)
+	}
+
+	modes := []struct {
+		name           string
+		validationMode string
+		cacheSize      int
+	}{
+		{"database", service.TOKEN_VALIDATION_DATABASE, 0},
+		{"database-cache", service.TOKEN_VALIDATION_DATABASE, 1000},
+		{"claims", service.TOKEN_VALIDATION_CLAIMS, 0},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			securityConfig := &config.SecurityConfig{
+				TokenIssuer:             "simple",
+				TokenAudience:           []string{"simple"},
+				TokenClaims:             map[string]string{"authorities": "authorities", "email": "email", "confirmed": "email_verified", "attribute.bench_name": "name"},
+				TokenValidationMode:     mode.validationMode,
+				UserCacheSize:           mode.cacheSize,
+				UserCacheTtl:            time.Duration(60) * time.Second,
+				AccessTokenExpiresIn:    time.Duration(30) * time.Minute,
+				AccessTokenJwkExpiresIn: time.Duration(720) * time.Minute,
+				AccessTokenJwkAlgorithm: "ES256",
+				JwkPublishLeadTime:      time.Duration(60) * time.Minute,
+				JwkRefreshInterval:      time.Duration(60) * time.Second,
+				JwkGenerationEnabled:    true,
+			}
+
+			jwtService := service.NewJwtService(securityConfig, repository.NewTransactor(dataSource), repository.NewJwkRepository(dataSource, jwkCipher))
+			confirmationService := service.NewConfirmationService(&config.AppConfig{}, securityConfig, jwtService, repository.NewConfirmationTokenRepository(dataSource))
+			outboxService := service.NewOutboxService(repository.NewOutboxEventRepository(dataSource), userRepository)
+			userDetailService := service.NewUserDetailService(securityConfig, jwtService, confirmationService, outboxService, userRepository)
+
+			jwtToken, err := jwtService.GetAccessJwtToken(ctx)
+			if err != nil {
+				b.Fatalf("failed to get access token key: %v", err)
+			}
+			token, err := jwtService.GenerateAuthToken(jwtToken, user.ID, &service.UserClaims{
+				Email:       user.Email,
+				Confirmed:   user.Confirmed,
+				CreatedAt:   user.CreatedAt,
+				Attributes:  map[string]string{"bench_name": "Bench"},
+				Authorities: []string{"bench"},
+			})
+			if err != nil {
+				b.Fatalf("failed to generate token: %v", err)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					userDetail, err := userDetailService.DecodeUserDetail(ctx, token)
+					if err != nil || userDetail.Id != user.ID.String() {
+						b.Errorf("failed to decode user detail: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}